@@ -0,0 +1,356 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+func vmAgentFileReadCmd() *cobra.Command {
+	var nodeName, output string
+	cmd := &cobra.Command{
+		Use:   "file-read <vmid> <path>",
+		Short: "Copy a file out of the VM via guest agent",
+		Long: `Copy a file out of the VM to stdout or --output (-o). Files over 16 MiB
+are read in chunks, which needs sh, dd and base64 in the guest.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  pxve vm agent file-read 100 /etc/hostname
+  pxve vm agent file-read 100 /var/log/syslog -o syslog`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			var n int64
+			read := func(w io.Writer) (err error) {
+				n, err = actions.VMAgentFileRead(context.Background(), proxmoxClient, vmid, nodeName, args[1], w)
+				return err
+			}
+			s := startSpinner("Reading " + args[1] + "...")
+			if output != "" {
+				err = writeFileAtomic(output, read)
+			} else {
+				err = read(cmd.OutOrStdout())
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if output != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "Copied %s (%s) from VM %d to %s.\n", args[1], formatBytes(uint64(n)), vmid, output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	// Shadows the global --output format flag, which has no use here.
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to this local file instead of stdout")
+	return cmd
+}
+
+// writeFileAtomic writes a local file through write, replacing path only once
+// write has succeeded, so a failed guest read never clobbers an existing file.
+// The file is created 0600: guest files are often private; don't widen access.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".pxve-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func vmAgentFileWriteCmd() *cobra.Command {
+	var nodeName, file string
+	cmd := &cobra.Command{
+		Use:   "file-write <vmid> <path>",
+		Short: "Write stdin to a file in the VM via guest agent",
+		Long: `Write stdin (or --file) to a file in the VM, replacing it. Files over 45 KiB
+are sent in base64 chunks, which needs sh and base64 in the guest.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  pxve vm agent file-write 100 /etc/motd < motd
+  pxve vm agent file-write 100 /opt/app/app.conf -f app.conf`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			var data []byte
+			if file == "" || file == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Writing " + args[1] + "...")
+			err = actions.VMAgentFileWrite(context.Background(), proxmoxClient, vmid, nodeName, args[1], data, func(done int) {
+				if len(data) > actions.AgentChunkSize {
+					s.SetMessage(fmt.Sprintf("Writing %s... %d%%", args[1], done*100/len(data)))
+				}
+			})
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s (%s) to VM %d.\n", args[1], formatBytes(uint64(len(data))), vmid)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "local file to send (default: stdin)")
+	return cmd
+}
+
+// vmAgentFSFreezeCmd builds fsfreeze-status, fsfreeze-freeze, and
+// fsfreeze-thaw.
+func vmAgentFSFreezeCmd(action, short string) *cobra.Command {
+	var nodeName string
+	cmd := &cobra.Command{
+		Use:   "fsfreeze-" + action + " <vmid>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Running fsfreeze-" + action + "...")
+			result, err := actions.VMAgentFSFreeze(context.Background(), proxmoxClient, vmid, nodeName, action)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			out := cmd.OutOrStdout()
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if action == "status" {
+					return enc.Encode(map[string]string{"status": result})
+				}
+				n, _ := strconv.Atoi(result)
+				return enc.Encode(map[string]int{"filesystems": n})
+			}
+			switch action {
+			case "status":
+				fmt.Fprintf(out, "VM %d filesystems are %s.\n", vmid, result)
+			case "freeze":
+				fmt.Fprintf(out, "Froze %s filesystem(s) on VM %d; thaw them with 'pxve vm agent fsfreeze-thaw %d'.\n", result, vmid, vmid)
+			case "thaw":
+				fmt.Fprintf(out, "Thawed %s filesystem(s) on VM %d.\n", result, vmid)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+func vmAgentInfoCmd() *cobra.Command {
+	var nodeName string
+	cmd := &cobra.Command{
+		Use:   "info <vmid>",
+		Short: "Show the guest agent version and supported commands",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Loading...")
+			info, err := actions.VMAgentInfo(context.Background(), proxmoxClient, vmid, nodeName)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			out := cmd.OutOrStdout()
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(info)
+			}
+			fmt.Fprintf(out, "Agent version: %s\n\n", info.Version)
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "COMMAND\tENABLED")
+			for _, c := range info.Commands {
+				fmt.Fprintf(w, "%s\t%s\n", c.Name, yesNoBool(c.Enabled))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+func vmAgentRunCmd() *cobra.Command {
+	var (
+		selector  string
+		timeout   time.Duration
+		parallel  int
+		stdinData string
+	)
+	cmd := &cobra.Command{
+		Use:   "run --selector <expr> -- <command> [args...]",
+		Short: "Run a command on every matching running VM via guest agent",
+		Long: `Run a command in parallel on every running VM that matches --selector,
+through the guest agent. Each VM's output is printed as it finishes, every
+line prefixed with the VM's ID and name (stderr goes to stderr), followed by
+a summary of exit codes. Exits non-zero if the command failed anywhere.
+
+Stopped VMs, templates, and containers that match are skipped.`,
+		Args: cobra.MinimumNArgs(1),
+		Example: `  pxve vm agent run --selector tag=web -- systemctl is-active nginx
+  pxve vm agent run --selector node=pve1,name=db-* --parallel 2 -- df -h /
+  pxve vm agent run --selector vmid=100-199 --output json -- uptime`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if selector == "" {
+				return fmt.Errorf("--selector is required")
+			}
+			sel, err := actions.ParseSelector(selector)
+			if err != nil {
+				return err
+			}
+			timeoutSecs, err := agentTimeoutSecs(timeout)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Finding VMs...")
+			guests, err := actions.SelectGuests(ctx, proxmoxClient, sel)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			var vms proxmox.ClusterResources
+			var skipped []string
+			for _, g := range guests {
+				if g.Type == "qemu" && g.Status == "running" && g.Template == 0 {
+					vms = append(vms, g)
+				} else {
+					skipped = append(skipped, strconv.FormatUint(g.VMID, 10))
+				}
+			}
+			out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+			if len(skipped) > 0 {
+				fmt.Fprintf(errOut, "Skipping %d matching guest(s) that are not running VMs: %s\n", len(skipped), strings.Join(skipped, ", "))
+			}
+			if len(vms) == 0 {
+				if flagOutput == "json" {
+					fmt.Fprintln(out, "[]")
+				} else if stdoutIsTerminal() {
+					fmt.Fprintf(out, "%sNo running VMs match the selector.%s\n", colorGold, colorReset)
+				} else {
+					fmt.Fprintln(out, "No running VMs match the selector.")
+				}
+				return nil
+			}
+
+			var done func(*actions.AgentRunResult)
+			if flagOutput != "json" {
+				done = func(r *actions.AgentRunResult) {
+					prefix := fmt.Sprintf("[%d %s] ", r.VMID, r.Name)
+					writePrefixed(out, prefix, r.Stdout)
+					writePrefixed(errOut, prefix, r.Stderr)
+					if r.Error != "" {
+						fmt.Fprintf(errOut, "%serror: %s\n", prefix, r.Error)
+					}
+				}
+			}
+			results := actions.VMAgentRun(ctx, proxmoxClient, vms, args, stdinData, timeoutSecs, parallel, done)
+
+			failed := 0
+			for _, r := range results {
+				if r.Failed() {
+					failed++
+				}
+			}
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintln(out)
+				w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "VMID\tNAME\tNODE\tEXIT\tTIME")
+				for _, r := range results {
+					exit := strconv.Itoa(r.ExitCode)
+					if r.Error != "" {
+						exit = "error"
+					}
+					fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", r.VMID, r.Name, r.Node, exit, r.Duration.Round(100*time.Millisecond))
+				}
+				if err := w.Flush(); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("command failed on %d of %d VMs", failed, len(results))
+			}
+			return nil
+		},
+	}
+	addSelectorFlag(cmd, &selector)
+	// Shadows the global --timeout, which bounds tasks; same duration format.
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "time limit per VM, e.g. 2m")
+	cmd.Flags().IntVar(&parallel, "parallel", 8, "number of VMs to run on at once")
+	cmd.Flags().StringVar(&stdinData, "stdin", "", "data to pass to command stdin")
+	return cmd
+}
+
+// agentTimeoutSecs converts a per-command --timeout to the whole seconds the
+// guest agent wait takes, rounding up.
+func agentTimeoutSecs(d time.Duration) (int, error) {
+	if d < time.Second {
+		return 0, fmt.Errorf("--timeout must be at least 1s")
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// writePrefixed writes each line of s to w with prefix in front.
+func writePrefixed(w io.Writer, prefix, s string) {
+	if s == "" {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		fmt.Fprintf(w, "%s%s\n", prefix, line)
+	}
+}
@@ -0,0 +1,304 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// agentHistoryFile keeps agent shell history in the home directory, next to
+// the config file.
+const agentHistoryFile = ".pxve_agent_history"
+
+// agentHistoryMax is how many history lines are kept.
+const agentHistoryMax = 500
+
+const agentShellHelp = `Lines run with sh in the guest; cd carries over, variables do not.
+  :get <guest-path> [local-path]   copy a file out of the guest
+  :put <local-path> [guest-path]   copy a file into the guest
+  :help                            show this help
+  :exit                            leave (or exit, Ctrl+D)
+`
+
+func vmAgentShellCmd() *cobra.Command {
+	var (
+		nodeName string
+		timeout  time.Duration
+	)
+	cmd := &cobra.Command{
+		Use:   "shell <vmid>",
+		Short: "Interactive shell over the guest agent",
+		Long: `Start an interactive shell that runs each line in the VM through the guest
+agent, for guests without network access or a working console. The guest
+needs a POSIX sh.
+
+Each line runs as its own sh process in the current directory: cd carries
+over between lines, shell variables and other state do not. Commands cannot
+be interactive and must finish within --timeout. Stderr is shown in red.
+Ctrl+C stops waiting for a command (it keeps running in the guest).
+
+Use :get and :put to copy files, :help for the list of commands. History is
+kept in ~/` + agentHistoryFile + `.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve vm agent shell 100
+  pxve vm agent shell 100 --timeout 5m
+  echo 'df -h' | pxve vm agent shell 100`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			timeoutSecs, err := agentTimeoutSecs(timeout)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Connecting to guest agent...")
+			sh, err := actions.NewAgentShell(context.Background(), proxmoxClient, vmid, nodeName, timeoutSecs)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			return runAgentShell(cmd, sh)
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	// Shadows the global --timeout, which bounds tasks; same duration format.
+	cmd.Flags().DurationVar(&timeout, "timeout", time.Minute, "time limit per command, e.g. 5m")
+	return cmd
+}
+
+// runAgentShell reads lines until EOF or :exit. On a terminal, lines are
+// read with line editing and history in raw mode, and the terminal goes back
+// to normal mode while a line runs so that Ctrl+C can interrupt the wait.
+func runAgentShell(cmd *cobra.Command, sh *actions.AgentShell) error {
+	out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	fd := int(os.Stdin.Fd())
+	interactive := term.IsTerminal(fd) && stdoutIsTerminal()
+
+	var readLine func() (string, error)
+	if interactive {
+		t := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "")
+		hist := loadAgentHistory()
+		for _, l := range hist {
+			t.History.Add(l)
+		}
+		readLine = func() (string, error) {
+			t.SetPrompt(fmt.Sprintf("%s:%s$ ", sh.Name, sh.Dir))
+			state, err := term.MakeRaw(fd)
+			if err != nil {
+				return "", err
+			}
+			line, err := t.ReadLine()
+			_ = term.Restore(fd, state)
+			if err == nil && strings.TrimSpace(line) != "" {
+				hist = appendAgentHistory(hist, line)
+			}
+			return line, err
+		}
+		fmt.Fprintf(out, "Connected to VM %d (%s) via guest agent. Type :help for commands.\n", sh.VMID, sh.Name)
+	} else {
+		sc := bufio.NewScanner(cmd.InOrStdin())
+		sc.Buffer(make([]byte, 64*1024), 1024*1024)
+		readLine = func() (string, error) {
+			if !sc.Scan() {
+				if err := sc.Err(); err != nil {
+					return "", err
+				}
+				return "", io.EOF
+			}
+			return sc.Text(), nil
+		}
+	}
+
+	lastExit := 0
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			if interactive {
+				fmt.Fprintln(out)
+			}
+			break
+		}
+		if err != nil && !errors.Is(err, term.ErrPasteIndicator) {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case line == ":exit" || line == ":quit" || line == "exit" || line == "logout":
+			return agentShellExit(lastExit, interactive)
+		case line == ":help":
+			fmt.Fprint(out, agentShellHelp)
+			continue
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		if strings.HasPrefix(line, ":") {
+			err = agentShellBuiltin(ctx, cmd, sh, strings.Fields(line))
+			if err == nil {
+				lastExit = 0
+			}
+		} else {
+			var st *proxmox.AgentExecStatus
+			st, err = sh.Run(ctx, line)
+			if err == nil {
+				fmt.Fprint(out, st.OutData)
+				if st.ErrData != "" {
+					if interactive {
+						fmt.Fprint(errOut, colorRed+st.ErrData+colorReset)
+					} else {
+						fmt.Fprint(errOut, st.ErrData)
+					}
+				}
+				if bool(st.OutTruncated) || st.ErrTruncated {
+					fmt.Fprintln(errOut, "(output truncated by the guest agent)")
+				}
+				lastExit = st.ExitCode
+				if interactive && st.ExitCode != 0 {
+					fmt.Fprintf(errOut, "%s[exit %d]%s\n", colorDim, st.ExitCode, colorReset)
+				}
+			}
+		}
+		stop()
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(errOut, "Interrupted; the command keeps running in the guest.")
+		} else if err != nil {
+			fmt.Fprintf(errOut, "Error: %v\n", err)
+		}
+		if err != nil {
+			lastExit = 1
+		}
+	}
+	return agentShellExit(lastExit, interactive)
+}
+
+// agentShellExit makes a non-interactive session fail like a script would
+// when its last command failed.
+func agentShellExit(code int, interactive bool) error {
+	if interactive || code == 0 {
+		return nil
+	}
+	return fmt.Errorf("last command exited with code %d", code)
+}
+
+// agentShellBuiltin runs a :command.
+func agentShellBuiltin(ctx context.Context, cmd *cobra.Command, sh *actions.AgentShell, f []string) error {
+	out := cmd.OutOrStdout()
+	switch f[0] {
+	case ":get":
+		if len(f) < 2 || len(f) > 3 {
+			return fmt.Errorf("usage: :get <guest-path> [local-path]")
+		}
+		local := path.Base(f[1])
+		if len(f) == 3 {
+			local = f[2]
+		}
+		if st, err := os.Stat(local); err == nil && st.IsDir() {
+			local = filepath.Join(local, path.Base(f[1]))
+		}
+		var n int64
+		s := startSpinner("Reading " + sh.Path(f[1]) + "...")
+		err := writeFileAtomic(local, func(w io.Writer) (err error) {
+			n, err = sh.Get(ctx, f[1], w)
+			return err
+		})
+		s.Stop()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Copied %s (%s) to %s.\n", sh.Path(f[1]), formatBytes(uint64(n)), local)
+		return nil
+	case ":put":
+		if len(f) < 2 || len(f) > 3 {
+			return fmt.Errorf("usage: :put <local-path> [guest-path]")
+		}
+		remote := filepath.Base(f[1])
+		if len(f) == 3 {
+			remote = f[2]
+			if strings.HasSuffix(remote, "/") {
+				remote += filepath.Base(f[1])
+			}
+		}
+		data, err := os.ReadFile(f[1])
+		if err != nil {
+			return err
+		}
+		target := sh.Path(remote)
+		s := startSpinner("Writing " + target + "...")
+		err = sh.Put(ctx, remote, data, func(done int) {
+			if len(data) > actions.AgentChunkSize {
+				s.SetMessage(fmt.Sprintf("Writing %s... %d%%", target, done*100/len(data)))
+			}
+		})
+		s.Stop()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Copied %s (%s) to %s.\n", f[1], formatBytes(uint64(len(data))), target)
+		return nil
+	}
+	return fmt.Errorf("unknown command %s (try :help)", f[0])
+}
+
+func agentHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, agentHistoryFile)
+}
+
+// loadAgentHistory returns the saved history, oldest first.
+func loadAgentHistory() []string {
+	p := agentHistoryPath()
+	if p == "" {
+		return nil
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) > agentHistoryMax {
+		lines = lines[len(lines)-agentHistoryMax:]
+	}
+	return lines
+}
+
+// appendAgentHistory adds line to hist and saves it. The file is private:
+// commands can contain secrets.
+func appendAgentHistory(hist []string, line string) []string {
+	if len(hist) > 0 && hist[len(hist)-1] == line {
+		return hist
+	}
+	hist = append(hist, line)
+	if len(hist) > agentHistoryMax {
+		hist = hist[len(hist)-agentHistoryMax:]
+	}
+	if p := agentHistoryPath(); p != "" {
+		_ = os.WriteFile(p, []byte(strings.Join(hist, "\n")+"\n"), 0o600)
+	}
+	return hist
+}
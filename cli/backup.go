@@ -160,6 +160,9 @@ func backupCreateCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Backing up VMID %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -197,6 +200,9 @@ func backupDeleteCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Deleting %s...\n", volid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -236,6 +242,9 @@ func backupRestoreCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Restoring %s to VMID %d...\n", volid, assignedID)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
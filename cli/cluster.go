@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"text/tabwriter"
 
+	proxmox "github.com/luthermonson/go-proxmox"
 	"github.com/spf13/cobra"
 
 	"github.com/chupakbra/proxmox-cli/internal/actions"
@@ -56,21 +57,30 @@ func clusterStatusCmd() *cobra.Command {
 }
 
 func clusterResourcesCmd() *cobra.Command {
-	var filterType string
+	var filterType, watch string
 	cmd := &cobra.Command{
 		Use:   "resources",
 		Short: "Show cluster resources",
 		Long: `Show all resources in the cluster. Use --type to filter by resource type.
 Valid types: vm, storage, node, pool`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := resolveWatch(watch, args)
+			if err != nil {
+				return err
+			}
 			if err := initClient(cmd); err != nil {
 				return err
 			}
-			ctx := context.Background()
 			var filters []string
 			if filterType != "" {
 				filters = append(filters, filterType)
 			}
+			if interval > 0 {
+				return runWatch(cmd, interval, func(ctx context.Context) (proxmox.ClusterResources, error) {
+					return actions.ClusterResources(ctx, proxmoxClient, filters...)
+				}, resourceTable)
+			}
+			ctx := context.Background()
 			s := startSpinner("Loading resources...")
 			resources, err := actions.ClusterResources(ctx, proxmoxClient, filters...)
 			s.Stop()
@@ -84,35 +94,45 @@ Valid types: vm, storage, node, pool`,
 				return enc.Encode(resources)
 			}
 
-			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "ID\tTYPE\tNAME\tNODE\tSTATUS\tCPU\tMEM\tDISK")
-			for _, r := range resources {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					r.ID,
-					r.Type,
-					r.Name,
-					r.Node,
-					r.Status,
-					formatCPUPercent(r.CPU),
-					formatBytes(r.Mem),
-					formatBytes(r.Disk),
-				)
-			}
-			return w.Flush()
+			return writeTable(cmd.OutOrStdout(), resourceTable(resources), nil)
 		},
 	}
 	cmd.Flags().StringVar(&filterType, "type", "", "filter by resource type (vm, storage, node, pool)")
+	addWatchFlag(cmd, &watch)
 	return cmd
 }
 
+func resourceTable(resources proxmox.ClusterResources) listTable {
+	t := listTable{header: "ID\tTYPE\tNAME\tNODE\tSTATUS\tCPU\tMEM\tDISK"}
+	for _, r := range resources {
+		usage := fmt.Sprintf("%s\t%s\t%s\t%s", r.Status, formatCPUPercent(r.CPU), formatBytes(r.Mem), formatBytes(r.Disk))
+		t.rows = append(t.rows, listRow{
+			line: fmt.Sprintf("%s\t%s\t%s\t%s\t%s", r.ID, r.Type, r.Name, r.Node, usage),
+			key:  r.ID,
+			sig:  usage,
+		})
+	}
+	return t
+}
+
 func clusterTasksCmd() *cobra.Command {
-	return &cobra.Command{
+	var watch string
+	cmd := &cobra.Command{
 		Use:   "tasks",
 		Short: "List recent cluster tasks",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := resolveWatch(watch, args)
+			if err != nil {
+				return err
+			}
 			if err := initClient(cmd); err != nil {
 				return err
 			}
+			if interval > 0 {
+				return runWatch(cmd, interval, func(ctx context.Context) (proxmox.Tasks, error) {
+					return actions.ClusterTasks(ctx, proxmoxClient)
+				}, taskTable)
+			}
 			ctx := context.Background()
 			s := startSpinner("Loading tasks...")
 			tasks, err := actions.ClusterTasks(ctx, proxmoxClient)
@@ -127,32 +147,41 @@ func clusterTasksCmd() *cobra.Command {
 				return enc.Encode(tasks)
 			}
 
-			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "UPID\tNODE\tTYPE\tUSER\tSTATUS\tSTARTED\tDURATION")
-			for _, t := range tasks {
-				started := "-"
-				if !t.StartTime.IsZero() {
-					started = t.StartTime.Format("2006-01-02 15:04:05")
-				}
-				duration := "-"
-				if t.Duration > 0 {
-					duration = t.Duration.Round(1e9).String()
-				}
-				status := t.Status
-				if t.ExitStatus != "" && t.ExitStatus != "running" {
-					status = t.ExitStatus
-				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					t.UPID,
-					t.Node,
-					t.Type,
-					t.User,
-					status,
-					started,
-					duration,
-				)
-			}
-			return w.Flush()
+			return writeTable(cmd.OutOrStdout(), taskTable(tasks), nil)
 		},
 	}
+	addWatchFlag(cmd, &watch)
+	return cmd
+}
+
+func taskTable(tasks proxmox.Tasks) listTable {
+	tbl := listTable{header: "UPID\tNODE\tTYPE\tUSER\tSTATUS\tSTARTED\tDURATION"}
+	for _, t := range tasks {
+		started := "-"
+		if !t.StartTime.IsZero() {
+			started = t.StartTime.Format("2006-01-02 15:04:05")
+		}
+		duration := "-"
+		if t.Duration > 0 {
+			duration = t.Duration.Round(1e9).String()
+		}
+		status := t.Status
+		if t.ExitStatus != "" && t.ExitStatus != "running" {
+			status = t.ExitStatus
+		}
+		tbl.rows = append(tbl.rows, listRow{
+			line: fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s",
+				t.UPID,
+				t.Node,
+				t.Type,
+				t.User,
+				status,
+				started,
+				duration,
+			),
+			key: string(t.UPID),
+			sig: status,
+		})
+	}
+	return tbl
 }
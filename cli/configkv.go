@@ -0,0 +1,396 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// guestIDArg returns the ID placeholder and noun for guests of type typ.
+func guestIDArg(typ string) (string, string) {
+	if typ == "lxc" {
+		return "<ctid>", "container"
+	}
+	return "<vmid>", "VM"
+}
+
+func guestConfigGetCmd(typ string) *cobra.Command {
+	var nodeName string
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "get " + id + " [key]",
+		Short: "Print raw " + noun + " config options",
+		Long: `Print one config option's raw value, or every option as "key: value"
+followed by the config digest. Pass the digest to "set --digest" or
+"unset --digest" to make a later change fail if the config changed in the
+meantime.`,
+		Args:              cobra.RangeArgs(1, 2),
+		ValidArgsFunction: configKeyCompletion(typ, false),
+		Example: fmt.Sprintf(`  pxve %[1]s config get 100
+  pxve %[1]s config get 100 net0
+  pxve %[1]s config get 100 --output json`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, typ, args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+			if len(args) == 2 {
+				key := args[1]
+				value, ok := cfg.Values[key]
+				if !ok {
+					if _, lerr := actions.LookupConfigOption(typ, key); lerr != nil {
+						return lerr
+					}
+					return fmt.Errorf("%s %d has no %s", capitalize(cfg.Noun()), cfg.VMID, key)
+				}
+				if flagOutput == "json" {
+					enc := json.NewEncoder(out)
+					enc.SetIndent("", "  ")
+					return enc.Encode(map[string]string{key: value})
+				}
+				fmt.Fprintln(out, value)
+				return nil
+			}
+
+			if flagOutput == "json" {
+				all := make(map[string]string, len(cfg.Values)+1)
+				for k, v := range cfg.Values {
+					all[k] = v
+				}
+				all["digest"] = cfg.Digest
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(all)
+			}
+			keys := make([]string, 0, len(cfg.Values))
+			for k := range cfg.Values {
+				keys = append(keys, k)
+			}
+			sort.Slice(keys, func(i, j int) bool { return actions.ConfigKeyLess(keys[i], keys[j]) })
+			for _, k := range keys {
+				fmt.Fprintf(out, "%s: %s\n", k, cfg.Values[k])
+			}
+			fmt.Fprintf(out, "digest: %s\n", cfg.Digest)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+// cliNoun returns the command name for guests of type typ.
+func cliNoun(typ string) string {
+	if typ == "lxc" {
+		return "ct"
+	}
+	return "vm"
+}
+
+func guestConfigSetCmd(typ string) *cobra.Command {
+	var (
+		o          configEditOpts
+		noValidate bool
+	)
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "set " + id + " <key=value>...",
+		Short: "Set any " + noun + " config option",
+		Long: `Set config options by their Proxmox name, passing values through as
+they are written in the config file. Keys and values are checked against a
+built-in schema of known options (booleans may be given as true/false);
+--no-validate skips the check for options the schema does not know.
+
+The change is shown as a diff and applied after confirmation. It carries
+the config digest, so it fails instead of overwriting a concurrent change.`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: configKeyCompletion(typ, true),
+		Example: fmt.Sprintf(`  pxve %[1]s config set 100 cores=4 memory=8192
+  pxve %[1]s config set 100 onboot=true startup=order=2,up=30
+  pxve %[1]s config set 100 description="web frontend" --force
+  pxve %[1]s config set 100 cores=8 --digest "$(pxve %[1]s config get 100 -o json | jq -r .digest)"`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			set := map[string]string{}
+			for _, arg := range args[1:] {
+				key, value, ok := strings.Cut(arg, "=")
+				if !ok || key == "" {
+					return fmt.Errorf("invalid argument %q (expected key=value)", arg)
+				}
+				if _, dup := set[key]; dup {
+					return fmt.Errorf("%s is given more than once", key)
+				}
+				if !noValidate {
+					var err error
+					if value, err = actions.ValidateConfigValue(typ, key, value); err != nil {
+						return err
+					}
+				}
+				set[key] = value
+			}
+			return editGuestConfig(cmd, typ, args[0], o, func(*actions.GuestConfig) (map[string]string, []string, error) {
+				return set, nil, nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "skip the built-in schema check")
+	addConfigEditFlags(cmd, &o)
+	addDigestFlag(cmd, &o)
+	return cmd
+}
+
+func guestConfigUnsetCmd(typ string) *cobra.Command {
+	var (
+		o          configEditOpts
+		noValidate bool
+	)
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:               "unset " + id + " <key>...",
+		Short:             "Remove " + noun + " config options",
+		Long:              `Remove config options, returning them to their Proxmox defaults.`,
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: configKeyCompletion(typ, false),
+		Example: fmt.Sprintf(`  pxve %[1]s config unset 100 description
+  pxve %[1]s config unset 100 cpulimit startup`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys := args[1:]
+			if !noValidate {
+				for _, key := range keys {
+					if _, err := actions.LookupConfigOption(typ, key); err != nil {
+						return err
+					}
+				}
+			}
+			return editGuestConfig(cmd, typ, args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				for _, key := range keys {
+					if _, ok := cfg.Values[key]; !ok {
+						return nil, nil, fmt.Errorf("%s %d has no %s", capitalize(cfg.Noun()), cfg.VMID, key)
+					}
+				}
+				return nil, keys, nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "skip the built-in schema check")
+	addConfigEditFlags(cmd, &o)
+	addDigestFlag(cmd, &o)
+	return cmd
+}
+
+func guestConfigExportCmd(typ string) *cobra.Command {
+	var nodeName string
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "export " + id,
+		Short: "Write the full " + noun + " config as YAML or JSON",
+		Long: `Write every config option as YAML (or JSON with --output json), for
+"config import". Values include pending changes that take effect at the
+next restart; the pending list names those keys.`,
+		Args: cobra.ExactArgs(1),
+		Example: fmt.Sprintf(`  pxve %[1]s config export 100 > golden/100.yaml
+  pxve %[1]s config export 100 --output json > golden/100.json`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, typ, args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			f, err := actions.ExportGuestConfig(context.Background(), proxmoxClient, cfg)
+			if err != nil {
+				return handleErr(err)
+			}
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(f)
+			}
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent(2)
+			if err := enc.Encode(f); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+func guestConfigImportCmd(typ string) *cobra.Command {
+	var (
+		o          configEditOpts
+		file       string
+		noValidate bool
+		fromOther  bool
+	)
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "import " + id + " -f <file>",
+		Short: "Make a " + noun + " config match an exported file",
+		Long: `Compare the config with a file written by "config export" (YAML or
+JSON) and apply only what differs: changed and new keys are set, keys
+missing from the file are removed. Lock, metadata, and unused volumes are
+never touched.
+
+A file exported from another guest is refused unless --from-other-guest is
+given. Its disks, mount points, smbios1 and vmgenid are then skipped, and
+NICs keep this guest's MAC addresses, so the two guests never share volumes
+or identities.
+
+The changes are shown as a diff; --dry-run stops there, which makes it a
+drift check against a golden config. On a running guest some changes only
+take effect at the next restart (see "pending").`,
+		Args: cobra.ExactArgs(1),
+		Example: fmt.Sprintf(`  pxve %[1]s config import 100 -f golden/100.yaml --dry-run
+  pxve %[1]s config import 100 -f golden/100.yaml`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			var data []byte
+			var err error
+			if file == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", file, err)
+			}
+			// YAML is a superset of JSON, so this reads both formats.
+			var want actions.ConfigFile
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			if err := dec.Decode(&want); err != nil && err != io.EOF {
+				return fmt.Errorf("parsing %s: %w", file, err)
+			}
+			if want.Type != "" && want.Type != typ {
+				_, fileNoun := guestIDArg(want.Type)
+				return fmt.Errorf("%s holds a %s config, not a %s config", file, fileNoun, noun)
+			}
+			if len(want.Config) == 0 {
+				return fmt.Errorf("%s has no config", file)
+			}
+			if vmid, err := strconv.Atoi(args[0]); err == nil && want.VMID != 0 && want.VMID != vmid && !fromOther {
+				return fmt.Errorf("%s holds the config of %s %d, not %d; pass --from-other-guest to import it without its disks, MAC addresses, and identity", file, noun, want.VMID, vmid)
+			}
+
+			return editGuestConfig(cmd, typ, args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				other := want.VMID != 0 && want.VMID != cfg.VMID
+				set, del := actions.PlanConfigImport(cfg, &want, other)
+				if !noValidate {
+					for k, v := range set {
+						if _, err := actions.ValidateConfigValue(typ, k, v); err != nil {
+							return nil, nil, fmt.Errorf("%s: %w", file, err)
+						}
+					}
+				}
+				if other {
+					fmt.Fprintf(cmd.OutOrStdout(), "Importing the config of %s %d into %s %d, keeping the disks, MAC addresses, and identity of %d.\n", noun, want.VMID, noun, cfg.VMID, cfg.VMID)
+				}
+				return set, del, nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "file from 'config export' (- for stdin)")
+	cmd.Flags().BoolVar(&noValidate, "no-validate", false, "skip the built-in schema check")
+	cmd.Flags().BoolVar(&fromOther, "from-other-guest", false, "allow a file exported from another guest (its disks, MACs, smbios1 and vmgenid are skipped)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// configKeyCompletion completes config keys after the guest ID, annotated
+// with their help text. When the guest's config can be loaded, numbered keys
+// complete to the existing ones plus the next free slot; with assign set,
+// keys end in "=" and known values are completed after it.
+func configKeyCompletion(typ string, assign bool) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if !assign && cmd.Name() == "get" && len(args) > 1 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if key, _, ok := strings.Cut(toComplete, "="); ok && assign {
+			o, err := actions.LookupConfigOption(typ, key)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			values := o.Enum
+			if o.Kind == actions.OptBool {
+				values = []string{"0", "1"}
+			}
+			var out []string
+			for _, v := range values {
+				out = append(out, key+"="+v)
+			}
+			return out, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		cur := completionGuestConfig(cmd, typ, args[0])
+		var out []string
+		add := func(key string, o actions.ConfigOption) {
+			if assign {
+				key += "="
+			}
+			out = append(out, key+"\t"+o.Help)
+		}
+		for _, o := range actions.ConfigSchema(typ) {
+			if o.Slots == 0 {
+				if _, set := cur[o.Key]; assign || cur == nil || set {
+					add(o.Key, o)
+				}
+				continue
+			}
+			if cur == nil {
+				add(o.Key+"0", o)
+				continue
+			}
+			for _, k := range o.Keys() {
+				if _, set := cur[k]; set {
+					add(k, o)
+				}
+			}
+			if assign {
+				if free, err := actions.FreeSlot(cur, o.Key, o.Slots); err == nil {
+					add(free, o)
+				}
+			}
+		}
+		directive := cobra.ShellCompDirectiveNoFileComp
+		if assign {
+			directive |= cobra.ShellCompDirectiveNoSpace
+		}
+		return out, directive
+	}
+}
+
+// completionGuestConfig loads the guest's config for shell completion, or
+// returns nil when it cannot be reached quickly.
+func completionGuestConfig(cmd *cobra.Command, typ, idArg string) map[string]string {
+	var vmid int
+	if _, err := fmt.Sscan(idArg, &vmid); err != nil {
+		return nil
+	}
+	if err := initClient(cmd); err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cfg, err := actions.GetGuestConfig(ctx, proxmoxClient, typ, vmid, "")
+	if err != nil {
+		return nil
+	}
+	return cfg.Values
+}
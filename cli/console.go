@@ -0,0 +1,339 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+	"github.com/chupakbra/proxmox-cli/internal/desktop"
+	"github.com/chupakbra/proxmox-cli/internal/termproxy"
+)
+
+// defaultEscape is the console detach character, Ctrl+] as in telnet and
+// virsh console.
+const defaultEscape = "^]"
+
+func addEscapeFlag(cmd *cobra.Command, escape *string) {
+	cmd.Flags().StringVar(escape, "escape", defaultEscape, `detach character: "^X" for Ctrl+X, a single character, or "none"`)
+}
+
+// parseEscape turns an --escape value into the byte that detaches, or 0 for
+// "none".
+func parseEscape(s string) (byte, error) {
+	switch {
+	case s == "none":
+		return 0, nil
+	case len(s) == 2 && s[0] == '^' && s[1] >= '@' && s[1] <= '_':
+		return s[1] - '@', nil
+	case len(s) == 2 && s[0] == '^' && s[1] >= 'a' && s[1] <= 'z':
+		return s[1] - 'a' + 1, nil
+	case len(s) == 1:
+		return s[0], nil
+	}
+	return 0, fmt.Errorf(`invalid escape %q (expected "^X", a single character, or "none")`, s)
+}
+
+func vmConsoleCmd() *cobra.Command {
+	var nodeName, serial, escape string
+	cmd := &cobra.Command{
+		Use:   "console <vmid>",
+		Short: "Attach to a VM's serial console",
+		Long: `Attach this terminal to a running VM's serial console through the Proxmox
+terminal proxy, like "qm terminal". The VM needs a serial port (see
+"pxve vm serial add") with a getty or kernel console on it. Type the escape
+character (Ctrl+] by default) to detach; the VM keeps running.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve vm console 100
+  pxve vm console 100 --serial serial1 --escape ^a`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			esc, err := parseEscape(escape)
+			if err != nil {
+				return err
+			}
+			cfg, err := loadGuestConfig(cmd, "qemu", args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Values[serial]; !ok {
+				return fmt.Errorf("VM %d has no %s; add one with 'pxve vm serial add %d'", cfg.VMID, serial, cfg.VMID)
+			}
+			return openGuestConsole(cmd, cfg, serial, esc)
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().StringVar(&serial, "serial", "serial0", "serial port to attach to")
+	addEscapeFlag(cmd, &escape)
+	return cmd
+}
+
+func ctConsoleCmd() *cobra.Command {
+	var nodeName, escape string
+	cmd := &cobra.Command{
+		Use:   "console <ctid>",
+		Short: "Attach to a container's console",
+		Long: `Attach this terminal to a running container's console through the Proxmox
+terminal proxy, like "pct console". Type the escape character (Ctrl+] by
+default) to detach; the container keeps running.`,
+		Args:    cobra.ExactArgs(1),
+		Example: `  pxve ct console 200`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			esc, err := parseEscape(escape)
+			if err != nil {
+				return err
+			}
+			cfg, err := loadGuestConfig(cmd, "lxc", args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			return openGuestConsole(cmd, cfg, "", esc)
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	addEscapeFlag(cmd, &escape)
+	return cmd
+}
+
+func nodeShellCmd() *cobra.Command {
+	var escape string
+	cmd := &cobra.Command{
+		Use:   "shell <node>",
+		Short: "Open a login shell on a node",
+		Long: `Open a login shell on a node in this terminal through the Proxmox terminal
+proxy, like the web UI's node Shell. Type the escape character (Ctrl+] by
+default) to detach, which ends the shell.`,
+		Args:    cobra.ExactArgs(1),
+		Example: `  pxve node shell pve1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			esc, err := parseEscape(escape)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Opening shell...")
+			tp, err := actions.OpenNodeShell(context.Background(), proxmoxClient, args[0])
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			return attachConsole(cmd, tp, "node "+args[0], esc)
+		},
+	}
+	addEscapeFlag(cmd, &escape)
+	return cmd
+}
+
+func vmSpiceCmd() *cobra.Command {
+	var nodeName, output, proxy string
+	var launch bool
+	cmd := &cobra.Command{
+		Use:   "spice <vmid>",
+		Short: "Write a SPICE connection file for a VM's graphical console",
+		Long: `Request a SPICE ticket for a running VM and write a virt-viewer (.vv)
+connection file to stdout, to --output (-o), or, with --launch, to a temporary
+file opened with remote-viewer. The ticket expires shortly, so open the file
+right away. The VM needs a SPICE display (vga=qxl).
+
+The viewer connects through the SPICE proxy on port 3128 of the host in the
+instance URL; use --proxy when that host is not reachable from the viewer.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve vm spice 100 --launch
+  pxve vm spice 100 -o web1.vv
+  pxve vm spice 100 --proxy pve1.example.com --launch`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, "qemu", args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			if !actions.HasSpiceDisplay(cfg) {
+				vga := cfg.Values["vga"]
+				if vga == "" {
+					vga = "std"
+				}
+				return fmt.Errorf("VM %d has no SPICE display (vga=%s); set one with 'pxve vm config set %d vga=qxl' and restart it", cfg.VMID, vga, cfg.VMID)
+			}
+			s := startSpinner("Requesting SPICE ticket...")
+			vv, err := actions.SpiceViewerFile(context.Background(), proxmoxClient, cfg, proxy)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			out := cmd.OutOrStdout()
+			path := output
+			switch {
+			case path == "" && !launch:
+				_, err := out.Write(vv)
+				return err
+			case path == "":
+				f, err := os.CreateTemp("", fmt.Sprintf("pxve-%d-*.vv", cfg.VMID))
+				if err != nil {
+					return err
+				}
+				path = f.Name()
+				_, err = f.Write(vv)
+				if cerr := f.Close(); err == nil {
+					err = cerr
+				}
+				if err != nil {
+					return err
+				}
+			default:
+				// The file holds the ticket; keep it private.
+				if err := os.WriteFile(path, vv, 0o600); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "Wrote SPICE connection file %s.\n", path)
+			}
+			if launch {
+				if err := desktop.OpenViewer(path); err != nil {
+					return fmt.Errorf("opening %s: %w (install virt-viewer for remote-viewer)", path, err)
+				}
+				fmt.Fprintf(out, "Opened SPICE console of VM %d.\n", cfg.VMID)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	// Shadows the global --output format flag, which has no use here.
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write the .vv file here instead of stdout")
+	cmd.Flags().StringVar(&proxy, "proxy", "", "SPICE proxy host (default: the instance URL's host)")
+	cmd.Flags().BoolVar(&launch, "launch", false, "open the file with remote-viewer")
+	return cmd
+}
+
+func vmVNCURLCmd() *cobra.Command {
+	var nodeName string
+	var open bool
+	cmd := &cobra.Command{
+		Use:   "vnc-url <vmid>",
+		Short: "Print the web UI noVNC console URL of a VM",
+		Long: `Print the address of a VM's noVNC console in the Proxmox web UI, as opened
+by its Console button. Each visit requests a fresh one-time VNC ticket with
+the browser's web UI login.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve vm vnc-url 100
+  pxve vm vnc-url 100 --open`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, "qemu", args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			u, err := actions.NoVNCURL(context.Background(), proxmoxClient, cfg)
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), u)
+			if open {
+				return desktop.Open(u)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().BoolVar(&open, "open", false, "also open the URL in the default browser")
+	return cmd
+}
+
+// openGuestConsole starts the console proxy of a running guest and attaches
+// to it.
+func openGuestConsole(cmd *cobra.Command, cfg *actions.GuestConfig, serial string, esc byte) error {
+	ctx := context.Background()
+	s := startSpinner("Opening console...")
+	status, err := actions.GuestStatus(ctx, proxmoxClient, cfg)
+	if err == nil && status != "running" {
+		s.Stop()
+		return fmt.Errorf("%s %d is not running", capitalize(cfg.Noun()), cfg.VMID)
+	}
+	var tp *actions.TermProxy
+	if err == nil {
+		tp, err = actions.OpenGuestConsole(ctx, proxmoxClient, cfg.Type, cfg.VMID, cfg.Node, serial)
+	}
+	s.Stop()
+	if err != nil {
+		return handleErr(err)
+	}
+	what := fmt.Sprintf("%s %d", cfg.Noun(), cfg.VMID)
+	if serial != "" {
+		what += " " + serial
+	}
+	return attachConsole(cmd, tp, what, esc)
+}
+
+// attachConsole connects to tp and wires it to the local terminal, which is
+// switched to raw mode so keys such as Ctrl+C reach the remote side. Window
+// size changes are forwarded until the session ends or esc is typed.
+func attachConsole(cmd *cobra.Command, tp *actions.TermProxy, what string, esc byte) error {
+	ctx := context.Background()
+	sess, err := termproxy.Dial(ctx, tp.URL, tp.Header, tp.TLS, tp.User, tp.Ticket)
+	if err != nil {
+		return handleErr(err)
+	}
+
+	out := cmd.OutOrStdout()
+	hint := "escape disabled"
+	if esc != 0 {
+		hint = "press " + escapeName(esc) + " to detach"
+	}
+	fmt.Fprintf(out, "Connected to %s (%s).\n", what, hint)
+
+	restore := func() {}
+	stdin := int(os.Stdin.Fd())
+	if term.IsTerminal(stdin) {
+		state, err := term.MakeRaw(stdin)
+		if err != nil {
+			sess.Close()
+			return err
+		}
+		restore = func() { term.Restore(stdin, state) }
+	}
+
+	stdout := int(os.Stdout.Fd())
+	if term.IsTerminal(stdout) {
+		resize := func() {
+			if cols, rows, err := term.GetSize(stdout); err == nil {
+				_ = sess.Resize(cols, rows)
+			}
+		}
+		resize()
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer func() {
+			signal.Stop(winch)
+			close(winch)
+		}()
+		go func() {
+			for range winch {
+				resize()
+			}
+		}()
+	}
+
+	err = sess.Attach(ctx, os.Stdin, out, esc)
+	restore()
+	switch {
+	case errors.Is(err, termproxy.ErrDetached):
+		fmt.Fprintf(out, "\nDetached from %s.\n", what)
+	case err != nil:
+		return handleErr(err)
+	default:
+		fmt.Fprintf(out, "\nConnection to %s closed.\n", what)
+	}
+	return nil
+}
+
+// escapeName renders a detach byte the way --escape takes it.
+func escapeName(b byte) string {
+	if b < 0x20 {
+		return "^" + string(rune(b+'@'))
+	}
+	return string(rune(b))
+}
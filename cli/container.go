@@ -1,14 +1,12 @@
 package cli
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 	"text/tabwriter"
-	"time"
 
 	proxmox "github.com/luthermonson/go-proxmox"
 	"github.com/spf13/cobra"
@@ -35,18 +33,39 @@ func containerCmd() *cobra.Command {
 	cmd.AddCommand(ctDiskCmd())
 	cmd.AddCommand(ctTagCmd())
 	cmd.AddCommand(ctConfigCmd())
+	cmd.AddCommand(guestPendingCmd("lxc"))
+	cmd.AddCommand(ctMountPointCmd())
+	cmd.AddCommand(ctNICCmd())
+	cmd.AddCommand(ctFeaturesCmd())
+	cmd.AddCommand(ctConsoleCmd())
+	cmd.AddCommand(ctMetricsCmd())
 	return cmd
 }
 
 func ctListCmd() *cobra.Command {
-	var nodeName string
+	var nodeName, watch string
+	var showIP bool
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List containers",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := resolveWatch(watch, args)
+			if err != nil {
+				return err
+			}
 			if err := initClient(cmd); err != nil {
 				return err
 			}
+			if showIP {
+				return listGuestsWithIPs(cmd, interval, "containers", func(ctx context.Context) (proxmox.ClusterResources, error) {
+					return actions.ListContainers(ctx, proxmoxClient, nodeName)
+				}, containerTable)
+			}
+			if interval > 0 {
+				return runWatch(cmd, interval, func(ctx context.Context) (proxmox.ClusterResources, error) {
+					return actions.ListContainers(ctx, proxmoxClient, nodeName)
+				}, containerTable)
+			}
 			ctx := context.Background()
 			s := startSpinner("Loading containers...")
 			cts, err := actions.ListContainers(ctx, proxmoxClient, nodeName)
@@ -58,6 +77,8 @@ func ctListCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "filter by node name")
+	addWatchFlag(cmd, &watch)
+	addShowIPFlag(cmd, &showIP)
 	return cmd
 }
 
@@ -77,39 +98,34 @@ func printContainers(cmd *cobra.Command, cts proxmox.ClusterResources) error {
 		return nil
 	}
 
-	// Write to a buffer first so tabwriter aligns columns before we apply color.
-	var buf bytes.Buffer
-	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "CTID\tNAME\tNODE\tSTATUS\tTMPL\tMEM\tDISK\tUPTIME")
+	return writeTable(cmd.OutOrStdout(), containerTable(cts), nil)
+}
+
+// containerTable builds the container list table; templates are drawn in red.
+func containerTable(cts proxmox.ClusterResources) listTable {
+	t := listTable{header: "CTID\tNAME\tNODE\tSTATUS\tTMPL\tMEM\tDISK\tUPTIME"}
 	for _, ct := range cts {
-		tmpl := ""
+		tmpl, color := "", ""
 		if ct.Template == 1 {
-			tmpl = "yes"
-		}
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			ct.VMID,
-			ct.Name,
-			ct.Node,
-			ct.Status,
-			tmpl,
-			formatBytes(ct.MaxMem),
-			formatBytes(ct.MaxDisk),
-			formatUptime(ct.Uptime),
-		)
-	}
-	w.Flush()
-
-	useColor := stdoutIsTerminal()
-	out := cmd.OutOrStdout()
-	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
-	for i, line := range lines {
-		if useColor && i > 0 && cts[i-1].Template == 1 {
-			fmt.Fprintf(out, "%s%s%s\n", colorRed, line, colorReset)
-		} else {
-			fmt.Fprintln(out, line)
+			tmpl, color = "yes", colorRed
 		}
+		t.rows = append(t.rows, listRow{
+			line: fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+				ct.VMID,
+				ct.Name,
+				ct.Node,
+				ct.Status,
+				tmpl,
+				formatBytes(ct.MaxMem),
+				formatBytes(ct.MaxDisk),
+				formatUptime(ct.Uptime),
+			),
+			key:   ct.ID,
+			sig:   fmt.Sprintf("%s\t%s\t%s", ct.Status, formatBytes(ct.MaxMem), formatBytes(ct.MaxDisk)),
+			color: color,
+		})
 	}
-	return nil
+	return t
 }
 
 func ctStartCmd() *cobra.Command {
@@ -133,6 +149,9 @@ func ctStartCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Starting container %d...\n", ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -166,6 +185,9 @@ func ctStopCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Stopping container %d...\n", ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -199,6 +221,9 @@ func ctRebootCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Rebooting container %d...\n", ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -280,6 +305,9 @@ func ctShutdownCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Shutting down container %d...\n", ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -313,6 +341,9 @@ func ctDeleteCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Deleting container %d...\n", ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -352,6 +383,9 @@ func ctCloneCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Cloning container %d to %q (ID %d)...\n", ctid, name, clonedID)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -452,6 +486,9 @@ size is a delta with a unit suffix, e.g. 10G, 512M. The '+' prefix is added auto
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Resizing disk %s on container %d by %s...\n", disk, ctid, size)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -532,6 +569,9 @@ The source volume is deleted after the move by default; use --delete=false to ke
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Moving volume %s on container %d to %q...\n", disk, ctid, storage)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -625,6 +665,9 @@ func ctTagAddCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
 			}
@@ -658,6 +701,9 @@ func ctTagRemoveCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
 			}
@@ -688,7 +734,8 @@ func ctConfigCmd() *cobra.Command {
 		Long: `View or modify a container's configuration.
 
 Without modification flags, displays the current config.
-With flags, updates the specified configuration options.`,
+With flags, updates the specified configuration options. The get, set
+and unset subcommands read and change any other option.`,
 		Args: cobra.ExactArgs(1),
 		Example: `  pxve ct config 101
   pxve ct config 101 --output json
@@ -743,6 +790,9 @@ With flags, updates the specified configuration options.`,
 				if err != nil {
 					return handleErr(err)
 				}
+				if detachTask(cmd, task) {
+					return nil
+				}
 				fmt.Fprintf(cmd.OutOrStdout(), "Updating container %d config...\n", ctid)
 				if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 					return handleErr(err)
@@ -789,6 +839,8 @@ With flags, updates the specified configuration options.`,
 	cmd.Flags().BoolVar(&noOnboot, "no-onboot", false, "disable start on boot")
 	cmd.Flags().BoolVar(&protection, "protection", false, "enable protection")
 	cmd.Flags().BoolVar(&noProtect, "no-protection", false, "disable protection")
+	cmd.AddCommand(guestConfigGetCmd("lxc"), guestConfigSetCmd("lxc"), guestConfigUnsetCmd("lxc"),
+		guestConfigExportCmd("lxc"), guestConfigImportCmd("lxc"))
 	return cmd
 }
 
@@ -801,6 +853,7 @@ func ctSnapshotCmd() *cobra.Command {
 	cmd.AddCommand(ctSnapshotCreateCmd())
 	cmd.AddCommand(ctSnapshotRollbackCmd())
 	cmd.AddCommand(ctSnapshotDeleteCmd())
+	cmd.AddCommand(snapshotEditCmd("lxc"))
 	return cmd
 }
 
@@ -832,16 +885,7 @@ func ctSnapshotListCmd() *cobra.Command {
 				return enc.Encode(snaps)
 			}
 
-			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "NAME\tPARENT\tCREATED\tDESCRIPTION")
-			for _, s := range snaps {
-				created := "-"
-				if s.SnapshotCreationTime > 0 {
-					created = time.Unix(s.SnapshotCreationTime, 0).Format("2006-01-02 15:04:05")
-				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.Parent, created, s.Description)
-			}
-			return w.Flush()
+			return printSnapshotTree(cmd.OutOrStdout(), snaps)
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
@@ -850,6 +894,7 @@ func ctSnapshotListCmd() *cobra.Command {
 
 func ctSnapshotCreateCmd() *cobra.Command {
 	var nodeName string
+	var opts actions.SnapshotOptions
 	cmd := &cobra.Command{
 		Use:   "create <ctid> <name>",
 		Short: "Create a snapshot of a container",
@@ -865,11 +910,14 @@ func ctSnapshotCreateCmd() *cobra.Command {
 			}
 			ctx := context.Background()
 			s := startSpinner("Connecting...")
-			task, err := actions.CreateContainerSnapshot(ctx, proxmoxClient, ctid, nodeName, snapName)
+			task, err := actions.CreateContainerSnapshot(ctx, proxmoxClient, ctid, nodeName, snapName, opts)
 			s.Stop()
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Creating snapshot %q for container %d...\n", snapName, ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -879,6 +927,7 @@ func ctSnapshotCreateCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().StringVar(&opts.Description, "description", "", "snapshot description")
 	return cmd
 }
 
@@ -904,6 +953,9 @@ func ctSnapshotRollbackCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Rolling back container %d to snapshot %q...\n", ctid, snapName)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -938,6 +990,9 @@ func ctSnapshotDeleteCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Deleting snapshot %q for container %d...\n", snapName, ctid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
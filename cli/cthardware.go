@@ -0,0 +1,486 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// ctMountPointCmd groups mount point sub-commands.
+func ctMountPointCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "mp",
+		Aliases: []string{"mountpoint"},
+		Short:   "Add, resize, or remove container mount points",
+	}
+	cmd.AddCommand(ctMPAddCmd(), ctMPResizeCmd(), guestDeviceRemoveCmd("lxc", "mp", "mount point"))
+	return cmd
+}
+
+// isBindMount reports whether a mount point value mounts a host directory
+// rather than a storage volume.
+func isBindMount(value string) bool {
+	return strings.HasPrefix(value, "/")
+}
+
+func ctMPAddCmd() *cobra.Command {
+	var (
+		o        configEditOpts
+		storage  string
+		size     string
+		bind     string
+		slot     int
+		readOnly bool
+		backup   bool
+		quota    bool
+		shared   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add <ctid> <path> (--storage <storage> --size <size> | --bind <host-dir>)",
+		Short: "Add a mount point to a container",
+		Long: `Mount a new storage volume (--storage and --size) or a host directory
+(--bind) at path inside the container.
+
+Bind mounts can only be added by root@pam. In unprivileged containers the
+host directory must be accessible to the mapped UIDs (100000 and up).`,
+		Args: cobra.ExactArgs(2),
+		Example: `  pxve ct mp add 101 /srv/data --storage local-lvm --size 20G --backup
+  pxve ct mp add 101 /mnt/media --bind /tank/media --ro
+  pxve ct mp add 101 /mnt/shared --bind /mnt/pve/cephfs/shared --shared`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			mountPath := args[1]
+			if !path.IsAbs(mountPath) {
+				return fmt.Errorf("mount path %q must be absolute", mountPath)
+			}
+			var p actions.PropertyString
+			switch {
+			case bind != "" && (storage != "" || size != ""):
+				return fmt.Errorf("--bind cannot be combined with --storage or --size")
+			case bind != "":
+				if !path.IsAbs(bind) {
+					return fmt.Errorf("bind mount source %q must be an absolute host path", bind)
+				}
+				if backup || quota {
+					return fmt.Errorf("--backup and --quota only apply to storage volumes")
+				}
+				p = actions.PropertyString{{Value: bind}}
+			case storage != "" && size != "":
+				gib, err := diskSizeGiB(size)
+				if err != nil {
+					return err
+				}
+				if shared {
+					return fmt.Errorf("--shared only applies to bind mounts")
+				}
+				p = actions.PropertyString{{Value: storage + ":" + gib}}
+			default:
+				return fmt.Errorf("give --storage and --size for a new volume, or --bind for a host directory")
+			}
+			p = p.Set("mp", mountPath)
+			if readOnly {
+				p = p.Set("ro", "1")
+			}
+			if backup {
+				p = p.Set("backup", "1")
+			}
+			if quota {
+				p = p.Set("quota", "1")
+			}
+			if shared {
+				p = p.Set("shared", "1")
+			}
+			return editGuestConfig(cmd, "lxc", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				for k, v := range cfg.Values {
+					if k == "rootfs" || strings.HasPrefix(k, "mp") {
+						if mountPointPath(v) == mountPath {
+							return nil, nil, fmt.Errorf("%s is already mounted at %s", k, mountPath)
+						}
+					}
+				}
+				key, err := deviceSlot(cfg, "mp", 256, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&storage, "storage", "", "storage to allocate a new volume on")
+	cmd.Flags().StringVar(&size, "size", "", "size of a new volume, e.g. 8G, 512M, 1T")
+	cmd.Flags().StringVar(&bind, "bind", "", "host directory to bind mount instead of a volume")
+	cmd.Flags().IntVar(&slot, "slot", -1, "mount point number, mpN (default: first free)")
+	cmd.Flags().BoolVar(&readOnly, "ro", false, "mount read-only")
+	cmd.Flags().BoolVar(&backup, "backup", false, "include the volume in backups")
+	cmd.Flags().BoolVar(&quota, "quota", false, "enable user quotas inside the container")
+	cmd.Flags().BoolVar(&shared, "shared", false, "mark a bind mount as available on all nodes (allows migration)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// mountPointPath returns the container path of a rootfs or mpN value.
+func mountPointPath(value string) string {
+	if strings.HasPrefix(value, "mp=") || strings.Contains(value, ",mp=") {
+		return actions.ParsePropertyString(value).Get("mp")
+	}
+	return "/" // rootfs
+}
+
+func ctMPResizeCmd() *cobra.Command {
+	var nodeName string
+	cmd := &cobra.Command{
+		Use:   "resize <ctid> <mpN> <size>",
+		Short: "Grow a container mount point volume",
+		Long: `Grow a mount point volume by the specified size delta, e.g. 10G or 512M.
+The '+' prefix is added automatically. Bind mounts cannot be resized.`,
+		Args:    cobra.ExactArgs(3),
+		Example: `  pxve ct mp resize 101 mp0 10G`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := args[1]
+			size, err := normalizeDiskSize(args[2])
+			if err != nil {
+				return err
+			}
+			cfg, err := loadGuestConfig(cmd, "lxc", args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			if err := requireConfigKey(cfg, key, "mp"); err != nil {
+				return err
+			}
+			if isBindMount(cfg.Values[key]) {
+				return fmt.Errorf("%s is a bind mount and cannot be resized", key)
+			}
+
+			ctx := context.Background()
+			s := startSpinner("Resizing mount point...")
+			task, err := actions.ResizeContainerDisk(ctx, proxmoxClient, cfg.VMID, cfg.Node, key, size)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if detachTask(cmd, task) {
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Resizing %s on container %d by %s...\n", key, cfg.VMID, size)
+			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Mount point %s resized.\n", key)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+// ctNICCmd groups container network interface sub-commands.
+func ctNICCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "net",
+		Short: "Add, change, or remove container network interfaces",
+	}
+	cmd.AddCommand(ctNICAddCmd(), ctNICSetCmd(), guestDeviceRemoveCmd("lxc", "net", "network interface"))
+	return cmd
+}
+
+// ctNICFlags are the network interface settings of ct net add and set.
+type ctNICFlags struct {
+	name     string
+	bridge   string
+	mac      string
+	ip       string
+	gw       string
+	ip6      string
+	gw6      string
+	tag      int
+	firewall bool
+	rate     string
+	mtu      int
+	linkDown bool
+}
+
+func addCTNICFlags(cmd *cobra.Command, f *ctNICFlags) {
+	cmd.Flags().StringVar(&f.name, "name", "", "interface name inside the container (default: ethN)")
+	cmd.Flags().StringVar(&f.bridge, "bridge", "", "bridge or SDN vnet to attach to")
+	cmd.Flags().StringVar(&f.mac, "mac", "", "MAC address (default: generated)")
+	cmd.Flags().StringVar(&f.ip, "ip", "", "IPv4 address in CIDR notation, dhcp, or manual (empty removes it)")
+	cmd.Flags().StringVar(&f.gw, "gw", "", "IPv4 gateway (empty removes it)")
+	cmd.Flags().StringVar(&f.ip6, "ip6", "", "IPv6 address in CIDR notation, dhcp, auto, or manual (empty removes it)")
+	cmd.Flags().StringVar(&f.gw6, "gw6", "", "IPv6 gateway (empty removes it)")
+	cmd.Flags().IntVar(&f.tag, "tag", 0, "VLAN tag (0 removes it)")
+	cmd.Flags().BoolVar(&f.firewall, "firewall", false, "enable the Proxmox firewall on this interface")
+	cmd.Flags().StringVar(&f.rate, "rate", "", "rate limit in MB/s (0 removes it)")
+	cmd.Flags().IntVar(&f.mtu, "mtu", 0, "MTU (0 removes it)")
+	cmd.Flags().BoolVar(&f.linkDown, "link-down", false, "disconnect the interface")
+}
+
+// apply sets the flags that were given on a network interface property
+// string and validates the resulting addressing.
+func (f *ctNICFlags) apply(cmd *cobra.Command, p actions.PropertyString) (actions.PropertyString, error) {
+	changed := cmd.Flags().Changed
+	setOrDel := func(flag, key, value string, del bool) {
+		if !changed(flag) {
+			return
+		}
+		if del {
+			p = p.Del(key)
+		} else {
+			p = p.Set(key, value)
+		}
+	}
+	setOrDel("name", "name", f.name, false)
+	setOrDel("bridge", "bridge", f.bridge, false)
+	setOrDel("mac", "hwaddr", f.mac, f.mac == "")
+	setOrDel("ip", "ip", f.ip, f.ip == "")
+	setOrDel("gw", "gw", f.gw, f.gw == "")
+	setOrDel("ip6", "ip6", f.ip6, f.ip6 == "")
+	setOrDel("gw6", "gw6", f.gw6, f.gw6 == "")
+	setOrDel("tag", "tag", strconv.Itoa(f.tag), f.tag == 0)
+	setOrDel("firewall", "firewall", "1", !f.firewall)
+	setOrDel("rate", "rate", f.rate, f.rate == "" || f.rate == "0")
+	setOrDel("mtu", "mtu", strconv.Itoa(f.mtu), f.mtu == 0)
+	setOrDel("link-down", "link_down", "1", !f.linkDown)
+
+	if err := checkCTAddress("ip", p.Get("ip"), p.Get("gw"), "dhcp", "manual"); err != nil {
+		return nil, err
+	}
+	if err := checkCTAddress("ip6", p.Get("ip6"), p.Get("gw6"), "dhcp", "auto", "manual"); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// checkCTAddress validates an ip/ip6 setting: a CIDR or one of the keywords.
+// A gateway is only valid with a static address.
+func checkCTAddress(key, addr, gw string, keywords ...string) error {
+	static := false
+	switch {
+	case addr == "":
+	case containsString(keywords, addr):
+	default:
+		if _, _, err := net.ParseCIDR(addr); err != nil {
+			return fmt.Errorf("invalid %s %q (expected CIDR, e.g. 10.0.0.5/24, or %s)", key, addr, strings.Join(keywords, ", "))
+		}
+		static = true
+	}
+	if gw == "" {
+		return nil
+	}
+	if !static {
+		return fmt.Errorf("a gateway needs a static %s address", key)
+	}
+	if net.ParseIP(gw) == nil {
+		return fmt.Errorf("invalid gateway %q", gw)
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func ctNICAddCmd() *cobra.Command {
+	var (
+		o    configEditOpts
+		nic  ctNICFlags
+		slot int
+	)
+	cmd := &cobra.Command{
+		Use:   "add <ctid> --bridge <bridge>",
+		Short: "Add a network interface to a container",
+		Args:  cobra.ExactArgs(1),
+		Example: `  pxve ct net add 101 --bridge vmbr0 --ip dhcp --firewall
+  pxve ct net add 101 --bridge vmbr1 --ip 10.0.20.5/24 --gw 10.0.20.1 --tag 20
+  pxve ct net add 101 --bridge web --ip 10.1.0.5/24 --name web0   # SDN vnet`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if nic.bridge == "" {
+				return fmt.Errorf("--bridge is required")
+			}
+			return editGuestConfig(cmd, "lxc", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key, err := deviceSlot(cfg, "net", 32, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				name := nic.name
+				if name == "" {
+					name = "eth" + strings.TrimPrefix(key, "net")
+				}
+				for k, v := range cfg.Values {
+					if strings.HasPrefix(k, "net") && actions.ParsePropertyString(v).Get("name") == name {
+						return nil, nil, fmt.Errorf("%s already uses the name %s", k, name)
+					}
+				}
+				p, err := nic.apply(cmd, actions.PropertyString{{Key: "name", Value: name}})
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	addCTNICFlags(cmd, &nic)
+	cmd.Flags().IntVar(&slot, "slot", -1, "interface number, netN (default: first free)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+func ctNICSetCmd() *cobra.Command {
+	var (
+		o   configEditOpts
+		nic ctNICFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "set <ctid> <netN>",
+		Short: "Change a container network interface",
+		Long:  `Change settings of a network interface. Settings not given are kept.`,
+		Args:  cobra.ExactArgs(2),
+		Example: `  pxve ct net set 101 net0 --ip 10.0.0.6/24 --gw 10.0.0.1
+  pxve ct net set 101 net0 --ip dhcp --gw ""           # back to DHCP
+  pxve ct net set 101 net1 --tag 0 --rate 0            # remove tag and rate limit`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if countChanged(cmd, "name", "bridge", "mac", "ip", "gw", "ip6", "gw6", "tag", "firewall", "rate", "mtu", "link-down") == 0 {
+				return fmt.Errorf("nothing to change")
+			}
+			if cmd.Flags().Changed("name") && nic.name == "" {
+				return fmt.Errorf("--name cannot be empty")
+			}
+			if cmd.Flags().Changed("bridge") && nic.bridge == "" {
+				return fmt.Errorf("--bridge cannot be empty")
+			}
+			return editGuestConfig(cmd, "lxc", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key := args[1]
+				if err := requireConfigKey(cfg, key, "net"); err != nil {
+					return nil, nil, err
+				}
+				p, err := nic.apply(cmd, actions.ParsePropertyString(cfg.Values[key]))
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	addCTNICFlags(cmd, &nic)
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// ctFeatures is the decoded "features" option of a container.
+type ctFeatures struct {
+	Nesting bool     `json:"nesting"`
+	Keyctl  bool     `json:"keyctl"`
+	Fuse    bool     `json:"fuse"`
+	Mount   []string `json:"mount"`
+}
+
+func parseCTFeatures(value string) ctFeatures {
+	p := actions.ParsePropertyString(value)
+	f := ctFeatures{
+		Nesting: p.Get("nesting") == "1",
+		Keyctl:  p.Get("keyctl") == "1",
+		Fuse:    p.Get("fuse") == "1",
+		Mount:   []string{},
+	}
+	if m := p.Get("mount"); m != "" {
+		f.Mount = strings.Split(m, ";")
+	}
+	return f
+}
+
+func ctFeaturesCmd() *cobra.Command {
+	var (
+		o       configEditOpts
+		nesting bool
+		keyctl  bool
+		fuse    bool
+		mount   string
+	)
+	cmd := &cobra.Command{
+		Use:   "features <ctid>",
+		Short: "View or toggle container features",
+		Long: `View or toggle LXC features of a container.
+
+Without flags, shows the current features. --nesting, --keyctl and --fuse
+take an optional =false to turn a feature off; --mount sets the allowed
+filesystem types, separated by ';' (empty removes them). Changes take
+effect at the next container start.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve ct features 101
+  pxve ct features 101 --nesting --keyctl        # e.g. for Docker in an unprivileged CT
+  pxve ct features 101 --fuse=false
+  pxve ct features 101 --mount "nfs;cifs"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if countChanged(cmd, "nesting", "keyctl", "fuse", "mount") == 0 {
+				cfg, err := loadGuestConfig(cmd, "lxc", args[0], o.nodeName)
+				if err != nil {
+					return err
+				}
+				return printCTFeatures(cmd, parseCTFeatures(cfg.Values["features"]))
+			}
+			return editGuestConfig(cmd, "lxc", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				if keyctl && cfg.Values["unprivileged"] != "1" {
+					return nil, nil, fmt.Errorf("keyctl only applies to unprivileged containers")
+				}
+				p := actions.ParsePropertyString(cfg.Values["features"])
+				toggle := func(flag string, on bool) {
+					if !cmd.Flags().Changed(flag) {
+						return
+					}
+					if on {
+						p = p.Set(flag, "1")
+					} else {
+						p = p.Del(flag)
+					}
+				}
+				toggle("nesting", nesting)
+				toggle("keyctl", keyctl)
+				toggle("fuse", fuse)
+				if cmd.Flags().Changed("mount") {
+					if mount == "" {
+						p = p.Del("mount")
+					} else {
+						p = p.Set("mount", strings.Trim(strings.ReplaceAll(mount, ",", ";"), ";"))
+					}
+				}
+				if len(p) == 0 {
+					return nil, []string{"features"}, nil
+				}
+				return map[string]string{"features": p.String()}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&nesting, "nesting", false, "allow nested containers (e.g. Docker, systemd features)")
+	cmd.Flags().BoolVar(&keyctl, "keyctl", false, "allow the keyctl() system call (unprivileged only)")
+	cmd.Flags().BoolVar(&fuse, "fuse", false, "allow FUSE filesystems")
+	cmd.Flags().StringVar(&mount, "mount", "", "filesystem types the container may mount, e.g. nfs;cifs")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+func printCTFeatures(cmd *cobra.Command, f ctFeatures) error {
+	if flagOutput == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(f)
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Nesting:\t%s\n", yesNoBool(f.Nesting))
+	fmt.Fprintf(w, "Keyctl:\t%s\n", yesNoBool(f.Keyctl))
+	fmt.Fprintf(w, "FUSE:\t%s\n", yesNoBool(f.Fuse))
+	fmt.Fprintf(w, "Mount:\t%s\n", dash(strings.Join(f.Mount, ", ")))
+	return w.Flush()
+}
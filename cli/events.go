@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/events"
+)
+
+func eventsCmd() *cobra.Command {
+	var (
+		since     string
+		interval  time.Duration
+		threshold float64
+		types     []string
+	)
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Stream cluster state changes as NDJSON",
+		Long: `Poll cluster resources and tasks and print one JSON object per line for
+every change until Ctrl-C.
+
+Event types:
+  guest.started, guest.stopped, guest.created, guest.removed
+  task.started, task.failed
+  node.offline, node.online
+  storage.above_threshold, storage.below_threshold
+
+The first poll is the baseline. With --since, tasks started within that
+window (a duration such as 15m, or an RFC 3339 time) are replayed first.`,
+		Example: `  pxve events
+  pxve events --since 1h --type task.failed,node.offline
+  pxve events --storage-threshold 85 | jq -c 'select(.type | startswith("storage."))'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var sinceTime time.Time
+			if since != "" {
+				t, err := parseSince(since)
+				if err != nil {
+					return err
+				}
+				sinceTime = t
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			if threshold < 0 || threshold > 100 {
+				return fmt.Errorf("--storage-threshold must be between 0 and 100")
+			}
+			wanted := make(map[string]bool, len(types))
+			for _, t := range types {
+				if !containsType(t) {
+					return fmt.Errorf("unknown event type %q (valid: %s)", t, strings.Join(events.Types, ", "))
+				}
+				wanted[t] = true
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			emit := func(evs []events.Event) error {
+				for _, e := range evs {
+					if len(wanted) > 0 && !wanted[e.Type] {
+						continue
+					}
+					if err := enc.Encode(e); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			var prev *events.Snapshot
+			for {
+				cur, err := events.Take(ctx, proxmoxClient)
+				if ctx.Err() != nil {
+					return nil
+				}
+				switch {
+				case err != nil:
+					// Keep the last good snapshot so nothing is lost across a
+					// transient failure.
+					fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", handleErr(err))
+				case prev == nil && !sinceTime.IsZero():
+					if err := emit(events.TasksSince(cur, sinceTime)); err != nil {
+						return err
+					}
+					prev = cur
+				default:
+					if err := emit(events.Diff(prev, cur, threshold/100)); err != nil {
+						return err
+					}
+					prev = cur
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(interval):
+				}
+			}
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "", "replay tasks started within this window first (e.g. 30m, or an RFC 3339 time)")
+	cmd.Flags().DurationVar(&interval, "interval", 5*time.Second, "poll interval")
+	cmd.Flags().Float64Var(&threshold, "storage-threshold", 90, "storage usage percentage whose crossing is reported (0 disables)")
+	cmd.Flags().StringSliceVar(&types, "type", nil, "only emit these comma-separated event types")
+	return cmd
+}
+
+// parseSince accepts a duration before now ("15m", "2h") or an RFC 3339 time.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: expected a duration (e.g. 30m) or an RFC 3339 time", s)
+}
+
+func containsType(t string) bool {
+	for _, v := range events.Types {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// defaultFactsMaxAge is how long collected facts are reused.
+const defaultFactsMaxAge = 15 * time.Minute
+
+func vmFactsCmd() *cobra.Command {
+	var (
+		nodeName, selector string
+		maxAge             time.Duration
+		refresh            bool
+	)
+	cmd := &cobra.Command{
+		Use:   "facts [<vmid>]",
+		Short: "Show guest inventory facts gathered through the guest agent",
+		Long: `Gather OS, hostname, time zone, logged-in users, filesystems with usage, and
+network interfaces from the guest agent of one VM, or of every running VM
+matching --selector.
+
+Facts are cached locally and reused for --max-age; --refresh queries the
+agents regardless. With --output json, each VM is a normalized document
+(an array for --selector); sections the agent could not answer are listed
+under "errors".`,
+		Args: cobra.MaximumNArgs(1),
+		Example: `  pxve vm facts 100
+  pxve vm facts --selector tag=web --output json
+  pxve vm facts 100 --refresh`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) == (selector != "") {
+				return fmt.Errorf("specify either a VMID or --selector")
+			}
+			expr := selector
+			if len(args) == 1 {
+				if _, err := strconv.Atoi(args[0]); err != nil {
+					return fmt.Errorf("invalid VMID %q", args[0])
+				}
+				expr = "vmid=" + args[0]
+				if nodeName != "" {
+					expr += ",node=" + nodeName
+				}
+			}
+			sel, err := actions.ParseSelector(expr)
+			if err != nil {
+				return err
+			}
+			if refresh {
+				maxAge = 0
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Gathering facts...")
+			guests, err := actions.SelectGuests(ctx, proxmoxClient, sel)
+			if err != nil {
+				s.Stop()
+				return handleErr(err)
+			}
+			var vms proxmox.ClusterResources
+			var skipped []string
+			for _, g := range guests {
+				if len(args) == 1 {
+					if g.Type != "qemu" {
+						s.Stop()
+						return fmt.Errorf("%d is a container; facts need the QEMU guest agent", g.VMID)
+					}
+					vms = append(vms, g)
+				} else if g.Type == "qemu" && g.Status == "running" && g.Template == 0 {
+					vms = append(vms, g)
+				} else {
+					skipped = append(skipped, strconv.FormatUint(g.VMID, 10))
+				}
+			}
+			if len(args) == 1 && len(vms) == 0 {
+				s.Stop()
+				return fmt.Errorf("VM %s not found", args[0])
+			}
+			facts := actions.CollectFacts(ctx, proxmoxClient, resolvedInstURL, vms, maxAge)
+			s.Stop()
+
+			out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+			if len(skipped) > 0 {
+				fmt.Fprintf(errOut, "Skipping %d matching guest(s) that are not running VMs: %s\n", len(skipped), strings.Join(skipped, ", "))
+			}
+			failed := 0
+			for _, f := range facts {
+				if f.Error != "" {
+					failed++
+				}
+			}
+
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				var err error
+				if len(args) == 1 {
+					err = enc.Encode(facts[0])
+				} else {
+					err = enc.Encode(facts)
+				}
+				if err != nil {
+					return err
+				}
+			} else if len(args) == 1 {
+				if facts[0].Error != "" {
+					return handleErr(fmt.Errorf("%s", facts[0].Error))
+				}
+				if err := printFacts(out, facts[0]); err != nil {
+					return err
+				}
+			} else if len(facts) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(out, "%sNo running VMs match the selector.%s\n", colorGold, colorReset)
+				} else {
+					fmt.Fprintln(out, "No running VMs match the selector.")
+				}
+				return nil
+			} else if err := printFactsTable(out, errOut, facts); err != nil {
+				return err
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("could not gather facts from %d of %d VMs", failed, len(facts))
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	addSelectorFlag(cmd, &selector)
+	cmd.Flags().DurationVar(&maxAge, "max-age", defaultFactsMaxAge, "reuse cached facts up to this old")
+	cmd.Flags().BoolVar(&refresh, "refresh", false, "ignore cached facts and query the guest agents")
+	return cmd
+}
+
+// printFacts prints the facts of one VM as sections.
+func printFacts(out io.Writer, f *actions.VMFacts) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "VM:\t%d (%s) on %s\n", f.VMID, f.Name, f.Node)
+	fmt.Fprintf(w, "Collected:\t%s\n", formatAge(f.CollectedAt))
+	fmt.Fprintf(w, "Hostname:\t%s\n", dash(f.Hostname))
+	if f.OS != nil {
+		fmt.Fprintf(w, "OS:\t%s\n", dash(factsOSName(f.OS)))
+		fmt.Fprintf(w, "Kernel:\t%s (%s)\n", dash(f.OS.Kernel), dash(f.OS.Arch))
+	}
+	if tz := f.Timezone; tz != nil {
+		off := time.Duration(tz.OffsetSeconds) * time.Second
+		sign := "+"
+		if off < 0 {
+			sign, off = "-", -off
+		}
+		fmt.Fprintf(w, "Timezone:\t%s (UTC%s%02d:%02d)\n", dash(tz.Zone), sign, int(off.Hours()), int(off.Minutes())%60)
+	}
+	var users []string
+	for _, u := range f.Users {
+		users = append(users, fmt.Sprintf("%s (since %s)", u.Name, u.LoginTime.Local().Format("2006-01-02 15:04")))
+	}
+	fmt.Fprintf(w, "Users:\t%s\n", dash(strings.Join(users, ", ")))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(f.Filesystems) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "MOUNTPOINT\tTYPE\tDEVICE\tSIZE\tUSED\tUSE%")
+		for _, fs := range f.Filesystems {
+			size, used, pct := "-", "-", "-"
+			if fs.TotalBytes > 0 {
+				size, used = formatBytes(fs.TotalBytes), formatBytes(fs.UsedBytes)
+				pct = fmt.Sprintf("%.0f%%", float64(fs.UsedBytes)*100/float64(fs.TotalBytes))
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", fs.Mountpoint, fs.Type, dash(fs.Device), size, used, pct)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(f.Interfaces) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "INTERFACE\tMAC\tIPV4\tIPV6")
+		for _, iface := range f.Interfaces {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", iface.Name, dash(iface.MAC), dash(strings.Join(iface.IPv4, ", ")), dash(strings.Join(iface.IPv6, ", ")))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(f.Errors) > 0 {
+		sections := make([]string, 0, len(f.Errors))
+		for section := range f.Errors {
+			sections = append(sections, section)
+		}
+		sort.Strings(sections)
+		fmt.Fprintln(out)
+		for _, section := range sections {
+			fmt.Fprintf(out, "%s unavailable: %s\n", capitalize(section), f.Errors[section])
+		}
+	}
+	return nil
+}
+
+// printFactsTable prints one line per VM; VMs whose facts could not be
+// gathered are reported on errOut.
+func printFactsTable(out, errOut io.Writer, facts []*actions.VMFacts) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VMID\tNAME\tHOSTNAME\tOS\tIPV4\tCOLLECTED")
+	for _, f := range facts {
+		if f.Error != "" {
+			fmt.Fprintf(errOut, "VM %d (%s): %s\n", f.VMID, f.Name, f.Error)
+			continue
+		}
+		var ips []string
+		for _, iface := range f.Interfaces {
+			for _, ip := range iface.IPv4 {
+				ips = append(ips, strings.SplitN(ip, "/", 2)[0])
+			}
+		}
+		osName := ""
+		if f.OS != nil {
+			osName = factsOSName(f.OS)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", f.VMID, f.Name, dash(f.Hostname), dash(osName), dash(strings.Join(ips, ", ")), formatAge(f.CollectedAt))
+	}
+	return w.Flush()
+}
+
+func factsOSName(o *actions.FactsOS) string {
+	if o.PrettyName != "" {
+		return o.PrettyName
+	}
+	return strings.TrimSpace(o.Name + " " + o.Version)
+}
+
+// formatAge describes how long ago t was, e.g. "3m ago".
+func formatAge(t time.Time) string {
+	d := time.Since(t)
+	if d < time.Minute {
+		return "just now"
+	}
+	return formatUptime(uint64(d.Seconds())) + " ago"
+}
@@ -0,0 +1,1163 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+	"github.com/chupakbra/proxmox-cli/internal/firewall"
+)
+
+// firewallScopeFlags are the persistent --cluster / --node / --vmid / --group
+// flags shared by every firewall subcommand.
+type firewallScopeFlags struct {
+	cluster bool
+	node    string
+	vmid    int
+	group   string
+}
+
+// resolve returns the selected scope. Exactly one of --cluster, --node,
+// --vmid and --group must be given, except that --node may accompany --vmid
+// as a location hint. --group is only accepted when allowGroup is set.
+func (f *firewallScopeFlags) resolve(ctx context.Context, allowGroup bool) (actions.FirewallScope, error) {
+	n := 0
+	for _, set := range []bool{f.cluster, f.node != "" && f.vmid == 0, f.vmid != 0, f.group != ""} {
+		if set {
+			n++
+		}
+	}
+	switch {
+	case f.group != "" && !allowGroup:
+		return actions.FirewallScope{}, fmt.Errorf("--group only applies to firewall rules")
+	case n == 0 && allowGroup:
+		return actions.FirewallScope{}, fmt.Errorf("specify one of --cluster, --node, --vmid, or --group")
+	case n == 0:
+		return actions.FirewallScope{}, fmt.Errorf("specify one of --cluster, --node, or --vmid")
+	case n > 1:
+		return actions.FirewallScope{}, fmt.Errorf("--cluster, --node, --vmid, and --group are mutually exclusive")
+	}
+	switch {
+	case f.vmid != 0:
+		return actions.GuestFirewall(ctx, proxmoxClient, f.vmid, f.node)
+	case f.node != "":
+		return actions.FirewallScope{Node: f.node}, nil
+	case f.group != "":
+		return actions.FirewallScope{Group: f.group}, nil
+	default:
+		return actions.ClusterFirewall, nil
+	}
+}
+
+func firewallCmd() *cobra.Command {
+	scope := &firewallScopeFlags{}
+	cmd := &cobra.Command{
+		Use:     "firewall",
+		Aliases: []string{"fw"},
+		Short:   "Manage cluster, node, and guest firewalls",
+		Long: `Manage firewall rules, options, IP sets, aliases, and security groups.
+
+Every subcommand works on one scope, selected with --cluster, --node <node>,
+or --vmid <id> (VMs and containers). Rule commands also accept --group <name>
+to edit the rules of a cluster security group.`,
+	}
+	cmd.PersistentFlags().BoolVar(&scope.cluster, "cluster", false, "datacenter firewall")
+	cmd.PersistentFlags().StringVar(&scope.node, "node", "", "node firewall (or the node of --vmid)")
+	cmd.PersistentFlags().IntVar(&scope.vmid, "vmid", 0, "VM or container firewall")
+	cmd.PersistentFlags().StringVar(&scope.group, "group", "", "security group (rule commands only)")
+
+	cmd.AddCommand(firewallRulesCmd(scope))
+	cmd.AddCommand(firewallOptionsCmd(scope))
+	cmd.AddCommand(firewallIPSetCmd(scope))
+	cmd.AddCommand(firewallAliasCmd(scope))
+	cmd.AddCommand(firewallGroupCmd())
+	cmd.AddCommand(firewallExportCmd(scope))
+	cmd.AddCommand(firewallSyncCmd(scope))
+	return cmd
+}
+
+// ---------------------------------------------------------------------------
+// Rules
+// ---------------------------------------------------------------------------
+
+func firewallRulesCmd(scope *firewallScopeFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rules",
+		Short: "List, add, delete, and reorder firewall rules",
+	}
+	cmd.AddCommand(firewallRulesListCmd(scope))
+	cmd.AddCommand(firewallRulesAddCmd(scope))
+	cmd.AddCommand(firewallRulesDeleteCmd(scope))
+	cmd.AddCommand(firewallRulesMoveCmd(scope))
+	return cmd
+}
+
+func firewallRulesListCmd(scope *firewallScopeFlags) *cobra.Command {
+	var effective bool
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List rules in evaluation order",
+		Long: `List rules in evaluation order. With --effective (guests only), security
+group references are expanded in place so the output shows every rule the
+guest's traffic is matched against.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading firewall rules...")
+			fs, err := scope.resolve(ctx, true)
+			if err != nil {
+				s.Stop()
+				return handleErr(err)
+			}
+			if effective && !fs.IsGuest() {
+				s.Stop()
+				return fmt.Errorf("--effective requires --vmid")
+			}
+			var rules []actions.EffectiveFirewallRule
+			if effective {
+				rules, err = actions.EffectiveGuestFirewall(ctx, proxmoxClient, fs)
+			} else {
+				var plain []actions.FirewallRule
+				plain, err = actions.FirewallRules(ctx, proxmoxClient, fs)
+				for _, r := range plain {
+					rules = append(rules, actions.EffectiveFirewallRule{FirewallRule: r})
+				}
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				out := make([]interface{}, len(rules))
+				for i, r := range rules {
+					if effective {
+						out[i] = struct {
+							actions.FirewallRule
+							Group string `json:"from_group,omitempty"`
+						}{r.FirewallRule, r.Group}
+					} else {
+						out[i] = r.FirewallRule
+					}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(out)
+			}
+
+			if len(rules) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo firewall rules for %s.%s\n", colorGold, fs, colorReset)
+				}
+				return nil
+			}
+			return writeTable(cmd.OutOrStdout(), firewallRuleTable(rules, effective), nil)
+		},
+	}
+	cmd.Flags().BoolVar(&effective, "effective", false, "expand security groups (guests only)")
+	return cmd
+}
+
+func firewallRuleTable(rules []actions.EffectiveFirewallRule, effective bool) listTable {
+	header := "POS\tON\tTYPE\tACTION\tMACRO\tPROTO\tSOURCE\tSPORT\tDEST\tDPORT\tIFACE\tLOG\tCOMMENT"
+	if effective {
+		header = "POS\tFROM\tON\tTYPE\tACTION\tMACRO\tPROTO\tSOURCE\tSPORT\tDEST\tDPORT\tIFACE\tLOG\tCOMMENT"
+	}
+	t := listTable{header: header}
+	for _, r := range rules {
+		on := "yes"
+		color := ""
+		if r.Enable == 0 {
+			on, color = "no", colorDim
+		}
+		cols := []string{strconv.Itoa(r.Pos)}
+		if effective {
+			from := "-"
+			if r.Group != "" {
+				from = r.Group
+				cols[0] = "  " + cols[0]
+			}
+			cols = append(cols, from)
+		}
+		cols = append(cols, on, r.Type, r.Action,
+			dash(r.Macro), dash(r.Proto), dash(r.Source), dash(r.Sport),
+			dash(r.Dest), dash(r.Dport), dash(r.Iface), dash(r.Log), r.Comment)
+		t.rows = append(t.rows, listRow{line: strings.Join(cols, "\t"), color: color})
+	}
+	return t
+}
+
+// dash returns s, or "-" when it is empty, for table cells.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func firewallRulesAddCmd(scope *firewallScopeFlags) *cobra.Command {
+	var (
+		rule    actions.FirewallRule
+		disable bool
+		pos     int
+	)
+	cmd := &cobra.Command{
+		Use:   "add",
+		Short: "Add a rule",
+		Long: `Add a rule, appended to the end of the list unless --pos is given.
+
+--type is in, out, or group. For in/out rules --action is ACCEPT, DROP, or
+REJECT; for group rules it is the security group name.`,
+		Example: `  pxve firewall rules add --vmid 100 --type in --action ACCEPT --macro SSH --source 10.0.0.0/8
+  pxve firewall rules add --cluster --type in --action ACCEPT --proto tcp --dport 8006 --pos 0
+  pxve firewall rules add --vmid 100 --type group --action webservers`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateFirewallRule(rule); err != nil {
+				return err
+			}
+			rule.Enable = 1
+			if disable {
+				rule.Enable = 0
+			}
+			if !cmd.Flags().Changed("pos") {
+				pos = -1
+			} else if pos < 0 {
+				return fmt.Errorf("--pos must not be negative")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Adding firewall rule...")
+			fs, err := scope.resolve(ctx, true)
+			if err == nil {
+				err = actions.AddFirewallRule(ctx, proxmoxClient, fs, rule, pos)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rule added to %s firewall.\n", fs)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&rule.Type, "type", "", "rule direction: in, out, or group (required)")
+	cmd.Flags().StringVar(&rule.Action, "action", "", "ACCEPT, DROP, REJECT, or a security group name (required)")
+	cmd.Flags().StringVar(&rule.Macro, "macro", "", "predefined service macro (e.g. SSH, HTTPS)")
+	cmd.Flags().StringVar(&rule.Proto, "proto", "", "protocol (e.g. tcp, udp, icmp)")
+	cmd.Flags().StringVar(&rule.Source, "source", "", "source address, CIDR, alias, or +ipset")
+	cmd.Flags().StringVar(&rule.Dest, "dest", "", "destination address, CIDR, alias, or +ipset")
+	cmd.Flags().StringVar(&rule.Sport, "sport", "", "source port(s)")
+	cmd.Flags().StringVar(&rule.Dport, "dport", "", "destination port(s)")
+	cmd.Flags().StringVar(&rule.IcmpType, "icmp-type", "", "ICMP type (with --proto icmp)")
+	cmd.Flags().StringVar(&rule.Iface, "iface", "", "network interface (e.g. net0)")
+	cmd.Flags().StringVar(&rule.Log, "log", "", "log level (e.g. nolog, info, warning)")
+	cmd.Flags().StringVar(&rule.Comment, "comment", "", "rule comment")
+	cmd.Flags().BoolVar(&disable, "disable", false, "add the rule disabled")
+	cmd.Flags().IntVar(&pos, "pos", 0, "insert at this position (0 = first; default: append)")
+	return cmd
+}
+
+// validateFirewallRule checks the fields Proxmox requires for every rule.
+func validateFirewallRule(r actions.FirewallRule) error {
+	switch r.Type {
+	case "in", "out":
+		switch r.Action {
+		case "ACCEPT", "DROP", "REJECT":
+		case "":
+			return fmt.Errorf("--action is required")
+		default:
+			return fmt.Errorf("invalid action %q (must be ACCEPT, DROP, or REJECT)", r.Action)
+		}
+	case "group":
+		if r.Action == "" {
+			return fmt.Errorf("--action must name the security group for group rules")
+		}
+	case "":
+		return fmt.Errorf("--type is required")
+	default:
+		return fmt.Errorf("invalid type %q (must be in, out, or group)", r.Type)
+	}
+	return nil
+}
+
+func parseRulePos(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid rule position %q", s)
+	}
+	return n, nil
+}
+
+func firewallRulesDeleteCmd(scope *firewallScopeFlags) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "delete <pos>",
+		Short: "Delete the rule at a position",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pos, err := parseRulePos(args[0])
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading firewall rules...")
+			fs, err := scope.resolve(ctx, true)
+			var rules []actions.FirewallRule
+			if err == nil {
+				rules, err = actions.FirewallRules(ctx, proxmoxClient, fs)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if pos >= len(rules) {
+				return fmt.Errorf("%s firewall has no rule at position %d", fs, pos)
+			}
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Delete rule %d (%s) from %s firewall? [y/N]: ",
+					pos, firewall.Summary(rules[pos]), fs)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			if err := actions.DeleteFirewallRule(ctx, proxmoxClient, fs, pos); err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rule %d deleted from %s firewall.\n", pos, fs)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+// readYes reads a y/yes answer from stdin.
+func readYes(cmd *cobra.Command) bool {
+	var answer string
+	fmt.Fscan(cmd.InOrStdin(), &answer)
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func firewallRulesMoveCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:     "move <pos> <new-pos>",
+		Short:   "Move a rule to a new position",
+		Example: `  pxve firewall rules move 4 0 --vmid 100   # make rule 4 the first rule`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pos, err := parseRulePos(args[0])
+			if err != nil {
+				return err
+			}
+			to, err := parseRulePos(args[1])
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Moving firewall rule...")
+			fs, err := scope.resolve(ctx, true)
+			var rules []actions.FirewallRule
+			if err == nil {
+				rules, err = actions.FirewallRules(ctx, proxmoxClient, fs)
+			}
+			if err == nil {
+				switch {
+				case pos >= len(rules):
+					err = fmt.Errorf("%s firewall has no rule at position %d", fs, pos)
+				case to >= len(rules):
+					err = fmt.Errorf("new position %d is past the last rule (%d)", to, len(rules)-1)
+				case pos != to:
+					err = actions.MoveFirewallRule(ctx, proxmoxClient, fs, pos, to)
+				}
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Rule %d moved to position %d in %s firewall.\n", pos, to, fs)
+			return nil
+		},
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Options
+// ---------------------------------------------------------------------------
+
+func firewallOptionsCmd(scope *firewallScopeFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "options",
+		Short: "Show or change firewall options",
+	}
+	cmd.AddCommand(firewallOptionsShowCmd(scope))
+	cmd.AddCommand(firewallOptionsSetCmd(scope))
+	return cmd
+}
+
+func firewallOptionsShowCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "show",
+		Short: "Show firewall options",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading firewall options...")
+			fs, err := scope.resolve(ctx, false)
+			var opts map[string]interface{}
+			if err == nil {
+				opts, err = actions.FirewallOptions(ctx, proxmoxClient, fs)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(opts)
+			}
+
+			// Proxmox omits options left at their defaults; always show the
+			// ones users look for first.
+			for _, k := range []string{"enable", "policy_in", "policy_out"} {
+				if fs.IsNode() && k != "enable" {
+					continue
+				}
+				if _, ok := opts[k]; !ok {
+					opts[k] = "(default)"
+				}
+			}
+			keys := make([]string, 0, len(opts))
+			for k := range opts {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "OPTION\tVALUE")
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s\t%v\n", k, opts[k])
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func firewallOptionsSetCmd(scope *firewallScopeFlags) *cobra.Command {
+	var (
+		enable, disable     bool
+		policyIn, policyOut string
+		unset               []string
+	)
+	cmd := &cobra.Command{
+		Use:   "set [key=value...]",
+		Short: "Change firewall options",
+		Long: `Change firewall options. Common options have flags; any other option
+accepted by the Proxmox API (e.g. log_level_in, macfilter, ipfilter) can be
+given as key=value. --unset resets options to their defaults.`,
+		Example: `  pxve firewall options set --cluster --enable --policy-in DROP
+  pxve firewall options set --vmid 100 --enable ipfilter=1
+  pxve firewall options set --vmid 100 --unset policy_out`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			set := map[string]string{}
+			for _, a := range args {
+				k, v, ok := strings.Cut(a, "=")
+				if !ok || k == "" {
+					return fmt.Errorf("invalid option %q (expected key=value)", a)
+				}
+				set[k] = v
+			}
+			if enable && disable {
+				return fmt.Errorf("--enable and --disable are mutually exclusive")
+			}
+			if enable {
+				set["enable"] = "1"
+			}
+			if disable {
+				set["enable"] = "0"
+			}
+			for flag, v := range map[string]string{"policy_in": policyIn, "policy_out": policyOut} {
+				if v == "" {
+					continue
+				}
+				v = strings.ToUpper(v)
+				if v != "ACCEPT" && v != "DROP" && v != "REJECT" {
+					return fmt.Errorf("invalid %s %q (must be ACCEPT, DROP, or REJECT)", flag, v)
+				}
+				set[flag] = v
+			}
+			if len(set) == 0 && len(unset) == 0 {
+				return fmt.Errorf("nothing to change")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Updating firewall options...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				err = actions.SetFirewallOptions(ctx, proxmoxClient, fs, set, unset)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Firewall options updated for %s.\n", fs)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&enable, "enable", false, "enable the firewall")
+	cmd.Flags().BoolVar(&disable, "disable", false, "disable the firewall")
+	cmd.Flags().StringVar(&policyIn, "policy-in", "", "default inbound policy: ACCEPT, DROP, or REJECT")
+	cmd.Flags().StringVar(&policyOut, "policy-out", "", "default outbound policy: ACCEPT, DROP, or REJECT")
+	cmd.Flags().StringSliceVar(&unset, "unset", nil, "comma-separated options to reset to their defaults")
+	return cmd
+}
+
+// ---------------------------------------------------------------------------
+// IP sets
+// ---------------------------------------------------------------------------
+
+func firewallIPSetCmd(scope *firewallScopeFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ipset",
+		Short: "Manage IP sets (cluster and guests)",
+	}
+	cmd.AddCommand(firewallIPSetListCmd(scope))
+	cmd.AddCommand(firewallIPSetCreateCmd(scope))
+	cmd.AddCommand(firewallIPSetDeleteCmd(scope))
+	cmd.AddCommand(firewallIPSetAddCmd(scope))
+	cmd.AddCommand(firewallIPSetRemoveCmd(scope))
+	return cmd
+}
+
+func firewallIPSetListCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [name]",
+		Short: "List IP sets and their entries",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading IP sets...")
+			fs, err := scope.resolve(ctx, false)
+			var sets []actions.FirewallIPSet
+			if err == nil {
+				sets, err = actions.FirewallIPSets(ctx, proxmoxClient, fs)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if len(args) == 1 {
+				var found []actions.FirewallIPSet
+				for _, set := range sets {
+					if set.Name == args[0] {
+						found = append(found, set)
+					}
+				}
+				if len(found) == 0 {
+					return fmt.Errorf("IP set %q not found in %s firewall", args[0], fs)
+				}
+				sets = found
+			}
+
+			if flagOutput == "json" {
+				if sets == nil {
+					sets = []actions.FirewallIPSet{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(sets)
+			}
+			if len(sets) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo IP sets for %s.%s\n", colorGold, fs, colorReset)
+				}
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "IPSET\tCIDR\tNOMATCH\tCOMMENT")
+			for _, set := range sets {
+				fmt.Fprintf(w, "%s\t\t\t%s\n", set.Name, set.Comment)
+				for _, e := range set.Entries {
+					nomatch := ""
+					if e.NoMatch {
+						nomatch = "yes"
+					}
+					fmt.Fprintf(w, "\t%s\t%s\t%s\n", e.CIDR, nomatch, e.Comment)
+				}
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func firewallIPSetCreateCmd(scope *firewallScopeFlags) *cobra.Command {
+	var comment string
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an empty IP set",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Creating IP set...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				err = actions.CreateFirewallIPSet(ctx, proxmoxClient, fs, args[0], comment)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "IP set %q created in %s firewall.\n", args[0], fs)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&comment, "comment", "", "IP set comment")
+	return cmd
+}
+
+func firewallIPSetDeleteCmd(scope *firewallScopeFlags) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete an IP set and its entries",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			fs, err := scope.resolve(ctx, false)
+			if err != nil {
+				return handleErr(err)
+			}
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Delete IP set %q and all its entries from %s firewall? [y/N]: ", args[0], fs)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			s := startSpinner("Deleting IP set...")
+			err = actions.DeleteFirewallIPSet(ctx, proxmoxClient, fs, args[0], true)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "IP set %q deleted from %s firewall.\n", args[0], fs)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+func firewallIPSetAddCmd(scope *firewallScopeFlags) *cobra.Command {
+	var entry actions.FirewallIPSetEntry
+	cmd := &cobra.Command{
+		Use:     "add <name> <cidr>",
+		Short:   "Add an address, network, or alias to an IP set",
+		Example: `  pxve firewall ipset add --cluster office 192.168.10.0/24 --comment "HQ LAN"`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entry.CIDR = args[1]
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Adding IP set entry...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				err = actions.AddFirewallIPSetEntry(ctx, proxmoxClient, fs, args[0], entry)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added %s to IP set %q.\n", args[1], args[0])
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&entry.NoMatch, "nomatch", false, "exclude this entry from the set")
+	cmd.Flags().StringVar(&entry.Comment, "comment", "", "entry comment")
+	return cmd
+}
+
+func firewallIPSetRemoveCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name> <cidr>",
+		Short: "Remove an entry from an IP set",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Removing IP set entry...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				err = actions.RemoveFirewallIPSetEntry(ctx, proxmoxClient, fs, args[0], args[1])
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed %s from IP set %q.\n", args[1], args[0])
+			return nil
+		},
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Aliases
+// ---------------------------------------------------------------------------
+
+func firewallAliasCmd(scope *firewallScopeFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manage address aliases (cluster and guests)",
+	}
+	cmd.AddCommand(firewallAliasListCmd(scope))
+	cmd.AddCommand(firewallAliasSaveCmd(scope, "add"))
+	cmd.AddCommand(firewallAliasSaveCmd(scope, "update"))
+	cmd.AddCommand(firewallAliasDeleteCmd(scope))
+	return cmd
+}
+
+func firewallAliasListCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List aliases",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading aliases...")
+			fs, err := scope.resolve(ctx, false)
+			var aliases []actions.FirewallAlias
+			if err == nil {
+				aliases, err = actions.FirewallAliases(ctx, proxmoxClient, fs)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if aliases == nil {
+					aliases = []actions.FirewallAlias{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(aliases)
+			}
+			if len(aliases) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo aliases for %s.%s\n", colorGold, fs, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCIDR\tCOMMENT")
+			for _, a := range aliases {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", a.Name, a.CIDR, a.Comment)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// firewallAliasSaveCmd builds "alias add" and "alias update", which take the
+// same arguments.
+func firewallAliasSaveCmd(scope *firewallScopeFlags, verb string) *cobra.Command {
+	var comment string
+	short := "Create an alias"
+	if verb == "update" {
+		short = "Change an alias's address or comment"
+	}
+	cmd := &cobra.Command{
+		Use:   verb + " <name> <cidr>",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a := actions.FirewallAlias{Name: args[0], CIDR: args[1], Comment: comment}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Saving alias...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				if verb == "add" {
+					err = actions.CreateFirewallAlias(ctx, proxmoxClient, fs, a)
+				} else {
+					// The API replaces the whole alias; keep the comment
+					// unless a new one was given.
+					if !cmd.Flags().Changed("comment") {
+						var existing []actions.FirewallAlias
+						existing, err = actions.FirewallAliases(ctx, proxmoxClient, fs)
+						for _, e := range existing {
+							if e.Name == a.Name {
+								a.Comment = e.Comment
+							}
+						}
+					}
+					if err == nil {
+						err = actions.UpdateFirewallAlias(ctx, proxmoxClient, fs, a)
+					}
+				}
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Alias %q saved in %s firewall.\n", a.Name, fs)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&comment, "comment", "", "alias comment")
+	return cmd
+}
+
+func firewallAliasDeleteCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete an alias",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Deleting alias...")
+			fs, err := scope.resolve(ctx, false)
+			if err == nil {
+				err = actions.DeleteFirewallAlias(ctx, proxmoxClient, fs, args[0])
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Alias %q deleted from %s firewall.\n", args[0], fs)
+			return nil
+		},
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Security groups
+// ---------------------------------------------------------------------------
+
+func firewallGroupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "group",
+		Short: "Manage cluster security groups",
+		Long: `Manage cluster security groups. Edit a group's rules with
+"pxve firewall rules ... --group <name>" and apply it to a guest or node with
+a rule of --type group.`,
+	}
+	cmd.AddCommand(firewallGroupListCmd())
+	cmd.AddCommand(firewallGroupCreateCmd())
+	cmd.AddCommand(firewallGroupDeleteCmd())
+	return cmd
+}
+
+func firewallGroupListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List security groups",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading security groups...")
+			groups, err := actions.FirewallGroups(ctx, proxmoxClient)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if groups == nil {
+					groups = []actions.FirewallGroup{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(groups)
+			}
+			if len(groups) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintln(cmd.OutOrStdout(), colorGold+"No security groups."+colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "GROUP\tCOMMENT")
+			for _, g := range groups {
+				fmt.Fprintf(w, "%s\t%s\n", g.Group, g.Comment)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func firewallGroupCreateCmd() *cobra.Command {
+	var comment string
+	cmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create an empty security group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Creating security group...")
+			err := actions.CreateFirewallGroup(ctx, proxmoxClient, args[0], comment)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Security group %q created.\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&comment, "comment", "", "group comment")
+	return cmd
+}
+
+func firewallGroupDeleteCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a security group (it must have no rules and no references)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Delete security group %q? [y/N]: ", args[0])
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Deleting security group...")
+			err := actions.DeleteFirewallGroup(ctx, proxmoxClient, args[0])
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Security group %q deleted.\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+// ---------------------------------------------------------------------------
+// Export / sync
+// ---------------------------------------------------------------------------
+
+func firewallExportCmd(scope *firewallScopeFlags) *cobra.Command {
+	return &cobra.Command{
+		Use:   "export",
+		Short: "Write rules, IP sets, and aliases as YAML",
+		Long: `Write the rules (in order), IP sets, and aliases of a cluster, node, or
+guest firewall as YAML, for review and for "pxve firewall sync".`,
+		Example: `  pxve firewall export --vmid 100 > web1-firewall.yaml
+  pxve firewall export --cluster > cluster-firewall.yaml`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Reading firewall...")
+			fs, err := scope.resolve(ctx, false)
+			var spec *firewall.Spec
+			if err == nil {
+				spec, err = firewall.Export(ctx, proxmoxClient, fs)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			enc := yaml.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent(2)
+			if err := enc.Encode(spec); err != nil {
+				return err
+			}
+			return enc.Close()
+		},
+	}
+}
+
+func firewallSyncCmd(scope *firewallScopeFlags) *cobra.Command {
+	var (
+		file   string
+		dryRun bool
+	)
+	cmd := &cobra.Command{
+		Use:   "sync -f <file>",
+		Short: "Reconcile a firewall with a YAML file",
+		Long: `Make a cluster, node, or guest firewall match a file written by
+"pxve firewall export": rules are added and removed until the rule list
+matches the file in order, and IP sets, their entries, and aliases are
+created, updated, or deleted.
+
+The scope comes from --cluster, --node, or --vmid, or else from the file's
+scope field, so an exported file can be applied to a different guest. A
+section missing from the file is left alone; an empty list ([]) removes
+everything in it. The planned changes are always printed; --dry-run stops
+there.`,
+		Example: `  pxve firewall sync -f web1-firewall.yaml --dry-run
+  pxve firewall sync -f web1-firewall.yaml --vmid 101
+  pxve firewall export --vmid 100 | pxve firewall sync -f - --vmid 101`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("--file is required")
+			}
+			var data []byte
+			var err error
+			if file == "-" {
+				data, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				data, err = os.ReadFile(file)
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", file, err)
+			}
+			var want firewall.Spec
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			if err := dec.Decode(&want); err != nil && err != io.EOF {
+				return fmt.Errorf("parsing %s: %w", file, err)
+			}
+
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Comparing firewall...")
+			var fs actions.FirewallScope
+			switch {
+			case scope.isSet():
+				fs, err = scope.resolve(ctx, false)
+			case want.Scope != "":
+				fs, err = firewall.ResolveRef(ctx, proxmoxClient, want.Scope)
+			default:
+				err = fmt.Errorf("the file has no scope; specify one of --cluster, --node, or --vmid")
+			}
+			var cur *firewall.Spec
+			if err == nil {
+				cur, err = firewall.Export(ctx, proxmoxClient, fs)
+			}
+			var changes []firewall.Change
+			if err == nil {
+				changes, err = firewall.Plan(cur, &want, fs.IsNode())
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			out := cmd.OutOrStdout()
+			if len(changes) == 0 {
+				fmt.Fprintf(out, "%s firewall is already in sync with %s.\n", capitalize(fs.String()), file)
+				return nil
+			}
+			printFirewallChanges(out, changes)
+			if dryRun {
+				fmt.Fprintf(out, "Dry run: %d change(s) to %s firewall not applied.\n", len(changes), fs)
+				return nil
+			}
+
+			s = startSpinner("Applying firewall changes...")
+			applied, err := firewall.Apply(ctx, proxmoxClient, fs, changes)
+			s.Stop()
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Applied %d of %d change(s) before the error.\n", applied, len(changes))
+				return handleErr(err)
+			}
+			fmt.Fprintf(out, "Applied %d change(s) to %s firewall.\n", applied, fs)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "YAML file from 'pxve firewall export' (- for stdin)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show the changes without applying them")
+	return cmd
+}
+
+// isSet reports whether any of the scope flags was given.
+func (f *firewallScopeFlags) isSet() bool {
+	return f.cluster || f.node != "" || f.vmid != 0 || f.group != ""
+}
+
+// printFirewallChanges lists planned changes one per line, colored by kind
+// on a terminal.
+func printFirewallChanges(w io.Writer, changes []firewall.Change) {
+	color := map[string]string{firewall.OpAdd: colorGreen, firewall.OpDelete: colorRed, firewall.OpUpdate: colorGold}
+	tty := stdoutIsTerminal()
+	for _, ch := range changes {
+		line := fmt.Sprintf("%s %s", ch.Op, ch.Object)
+		if ch.Detail != "" {
+			line += ": " + ch.Detail
+		}
+		if tty {
+			line = color[ch.Op] + line + colorReset
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
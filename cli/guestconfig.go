@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// configEditOpts are the flags shared by commands that change a guest's
+// configuration through a reviewed diff.
+type configEditOpts struct {
+	nodeName string
+	force    bool
+	dryRun   bool
+	digest   string // expected config digest; empty uses the one just read
+}
+
+func addConfigEditFlags(cmd *cobra.Command, o *configEditOpts) {
+	cmd.Flags().StringVar(&o.nodeName, "node", "", "node name")
+	cmd.Flags().BoolVar(&o.force, "force", false, "apply without confirmation")
+	cmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "show the changes without applying them")
+}
+
+// addDigestFlag lets scripts pass the digest of the config they based the
+// change on (see "config get"), so it is rejected if the config changed
+// since then rather than since this command read it.
+func addDigestFlag(cmd *cobra.Command, o *configEditOpts) {
+	cmd.Flags().StringVar(&o.digest, "digest", "", "apply only if the config still has this digest")
+}
+
+// configBuilder computes the keys to set and remove from a guest's current
+// configuration.
+type configBuilder func(cfg *actions.GuestConfig) (set map[string]string, del []string, err error)
+
+// editGuestConfig loads the configuration of guest idArg (typ "qemu" or
+// "lxc"), computes a change with build, shows it as a diff and, after
+// confirmation, applies it. The update carries the config digest, so a
+// concurrent change by someone else makes it fail instead of being
+// silently overwritten.
+func editGuestConfig(cmd *cobra.Command, typ, idArg string, o configEditOpts, build configBuilder) error {
+	cfg, err := loadGuestConfig(cmd, typ, idArg, o.nodeName)
+	if err != nil {
+		return err
+	}
+	if o.digest != "" && o.digest != cfg.Digest {
+		return fmt.Errorf("%s %d config was changed by someone else since digest %s; nothing applied", capitalize(cfg.Noun()), cfg.VMID, o.digest)
+	}
+	set, del, err := build(cfg)
+	if err != nil {
+		return err
+	}
+	return applyGuestConfig(cmd, cfg, set, del, o)
+}
+
+// loadGuestConfig parses idArg, initializes the client and loads the
+// guest's configuration.
+func loadGuestConfig(cmd *cobra.Command, typ, idArg, nodeName string) (*actions.GuestConfig, error) {
+	vmid, err := strconv.Atoi(idArg)
+	if err != nil {
+		if typ == "lxc" {
+			return nil, fmt.Errorf("invalid CTID %q", idArg)
+		}
+		return nil, fmt.Errorf("invalid VMID %q", idArg)
+	}
+	if err := initClient(cmd); err != nil {
+		return nil, err
+	}
+	s := startSpinner("Loading config...")
+	cfg, err := actions.GetGuestConfig(context.Background(), proxmoxClient, typ, vmid, nodeName)
+	s.Stop()
+	if err != nil {
+		return nil, handleErr(err)
+	}
+	return cfg, nil
+}
+
+// applyGuestConfig shows the diff of setting set and removing del on cfg
+// and applies it unless o.dryRun is set or the user declines.
+func applyGuestConfig(cmd *cobra.Command, cfg *actions.GuestConfig, set map[string]string, del []string, o configEditOpts) error {
+	out := cmd.OutOrStdout()
+	changes := actions.DiffGuestConfig(cfg.Values, set, del)
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "No changes to %s %d.\n", cfg.Noun(), cfg.VMID)
+		return nil
+	}
+	printConfigChanges(out, changes)
+	if o.dryRun {
+		return nil
+	}
+	if !o.force {
+		fmt.Fprintf(out, "Apply to %s %d? [y/N]: ", cfg.Noun(), cfg.VMID)
+		if !readYes(cmd) {
+			fmt.Fprintln(out, "Cancelled.")
+			return nil
+		}
+	}
+
+	ctx := context.Background()
+	s := startSpinner("Updating config...")
+	task, err := actions.UpdateGuestConfig(ctx, proxmoxClient, cfg, set, del)
+	s.Stop()
+	if err != nil {
+		return configUpdateErr(cfg, err)
+	}
+	if task != nil {
+		if detachTask(cmd, task) {
+			return nil
+		}
+		if err := watchTask(ctx, out, task); err != nil {
+			return configUpdateErr(cfg, err)
+		}
+	}
+	fmt.Fprintf(out, "%s %d config updated.\n", capitalize(cfg.Noun()), cfg.VMID)
+	return nil
+}
+
+func configUpdateErr(cfg *actions.GuestConfig, err error) error {
+	if actions.IsConfigChanged(err) {
+		return fmt.Errorf("%s %d config was changed by someone else since it was read; nothing applied — re-run to review the new diff", capitalize(cfg.Noun()), cfg.VMID)
+	}
+	return handleErr(err)
+}
+
+// printConfigChanges prints config changes as -/+ lines, colored on a
+// terminal.
+func printConfigChanges(w io.Writer, changes []actions.ConfigChange) {
+	tty := stdoutIsTerminal()
+	line := func(color, prefix, key, value string) {
+		l := fmt.Sprintf("%s %s: %s", prefix, key, value)
+		if tty {
+			l = color + l + colorReset
+		}
+		fmt.Fprintln(w, l)
+	}
+	for _, ch := range changes {
+		if ch.Old != "" {
+			line(colorRed, "-", ch.Key, ch.Old)
+		}
+		if ch.New != "" {
+			line(colorGreen, "+", ch.Key, ch.New)
+		}
+	}
+}
+
+// requireConfigKey returns an error unless cfg has key and it starts with
+// prefix (e.g. "net" for net0).
+func requireConfigKey(cfg *actions.GuestConfig, key, prefix string) error {
+	if _, err := strconv.Atoi(strings.TrimPrefix(key, prefix)); err != nil || !strings.HasPrefix(key, prefix) {
+		return fmt.Errorf("invalid device %q (expected %sN)", key, prefix)
+	}
+	if _, ok := cfg.Values[key]; !ok {
+		return fmt.Errorf("%s %d has no %s", capitalize(cfg.Noun()), cfg.VMID, key)
+	}
+	return nil
+}
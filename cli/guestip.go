@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// ipGuest is a guest list entry with its primary IP, for --show-ip.
+type ipGuest struct {
+	*proxmox.ClusterResource
+	IP string `json:"ip,omitempty"`
+}
+
+// addShowIPFlag adds --show-ip to a guest list command.
+func addShowIPFlag(cmd *cobra.Command, p *bool) {
+	cmd.Flags().BoolVar(p, "show-ip", false, "add an IP column (guest agent for VMs, interfaces for CTs; cached for a few minutes)")
+}
+
+// withGuestIPs looks up the IPs of guests.
+func withGuestIPs(ctx context.Context, guests proxmox.ClusterResources) []ipGuest {
+	ips := actions.GuestIPs(ctx, proxmoxClient, resolvedInstURL, guests)
+	out := make([]ipGuest, len(guests))
+	for i, g := range guests {
+		out[i] = ipGuest{ClusterResource: g, IP: ips[g.VMID]}
+	}
+	return out
+}
+
+// ipTable builds a guest list table with table and appends the IP column.
+func ipTable(table func(proxmox.ClusterResources) listTable) func([]ipGuest) listTable {
+	return func(rows []ipGuest) listTable {
+		guests := make(proxmox.ClusterResources, len(rows))
+		for i, r := range rows {
+			guests[i] = r.ClusterResource
+		}
+		t := table(guests)
+		t.header += "\tIP"
+		for i := range t.rows {
+			t.rows[i].line += "\t" + dash(rows[i].IP)
+			t.rows[i].sig += "\t" + rows[i].IP
+		}
+		return t
+	}
+}
+
+// listGuestsWithIPs runs a guest list command with --show-ip: once, or
+// every interval with --watch. noun names the guests in the empty-list
+// notice, e.g. "VMs".
+func listGuestsWithIPs(cmd *cobra.Command, interval time.Duration, noun string, fetch func(context.Context) (proxmox.ClusterResources, error), table func(proxmox.ClusterResources) listTable) error {
+	fetchIPs := func(ctx context.Context) ([]ipGuest, error) {
+		guests, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return withGuestIPs(ctx, guests), nil
+	}
+	if interval > 0 {
+		return runWatch(cmd, interval, fetchIPs, ipTable(table))
+	}
+
+	s := startSpinner("Loading " + noun + "...")
+	rows, err := fetchIPs(context.Background())
+	s.Stop()
+	if err != nil {
+		return handleErr(err)
+	}
+	out := cmd.OutOrStdout()
+	if flagOutput == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+	if len(rows) == 0 {
+		if stdoutIsTerminal() {
+			fmt.Fprintf(out, "%sNo %s available.%s\n", colorGold, noun, colorReset)
+		} else {
+			fmt.Fprintf(out, "No %s available.\n", noun)
+		}
+		return nil
+	}
+	return writeTable(out, ipTable(table)(rows), nil)
+}
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/config"
+	"github.com/chupakbra/proxmox-cli/internal/hooks"
+)
+
+func hookCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Inspect and test task-completion hooks from ~/.pxve.yaml",
+		Long: `Hooks run after any task pxve waits on finishes, from both the CLI and the
+TUI. Each hook is a local command (run with sh -c, payload JSON on stdin and
+PXVE_* environment variables) or a URL the payload JSON is POSTed to.
+
+Configure them in ~/.pxve.yaml:
+
+  hooks:
+    - name: chat
+      url: https://hooks.example.com/pxve
+      headers:
+        Authorization: Bearer <token>
+      on: failure        # always (default), success, or failure
+    - name: notify
+      command: notify-send "pxve" "$PXVE_TASK_TYPE $PXVE_TARGET: $PXVE_STATUS"
+      timeout: 10s       # default 30s`,
+	}
+	cmd.AddCommand(hookListCmd())
+	cmd.AddCommand(hookTestCmd())
+	return cmd
+}
+
+func hookListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List configured hooks",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+
+			if flagOutput == "json" {
+				list := cfg.Hooks
+				if list == nil {
+					list = []config.HookConfig{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(list)
+			}
+
+			if len(cfg.Hooks) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintln(cmd.OutOrStdout(), colorGold+"No hooks configured — add a hooks: section to ~/.pxve.yaml (see 'pxve hook --help')."+colorReset)
+				}
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tTYPE\tTARGET\tON\tTIMEOUT\tVALID")
+			for _, h := range cfg.Hooks {
+				typ, target := "command", h.Command
+				if h.URL != "" {
+					typ, target = "webhook", h.URL
+				}
+				on := h.On
+				if on == "" {
+					on = "always"
+				}
+				timeout := h.Timeout
+				if timeout == "" {
+					timeout = hooks.DefaultTimeout.String()
+				}
+				valid := "yes"
+				if err := hooks.Validate(h); err != nil {
+					valid = "no: " + strings.TrimPrefix(err.Error(), "hook "+hooks.Label(h)+": ")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", h.Name, typ, target, on, timeout, valid)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func hookTestCmd() *cobra.Command {
+	var failed bool
+	cmd := &cobra.Command{
+		Use:   "test [name...]",
+		Short: "Fire hooks with a sample payload",
+		Long: `Fire the configured hooks (or only the named ones) with a sample payload for
+a successful task, or a failed one with --failed. No Proxmox instance is
+contacted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			selected := cfg.Hooks
+			if len(args) > 0 {
+				selected = nil
+				for _, name := range args {
+					found := false
+					for _, h := range cfg.Hooks {
+						if h.Name == name {
+							selected = append(selected, h)
+							found = true
+						}
+					}
+					if !found {
+						return fmt.Errorf("hook %q not found in config", name)
+					}
+				}
+			}
+			if len(selected) == 0 {
+				return fmt.Errorf("no hooks configured")
+			}
+
+			started := time.Now().Add(-12 * time.Second)
+			task := &proxmox.Task{
+				UPID:       proxmox.UPID(fmt.Sprintf("UPID:pve:00000000:00000000:%08X:qmstart:100:root@pam:", started.Unix())),
+				Node:       "pve",
+				Type:       "qmstart",
+				ID:         "100",
+				User:       "root@pam",
+				ExitStatus: "OK",
+			}
+			if failed {
+				task.ExitStatus = "command failed: exit code 1"
+				task.IsFailed = true
+			}
+			p := hooks.NewPayload(task, "test", "pxve hook test", started)
+
+			if err := hooks.Run(context.Background(), selected, p, cmd.ErrOrStderr()); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Fired %d hook(s)\n", len(selected))
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&failed, "failed", false, "send a payload for a failed task")
+	return cmd
+}
+
+// runTaskHooks fires the configured hooks for a task the CLI waited on. Tasks
+// that did not finish (e.g. --timeout expired) are skipped. Hook failures are
+// reported as warnings and never change the command's result.
+func runTaskHooks(task *proxmox.Task, started time.Time) {
+	if resolvedConfig == nil || len(resolvedConfig.Hooks) == 0 || !task.IsCompleted {
+		return
+	}
+	p := hooks.NewPayload(task, "cli", resolvedCommand, started)
+	if err := hooks.Run(context.Background(), resolvedConfig.Hooks, p, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
@@ -0,0 +1,301 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// sparkWidth is the maximum number of characters in a TREND sparkline.
+const sparkWidth = 40
+
+// metricSeries describes one row of the metrics table and one CSV column.
+type metricSeries struct {
+	name    string
+	csv     string
+	value   func(p actions.MetricPoint) *float64
+	format  func(v float64) string
+	csvOnly bool // omitted from the summary table
+}
+
+var (
+	seriesCPU       = metricSeries{name: "CPU", csv: "cpu", value: func(p actions.MetricPoint) *float64 { return p.CPU }, format: formatCPUPercent}
+	seriesMem       = metricSeries{name: "Memory", csv: "mem_used", value: func(p actions.MetricPoint) *float64 { return p.MemUsed }, format: formatBytesFloat}
+	seriesMemTotal  = metricSeries{name: "Memory Total", csv: "mem_total", value: func(p actions.MetricPoint) *float64 { return p.MemTotal }, format: formatBytesFloat, csvOnly: true}
+	seriesNetIn     = metricSeries{name: "Net In", csv: "net_in", value: func(p actions.MetricPoint) *float64 { return p.NetIn }, format: formatRate}
+	seriesNetOut    = metricSeries{name: "Net Out", csv: "net_out", value: func(p actions.MetricPoint) *float64 { return p.NetOut }, format: formatRate}
+	seriesDiskRead  = metricSeries{name: "Disk Read", csv: "disk_read", value: func(p actions.MetricPoint) *float64 { return p.DiskRead }, format: formatRate}
+	seriesDiskWrite = metricSeries{name: "Disk Write", csv: "disk_write", value: func(p actions.MetricPoint) *float64 { return p.DiskWrite }, format: formatRate}
+	seriesIOWait    = metricSeries{name: "IO Wait", csv: "io_wait", value: func(p actions.MetricPoint) *float64 { return p.IOWait }, format: formatCPUPercent}
+	seriesLoad      = metricSeries{name: "Load", csv: "load_avg", value: func(p actions.MetricPoint) *float64 { return p.LoadAvg }, format: func(v float64) string { return fmt.Sprintf("%.2f", v) }}
+
+	nodeMetricSeries  = []metricSeries{seriesCPU, seriesIOWait, seriesLoad, seriesMem, seriesMemTotal, seriesNetIn, seriesNetOut}
+	guestMetricSeries = []metricSeries{seriesCPU, seriesMem, seriesMemTotal, seriesNetIn, seriesNetOut, seriesDiskRead, seriesDiskWrite}
+)
+
+func nodeMetricsCmd() *cobra.Command {
+	var timeframe, cf string
+	cmd := &cobra.Command{
+		Use:   "metrics <node>",
+		Short: "Show CPU, memory, and network history for a node",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName := args[0]
+			cf, err := actions.ValidateMetricParams(timeframe, cf)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading metrics...")
+			points, err := actions.NodeMetrics(ctx, proxmoxClient, nodeName, timeframe, cf)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			return printMetrics(cmd, points, nodeMetricSeries, timeframe, cf)
+		},
+	}
+	addMetricFlags(cmd, &timeframe, &cf)
+	return cmd
+}
+
+func vmMetricsCmd() *cobra.Command {
+	var nodeName, timeframe, cf string
+	cmd := &cobra.Command{
+		Use:   "metrics <vmid>",
+		Short: "Show CPU, memory, network, and disk I/O history for a VM",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid VMID %q", args[0])
+			}
+			cf, err := actions.ValidateMetricParams(timeframe, cf)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading metrics...")
+			points, err := actions.VMMetrics(ctx, proxmoxClient, vmid, nodeName, timeframe, cf)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			return printMetrics(cmd, points, guestMetricSeries, timeframe, cf)
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	addMetricFlags(cmd, &timeframe, &cf)
+	return cmd
+}
+
+func ctMetricsCmd() *cobra.Command {
+	var nodeName, timeframe, cf string
+	cmd := &cobra.Command{
+		Use:   "metrics <ctid>",
+		Short: "Show CPU, memory, network, and disk I/O history for a container",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid CTID %q", args[0])
+			}
+			cf, err := actions.ValidateMetricParams(timeframe, cf)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading metrics...")
+			points, err := actions.ContainerMetrics(ctx, proxmoxClient, ctid, nodeName, timeframe, cf)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			return printMetrics(cmd, points, guestMetricSeries, timeframe, cf)
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	addMetricFlags(cmd, &timeframe, &cf)
+	return cmd
+}
+
+func addMetricFlags(cmd *cobra.Command, timeframe, cf *string) {
+	cmd.Flags().StringVar(timeframe, "timeframe", "hour", "time range: "+strings.Join(actions.MetricTimeframes, ", "))
+	cmd.Flags().StringVar(cf, "cf", "AVERAGE", "consolidation function: AVERAGE or MAX")
+}
+
+// printMetrics renders RRD points as a summary table with sparklines, raw
+// CSV rows (-o csv), or JSON (-o json).
+func printMetrics(cmd *cobra.Command, points []actions.MetricPoint, series []metricSeries, timeframe, cf string) error {
+	out := cmd.OutOrStdout()
+	switch flagOutput {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(points)
+	case "csv":
+		w := csv.NewWriter(out)
+		header := []string{"time"}
+		for _, s := range series {
+			header = append(header, s.csv)
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		for _, p := range points {
+			row := []string{time.Unix(p.Time, 0).UTC().Format(time.RFC3339)}
+			for _, s := range series {
+				if v := s.value(p); v != nil {
+					row = append(row, strconv.FormatFloat(*v, 'f', -1, 64))
+				} else {
+					row = append(row, "")
+				}
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	if len(points) == 0 {
+		if stdoutIsTerminal() {
+			fmt.Fprintf(out, "%sNo metrics data.%s\n", colorGold, colorReset)
+		}
+		return nil
+	}
+
+	first := time.Unix(points[0].Time, 0)
+	last := time.Unix(points[len(points)-1].Time, 0)
+	fmt.Fprintf(out, "Timeframe: %s (%s), %d samples, %s — %s\n\n",
+		timeframe, cf, len(points), first.Format("2006-01-02 15:04"), last.Format("2006-01-02 15:04"))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tMIN\tAVG\tMAX\tLAST\tTREND")
+	for _, s := range series {
+		if s.csvOnly {
+			continue
+		}
+		vals := make([]float64, len(points))
+		have := false
+		for i, p := range points {
+			if v := s.value(p); v != nil {
+				vals[i] = *v
+				have = true
+			} else {
+				vals[i] = math.NaN()
+			}
+		}
+		if !have {
+			continue
+		}
+		min, avg, max, lastVal := seriesStats(vals)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.name, s.format(min), s.format(avg), s.format(max), s.format(lastVal), sparkline(vals, sparkWidth))
+	}
+	return w.Flush()
+}
+
+// seriesStats returns min, average, max, and the last known value of vals,
+// ignoring NaN gaps. vals must contain at least one non-NaN value.
+func seriesStats(vals []float64) (min, avg, max, last float64) {
+	min, max = math.Inf(1), math.Inf(-1)
+	var sum float64
+	var n int
+	for _, v := range vals {
+		if math.IsNaN(v) {
+			continue
+		}
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+		sum += v
+		n++
+		last = v
+	}
+	return min, sum / float64(n), max, last
+}
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline renders vals as a row of block characters at most width wide,
+// averaging neighbouring samples when there are more values than columns.
+// The scale runs from zero to the series maximum; NaN gaps render as spaces.
+func sparkline(vals []float64, width int) string {
+	cols := downsample(vals, width)
+	max := 0.0
+	for _, v := range cols {
+		if !math.IsNaN(v) && v > max {
+			max = v
+		}
+	}
+	var b strings.Builder
+	for _, v := range cols {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case max == 0:
+			b.WriteRune(sparkRunes[0])
+		default:
+			idx := int(v / max * float64(len(sparkRunes)-1))
+			b.WriteRune(sparkRunes[idx])
+		}
+	}
+	return b.String()
+}
+
+// downsample averages vals into at most width buckets, skipping NaN values.
+// A bucket with no values at all is NaN.
+func downsample(vals []float64, width int) []float64 {
+	if len(vals) <= width {
+		return vals
+	}
+	out := make([]float64, width)
+	for i := range out {
+		lo := i * len(vals) / width
+		hi := (i + 1) * len(vals) / width
+		var sum float64
+		var n int
+		for _, v := range vals[lo:hi] {
+			if !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+		if n == 0 {
+			out[i] = math.NaN()
+		} else {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
+
+// formatBytesFloat formats a byte count reported as a float by the RRD.
+func formatBytesFloat(v float64) string {
+	return formatBytes(uint64(math.Max(v, 0)))
+}
+
+// formatRate formats a bytes-per-second rate.
+func formatRate(v float64) string {
+	return formatBytesFloat(v) + "/s"
+}
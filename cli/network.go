@@ -0,0 +1,480 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// settingFlag maps a command-line flag to an API parameter. Boolean flags
+// send 1 or 0; list flags accept comma- or space-separated values and send
+// them space-separated.
+type settingFlag struct {
+	flag, key, usage string
+	boolean, list    bool
+}
+
+// networkSettingFlags are the interface settings of node network commands.
+var networkSettingFlags = []settingFlag{
+	{flag: "cidr", key: "cidr", usage: "IPv4 address in CIDR notation (e.g. 10.0.0.2/24)"},
+	{flag: "gateway", key: "gateway", usage: "IPv4 default gateway"},
+	{flag: "cidr6", key: "cidr6", usage: "IPv6 address in CIDR notation"},
+	{flag: "gateway6", key: "gateway6", usage: "IPv6 default gateway"},
+	{flag: "bridge-ports", key: "bridge_ports", usage: "bridge ports (comma- or space-separated)", list: true},
+	{flag: "vlan-aware", key: "bridge_vlan_aware", usage: "make the bridge VLAN aware", boolean: true},
+	{flag: "slaves", key: "slaves", usage: "bond members (comma- or space-separated)", list: true},
+	{flag: "bond-mode", key: "bond_mode", usage: "bond mode (e.g. active-backup, 802.3ad, balance-rr)"},
+	{flag: "bond-primary", key: "bond-primary", usage: "primary bond member (active-backup)"},
+	{flag: "hash-policy", key: "bond_xmit_hash_policy", usage: "bond transmit hash policy (layer2, layer2+3, layer3+4)"},
+	{flag: "vlan-id", key: "vlan-id", usage: "VLAN tag (vlan type)"},
+	{flag: "vlan-raw-device", key: "vlan-raw-device", usage: "parent device (vlan type)"},
+	{flag: "mtu", key: "mtu", usage: "MTU"},
+	{flag: "autostart", key: "autostart", usage: "bring the interface up at boot", boolean: true},
+	{flag: "comments", key: "comments", usage: "comment"},
+}
+
+// apiSettings holds the values of a command's setting flags.
+type apiSettings struct {
+	table   []settingFlag
+	strings map[string]*string
+	bools   map[string]*bool
+	extra   []string
+}
+
+func addSettingFlags(cmd *cobra.Command, s *apiSettings, table []settingFlag) {
+	s.table = table
+	s.strings = map[string]*string{}
+	s.bools = map[string]*bool{}
+	for _, f := range table {
+		if f.boolean {
+			s.bools[f.flag] = cmd.Flags().Bool(f.flag, false, f.usage)
+		} else {
+			s.strings[f.flag] = cmd.Flags().String(f.flag, "", f.usage)
+		}
+	}
+	cmd.Flags().StringArrayVar(&s.extra, "set", nil, "other API parameter as key=value (repeatable)")
+}
+
+// params returns the API parameters for the flags that were given.
+func (s *apiSettings) params(cmd *cobra.Command) (map[string]string, error) {
+	params := map[string]string{}
+	for _, f := range s.table {
+		if !cmd.Flags().Changed(f.flag) {
+			continue
+		}
+		switch {
+		case f.boolean:
+			params[f.key] = "0"
+			if *s.bools[f.flag] {
+				params[f.key] = "1"
+			}
+		case f.list:
+			v := *s.strings[f.flag]
+			params[f.key] = strings.Join(strings.FieldsFunc(v, func(r rune) bool { return r == ',' || r == ' ' }), " ")
+		default:
+			params[f.key] = *s.strings[f.flag]
+		}
+	}
+	for _, kv := range s.extra {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --set %q (expected key=value)", kv)
+		}
+		params[k] = v
+	}
+	return params, nil
+}
+
+func nodeNetworkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "network",
+		Short: "Manage node network interfaces (bridges, bonds, VLANs)",
+		Long: `Manage node network interfaces. create, update, and delete only change the
+pending configuration (/etc/network/interfaces.new); review it with "diff",
+then "apply" it or "revert" it.`,
+	}
+	cmd.AddCommand(nodeNetworkListCmd())
+	cmd.AddCommand(nodeNetworkShowCmd())
+	cmd.AddCommand(nodeNetworkCreateCmd())
+	cmd.AddCommand(nodeNetworkUpdateCmd())
+	cmd.AddCommand(nodeNetworkDeleteCmd())
+	cmd.AddCommand(nodeNetworkDiffCmd())
+	cmd.AddCommand(nodeNetworkApplyCmd())
+	cmd.AddCommand(nodeNetworkRevertCmd())
+	return cmd
+}
+
+func nodeNetworkListCmd() *cobra.Command {
+	var typ string
+	cmd := &cobra.Command{
+		Use:   "list <node>",
+		Short: "List network interfaces, including pending changes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName := args[0]
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading network interfaces...")
+			ifaces, err := actions.NodeInterfaces(ctx, proxmoxClient, nodeName, typ)
+			var changes string
+			if err == nil {
+				changes, err = actions.NodeNetworkChanges(ctx, proxmoxClient, nodeName)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if ifaces == nil {
+					ifaces = []actions.NodeInterface{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(ifaces)
+			}
+
+			if len(ifaces) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo network interfaces on %s.%s\n", colorGold, nodeName, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "IFACE\tTYPE\tACTIVE\tAUTOSTART\tMETHOD\tCIDR\tGATEWAY\tPORTS/SLAVES\tCOMMENT")
+			for _, n := range ifaces {
+				ports := n.BridgePorts
+				if ports == "" {
+					ports = n.Slaves
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					n.Iface, n.Type, yesNoBool(n.Active), yesNoBool(n.Autostart), dash(n.Method),
+					dash(n.CIDR), dash(n.Gateway), dash(ports), n.Comments)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if changes != "" && stdoutIsTerminal() {
+				fmt.Fprintf(cmd.OutOrStdout(), "%sPending changes — review with 'pxve node network diff %s', then apply or revert.%s\n",
+					colorGold, nodeName, colorReset)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&typ, "type", "", "only this interface type (e.g. bridge, bond, vlan, any_bridge)")
+	return cmd
+}
+
+func nodeNetworkShowCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "show <node> <iface>",
+		Short: "Show all settings of a network interface",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading...")
+			n, err := actions.GetNodeInterface(ctx, proxmoxClient, args[0], args[1])
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(n)
+			}
+
+			keys := make([]string, 0, len(n.Fields))
+			for k := range n.Fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s:\t%v\n", k, n.Fields[k])
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func nodeNetworkCreateCmd() *cobra.Command {
+	var (
+		typ      string
+		settings apiSettings
+	)
+	cmd := &cobra.Command{
+		Use:   "create <node> <iface>",
+		Short: "Add an interface to the pending configuration",
+		Example: `  pxve node network create pve vmbr1 --type bridge --bridge-ports eno2 --vlan-aware --autostart
+  pxve node network create pve bond0 --type bond --slaves eno1,eno2 --bond-mode 802.3ad --hash-policy layer3+4
+  pxve node network create pve vmbr0.20 --type vlan --cidr 10.20.0.2/24 --autostart`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName, iface := args[0], args[1]
+			valid := false
+			for _, t := range actions.NetworkTypes {
+				valid = valid || t == typ
+			}
+			if !valid {
+				return fmt.Errorf("invalid --type %q (must be one of %s)", typ, strings.Join(actions.NetworkTypes, ", "))
+			}
+			params, err := settings.params(cmd)
+			if err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Creating interface...")
+			err = actions.CreateNodeInterface(ctx, proxmoxClient, nodeName, iface, typ, params)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Interface %s added to the pending configuration of %s.\n", iface, nodeName)
+			printPendingNetworkHint(cmd.OutOrStdout(), nodeName)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&typ, "type", "", "interface type: "+strings.Join(actions.NetworkTypes, ", ")+" (required)")
+	addSettingFlags(cmd, &settings, networkSettingFlags)
+	return cmd
+}
+
+func nodeNetworkUpdateCmd() *cobra.Command {
+	var (
+		settings apiSettings
+		unset    []string
+	)
+	cmd := &cobra.Command{
+		Use:   "update <node> <iface>",
+		Short: "Change an interface in the pending configuration",
+		Example: `  pxve node network update pve vmbr0 --cidr 192.168.1.10/24 --gateway 192.168.1.1
+  pxve node network update pve vmbr1 --unset bridge_vlan_aware,comments`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName, iface := args[0], args[1]
+			params, err := settings.params(cmd)
+			if err != nil {
+				return err
+			}
+			if len(params) == 0 && len(unset) == 0 {
+				return fmt.Errorf("nothing to change")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Updating interface...")
+			err = actions.UpdateNodeInterface(ctx, proxmoxClient, nodeName, iface, params, unset)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Interface %s updated in the pending configuration of %s.\n", iface, nodeName)
+			printPendingNetworkHint(cmd.OutOrStdout(), nodeName)
+			return nil
+		},
+	}
+	addSettingFlags(cmd, &settings, networkSettingFlags)
+	cmd.Flags().StringSliceVar(&unset, "unset", nil, "comma-separated API parameters to remove (e.g. gateway,comments)")
+	return cmd
+}
+
+func nodeNetworkDeleteCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "delete <node> <iface>",
+		Short: "Remove an interface from the pending configuration",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName, iface := args[0], args[1]
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Remove interface %s from %s? [y/N]: ", iface, nodeName)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Deleting interface...")
+			err := actions.DeleteNodeInterface(ctx, proxmoxClient, nodeName, iface)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Interface %s removed from the pending configuration of %s.\n", iface, nodeName)
+			printPendingNetworkHint(cmd.OutOrStdout(), nodeName)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+func printPendingNetworkHint(w io.Writer, nodeName string) {
+	fmt.Fprintf(w, "Review with 'pxve node network diff %s', then 'pxve node network apply %s'.\n", nodeName, nodeName)
+}
+
+// loadNetworkChanges fetches the pending diff for nodeName with a spinner.
+func loadNetworkChanges(cmd *cobra.Command, nodeName string) (string, error) {
+	if err := initClient(cmd); err != nil {
+		return "", err
+	}
+	s := startSpinner("Loading pending changes...")
+	changes, err := actions.NodeNetworkChanges(context.Background(), proxmoxClient, nodeName)
+	s.Stop()
+	if err != nil {
+		return "", handleErr(err)
+	}
+	return changes, nil
+}
+
+// printNetworkDiff prints a unified diff, colored on a terminal.
+func printNetworkDiff(w io.Writer, diff string) {
+	tty := stdoutIsTerminal()
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		if tty {
+			switch {
+			case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			case strings.HasPrefix(line, "+"):
+				line = colorGreen + line + colorReset
+			case strings.HasPrefix(line, "-"):
+				line = colorRed + line + colorReset
+			case strings.HasPrefix(line, "@@"):
+				line = colorGold + line + colorReset
+			}
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+func nodeNetworkDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <node>",
+		Short: "Show pending network changes as a diff",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			changes, err := loadNetworkChanges(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(map[string]interface{}{"node": args[0], "pending": changes != "", "changes": changes})
+			}
+			if changes == "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No pending network changes on %s.\n", args[0])
+				return nil
+			}
+			printNetworkDiff(cmd.OutOrStdout(), changes)
+			return nil
+		},
+	}
+}
+
+func nodeNetworkApplyCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "apply <node>",
+		Short: "Apply pending network changes (ifreload)",
+		Long: `Show the pending network changes and, after confirmation, apply them with
+ifreload. A mistake can cut the node off the network; make sure you have
+console access before applying changes to the management interface.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName := args[0]
+			changes, err := loadNetworkChanges(cmd, nodeName)
+			if err != nil {
+				return err
+			}
+			if changes == "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No pending network changes on %s.\n", nodeName)
+				return nil
+			}
+			printNetworkDiff(cmd.OutOrStdout(), changes)
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Apply these changes to %s? [y/N]: ", nodeName)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			ctx := context.Background()
+			s := startSpinner("Applying network configuration...")
+			task, err := actions.ApplyNodeNetwork(ctx, proxmoxClient, nodeName)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if detachTask(cmd, task) {
+				return nil
+			}
+			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Network configuration applied on %s.\n", nodeName)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+func nodeNetworkRevertCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "revert <node>",
+		Short: "Discard pending network changes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			nodeName := args[0]
+			changes, err := loadNetworkChanges(cmd, nodeName)
+			if err != nil {
+				return err
+			}
+			if changes == "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "No pending network changes on %s.\n", nodeName)
+				return nil
+			}
+			if !force {
+				printNetworkDiff(cmd.OutOrStdout(), changes)
+				fmt.Fprintf(cmd.OutOrStdout(), "Discard these changes on %s? [y/N]: ", nodeName)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			s := startSpinner("Reverting...")
+			err = actions.RevertNodeNetwork(context.Background(), proxmoxClient, nodeName)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Pending network changes on %s discarded.\n", nodeName)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"text/tabwriter"
 
+	proxmox "github.com/luthermonson/go-proxmox"
 	"github.com/spf13/cobra"
 
 	"github.com/chupakbra/proxmox-cli/internal/actions"
@@ -18,17 +19,30 @@ func nodeCmd() *cobra.Command {
 	}
 	cmd.AddCommand(nodeListCmd())
 	cmd.AddCommand(nodeStatusCmd())
+	cmd.AddCommand(nodeMetricsCmd())
+	cmd.AddCommand(nodeNetworkCmd())
+	cmd.AddCommand(nodeShellCmd())
 	return cmd
 }
 
 func nodeListCmd() *cobra.Command {
-	return &cobra.Command{
+	var watch string
+	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all nodes in the cluster",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := resolveWatch(watch, args)
+			if err != nil {
+				return err
+			}
 			if err := initClient(cmd); err != nil {
 				return err
 			}
+			if interval > 0 {
+				return runWatch(cmd, interval, func(ctx context.Context) (proxmox.NodeStatuses, error) {
+					return actions.ListNodes(ctx, proxmoxClient)
+				}, nodeTable)
+			}
 			ctx := context.Background()
 			s := startSpinner("Loading nodes...")
 			nodes, err := actions.ListNodes(ctx, proxmoxClient)
@@ -43,22 +57,31 @@ func nodeListCmd() *cobra.Command {
 				return enc.Encode(nodes)
 			}
 
-			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "NODE\tSTATUS\tCPU\tMEM USED\tMEM TOTAL\tDISK\tUPTIME")
-			for _, n := range nodes {
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-					n.Node,
-					n.Status,
-					formatCPUPercent(n.CPU),
-					formatBytes(n.Mem),
-					formatBytes(n.MaxMem),
-					formatBytes(n.Disk),
-					formatUptime(n.Uptime),
-				)
-			}
-			return w.Flush()
+			return writeTable(cmd.OutOrStdout(), nodeTable(nodes), nil)
 		},
 	}
+	addWatchFlag(cmd, &watch)
+	return cmd
+}
+
+func nodeTable(nodes proxmox.NodeStatuses) listTable {
+	t := listTable{header: "NODE\tSTATUS\tCPU\tMEM USED\tMEM TOTAL\tDISK\tUPTIME"}
+	for _, n := range nodes {
+		t.rows = append(t.rows, listRow{
+			line: fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s\t%s",
+				n.Node,
+				n.Status,
+				formatCPUPercent(n.CPU),
+				formatBytes(n.Mem),
+				formatBytes(n.MaxMem),
+				formatBytes(n.Disk),
+				formatUptime(n.Uptime),
+			),
+			key: n.Node,
+			sig: fmt.Sprintf("%s\t%s\t%s\t%s", n.Status, formatCPUPercent(n.CPU), formatBytes(n.Mem), formatBytes(n.Disk)),
+		})
+	}
+	return t
 }
 
 func nodeStatusCmd() *cobra.Command {
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -16,9 +17,12 @@ import (
 
 // ANSI color codes used across CLI output functions.
 const (
-	colorReset = "\033[0m"
-	colorRed   = "\033[31m"         // template VMs / containers
-	colorGold  = "\033[38;5;220m"   // empty-list notices
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"       // template VMs / containers
+	colorGreen   = "\033[32m"       // firewall sync additions
+	colorGold    = "\033[38;5;220m" // empty-list notices
+	colorChanged = "\033[1;33m"     // rows that changed since the last --watch refresh
+	colorDim     = "\033[2m"        // disabled firewall rules
 )
 
 // Spinner shows an animated braille spinner on stderr while work is in progress.
@@ -26,6 +30,8 @@ const (
 type Spinner struct {
 	stop chan struct{}
 	wg   sync.WaitGroup
+	mu   sync.Mutex
+	msg  string
 }
 
 var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
@@ -33,7 +39,7 @@ var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "
 // startSpinner starts the spinner with the given message and returns it.
 // Call Stop() when the operation completes.
 func startSpinner(msg string) *Spinner {
-	s := &Spinner{stop: make(chan struct{})}
+	s := &Spinner{stop: make(chan struct{}), msg: msg}
 	if !stderrIsTerminal() {
 		return s
 	}
@@ -49,7 +55,9 @@ func startSpinner(msg string) *Spinner {
 				fmt.Fprint(os.Stderr, "\r\033[K") // clear the spinner line
 				return
 			case <-tick.C:
-				fmt.Fprintf(os.Stderr, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], msg)
+				s.mu.Lock()
+				fmt.Fprintf(os.Stderr, "\r\033[K%s %s", spinnerFrames[i%len(spinnerFrames)], s.msg)
+				s.mu.Unlock()
 				i++
 			}
 		}
@@ -57,6 +65,13 @@ func startSpinner(msg string) *Spinner {
 	return s
 }
 
+// SetMessage replaces the spinner's message, e.g. to show progress.
+func (s *Spinner) SetMessage(msg string) {
+	s.mu.Lock()
+	s.msg = msg
+	s.mu.Unlock()
+}
+
 // Stop halts the spinner and clears its line. Safe to call on a no-op spinner.
 func (s *Spinner) Stop() {
 	select {
@@ -174,18 +189,69 @@ func selectFromList(cmd *cobra.Command, items map[string]string, noun string) (s
 	}
 }
 
-// watchTask streams task log lines to w. Falls back to WaitFor if Watch
-// returns an error (e.g. no logs yet available).
+// taskHandle is the machine-readable reference printed for a task in
+// --no-wait mode. Scripts can pass it back to "pxve task wait".
+type taskHandle struct {
+	UPID string `json:"upid"`
+	Node string `json:"node"`
+	Type string `json:"type"`
+}
+
+// detachTask prints a handle for task and returns true when --no-wait is in
+// effect, in which case the caller should return without waiting. The handle
+// is the bare UPID, or a single-line JSON object with --output json.
+func detachTask(cmd *cobra.Command, task *proxmox.Task) bool {
+	if flagWait && !flagNoWait {
+		return false
+	}
+	if flagOutput == "json" {
+		json.NewEncoder(cmd.OutOrStdout()).Encode(taskHandle{ //nolint:errcheck
+			UPID: string(task.UPID),
+			Node: task.Node,
+			Type: task.Type,
+		})
+	} else {
+		fmt.Fprintln(cmd.OutOrStdout(), task.UPID)
+	}
+	return true
+}
+
+// taskContext bounds ctx by --timeout. A zero timeout waits indefinitely.
+func taskContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if flagTimeout > 0 {
+		return context.WithTimeout(ctx, flagTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// watchTask streams task log lines to w until the task finishes or --timeout
+// expires. Falls back to polling if Watch returns an error (e.g. no logs yet
+// available). Configured hooks run once the task has finished.
 func watchTask(ctx context.Context, w io.Writer, task *proxmox.Task) error {
+	started := time.Now()
+	defer func() { runTaskHooks(task, started) }()
+
+	ctx, cancel := taskContext(ctx)
+	defer cancel()
+
 	ch, err := task.Watch(ctx, 0)
 	if err != nil {
 		// No log output available — just wait for completion
 		fmt.Fprintf(w, "Task %s started (no log output available)...\n", task.UPID)
-		return task.WaitFor(ctx, 300)
+		return waitTask(ctx, task)
 	}
-	for line := range ch {
-		if line != "" && line != "no content" {
-			fmt.Fprintln(w, line)
+stream:
+	for {
+		select {
+		case <-ctx.Done():
+			return taskTimeoutErr(task)
+		case line, ok := <-ch:
+			if !ok {
+				break stream
+			}
+			if line != "" && line != "no content" {
+				fmt.Fprintln(w, line)
+			}
 		}
 	}
 	// After channel closes, ping to get final status
@@ -197,3 +263,34 @@ func watchTask(ctx context.Context, w io.Writer, task *proxmox.Task) error {
 	}
 	return nil
 }
+
+// waitTask polls task until it stops running or ctx is done. It returns an
+// error when the task finished with a non-OK exit status.
+func waitTask(ctx context.Context, task *proxmox.Task) error {
+	for {
+		if err := task.Ping(ctx); err != nil {
+			if ctx.Err() != nil {
+				return taskTimeoutErr(task)
+			}
+			return err
+		}
+		if task.Status != proxmox.TaskRunning {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return taskTimeoutErr(task)
+		case <-time.After(proxmox.DefaultWaitInterval):
+		}
+	}
+	if task.IsFailed {
+		return fmt.Errorf("task failed: %s", task.ExitStatus)
+	}
+	return nil
+}
+
+// taskTimeoutErr reports that --timeout expired. The task itself keeps running
+// on the server, so the UPID is included for a later "pxve task wait".
+func taskTimeoutErr(task *proxmox.Task) error {
+	return fmt.Errorf("timed out after %s waiting for task %s (it is still running on the server)", flagTimeout, task.UPID)
+}
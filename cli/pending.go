@@ -0,0 +1,215 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// pendingRow is a pending change as printed by "pending", with whether the
+// guest must be restarted for it to take effect.
+type pendingRow struct {
+	actions.PendingChange
+	Reboot bool `json:"reboot"`
+}
+
+func guestPendingCmd(typ string) *cobra.Command {
+	var nodeName string
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "pending " + id,
+		Short: "Show " + noun + " config changes that are not live yet",
+		Long: `List config changes a running guest could not apply immediately, with
+the running and the pending value of each key. Changes of a running guest
+need a reboot; a stopped guest picks them up when it starts.`,
+		Args: cobra.ExactArgs(1),
+		Example: fmt.Sprintf(`  pxve %[1]s pending 100
+  pxve %[1]s pending revert 100 memory`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, typ, args[0], nodeName)
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading pending changes...")
+			pending, err := actions.GuestPending(ctx, proxmoxClient, cfg)
+			var status string
+			if err == nil {
+				status, err = actions.GuestStatus(ctx, proxmoxClient, cfg)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			rows := make([]pendingRow, len(pending))
+			for i, p := range pending {
+				rows[i] = pendingRow{PendingChange: p, Reboot: status == "running"}
+			}
+
+			out := cmd.OutOrStdout()
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(rows)
+			}
+			if len(rows) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(out, "%s%s %d has no pending changes.%s\n", colorGold, capitalize(cfg.Noun()), cfg.VMID, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tCURRENT\tPENDING\tREBOOT")
+			for _, r := range rows {
+				pendingValue := r.Pending
+				if r.Delete {
+					pendingValue = "(removed)"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Key, dash(r.Value), dash(pendingValue), yesNoBool(r.Reboot))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if status == "running" && stdoutIsTerminal() {
+				fmt.Fprintf(out, "\nReboot %s %d to apply, or revert with 'pxve %s pending revert %d'.\n", cfg.Noun(), cfg.VMID, cliNoun(typ), cfg.VMID)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.AddCommand(guestPendingRevertCmd(typ))
+	return cmd
+}
+
+func guestPendingRevertCmd(typ string) *cobra.Command {
+	var o configEditOpts
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   "revert " + id + " [key]...",
+		Short: "Drop pending " + noun + " config changes",
+		Long: `Drop pending config changes so the running values stay. Without keys,
+every pending change is reverted.`,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: pendingKeyCompletion(typ),
+		Example: fmt.Sprintf(`  pxve %[1]s pending revert 100
+  pxve %[1]s pending revert 100 memory net0`, cliNoun(typ)),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadGuestConfig(cmd, typ, args[0], o.nodeName)
+			if err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading pending changes...")
+			pending, err := actions.GuestPending(ctx, proxmoxClient, cfg)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			out := cmd.OutOrStdout()
+			keys := args[1:]
+			byKey := map[string]actions.PendingChange{}
+			for _, p := range pending {
+				byKey[p.Key] = p
+			}
+			for _, k := range keys {
+				if _, ok := byKey[k]; !ok {
+					return fmt.Errorf("%s %d has no pending change to %s", capitalize(cfg.Noun()), cfg.VMID, k)
+				}
+			}
+			if len(keys) == 0 {
+				for _, p := range pending {
+					keys = append(keys, p.Key)
+				}
+			}
+			if len(keys) == 0 {
+				fmt.Fprintf(out, "%s %d has no pending changes.\n", capitalize(cfg.Noun()), cfg.VMID)
+				return nil
+			}
+
+			// Shown as a diff of the config the guest will have after its
+			// next restart: the pending value goes, the running one stays.
+			var changes []actions.ConfigChange
+			for _, k := range keys {
+				p := byKey[k]
+				ch := actions.ConfigChange{Key: k, Old: p.Pending, New: p.Value}
+				if p.Delete {
+					ch.Old = ""
+				}
+				changes = append(changes, ch)
+			}
+			printConfigChanges(out, changes)
+			if o.dryRun {
+				return nil
+			}
+			if !o.force {
+				fmt.Fprintf(out, "Revert %d pending change(s) of %s %d? [y/N]: ", len(keys), cfg.Noun(), cfg.VMID)
+				if !readYes(cmd) {
+					fmt.Fprintln(out, "Cancelled.")
+					return nil
+				}
+			}
+
+			s = startSpinner("Reverting...")
+			task, err := actions.RevertGuestPending(ctx, proxmoxClient, cfg, keys)
+			s.Stop()
+			if err != nil {
+				return configUpdateErr(cfg, err)
+			}
+			if task != nil {
+				if detachTask(cmd, task) {
+					return nil
+				}
+				if err := watchTask(ctx, out, task); err != nil {
+					return configUpdateErr(cfg, err)
+				}
+			}
+			fmt.Fprintf(out, "Reverted %s on %s %d.\n", strings.Join(keys, ", "), cfg.Noun(), cfg.VMID)
+			return nil
+		},
+	}
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// pendingKeyCompletion completes the keys with pending changes, skipping
+// those already on the command line.
+func pendingKeyCompletion(typ string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var vmid int
+		if _, err := fmt.Sscan(args[0], &vmid); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		if err := initClient(cmd); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		cfg, err := actions.GetGuestConfig(ctx, proxmoxClient, typ, vmid, "")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		pending, err := actions.GuestPending(ctx, proxmoxClient, cfg)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var out []string
+		for _, p := range pending {
+			if !slices.Contains(args[1:], p.Key) {
+				out = append(out, p.Key)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
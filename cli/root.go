@@ -3,6 +3,8 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	proxmox "github.com/luthermonson/go-proxmox"
 	"github.com/spf13/cobra"
@@ -22,6 +24,7 @@ var (
 	proxmoxClient   *proxmox.Client
 	resolvedConfig  *config.Config
 	resolvedInstURL string
+	resolvedCommand string // command path and positional args, reported to hooks
 
 	// global flags
 	flagInstance    string
@@ -34,6 +37,9 @@ var (
 	flagOutput      string
 	flagTUI         bool
 	flagUpgrade     bool
+	flagWait        bool
+	flagNoWait      bool
+	flagTimeout     time.Duration
 )
 
 // rootCmd is the base command.
@@ -90,7 +96,10 @@ func Execute() {
 	rootCmd.PersistentFlags().StringVar(&flagUsername, "username", "", "Proxmox username (e.g. root@pam)")
 	rootCmd.PersistentFlags().StringVar(&flagPassword, "password", "", "Proxmox password")
 	rootCmd.PersistentFlags().BoolVar(&flagSecure, "secure", false, "enforce TLS certificate verification (default is to skip verification)")
-	rootCmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", "table", "output format: table or json")
+	rootCmd.PersistentFlags().StringVarP(&flagOutput, "output", "o", "table", "output format: table or json (metrics commands also accept csv)")
+	rootCmd.PersistentFlags().BoolVar(&flagWait, "wait", true, "wait for tasks started by mutating commands to finish")
+	rootCmd.PersistentFlags().BoolVar(&flagNoWait, "no-wait", false, "print the task UPID (or a JSON handle with --output json) and exit without waiting")
+	rootCmd.PersistentFlags().DurationVar(&flagTimeout, "timeout", 0, "maximum time to wait for a task, e.g. 30m (0 = no limit)")
 
 	// Sub-command groups
 	rootCmd.AddCommand(instanceCmd())
@@ -103,6 +112,13 @@ func Execute() {
 	rootCmd.AddCommand(roleCmd())
 	rootCmd.AddCommand(backupCmd())
 	rootCmd.AddCommand(groupCmd())
+	rootCmd.AddCommand(taskCmd())
+	rootCmd.AddCommand(serveCmd())
+	rootCmd.AddCommand(eventsCmd())
+	rootCmd.AddCommand(hookCmd())
+	rootCmd.AddCommand(firewallCmd())
+	rootCmd.AddCommand(sdnCmd())
+	rootCmd.AddCommand(snapshotCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, "Error:", err)
@@ -119,6 +135,7 @@ func initClient(cmd *cobra.Command) error {
 		return fmt.Errorf("loading config: %w", err)
 	}
 	resolvedConfig = cfg
+	resolvedCommand = strings.Join(append([]string{cmd.CommandPath()}, cmd.Flags().Args()...), " ")
 
 	// Inline flags take precedence over everything when --url is provided
 	if flagURL != "" {
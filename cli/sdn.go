@@ -0,0 +1,621 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// sdnColumn is a table column of an SDN object list.
+type sdnColumn struct {
+	header, key string
+}
+
+// sdnKindSpec describes the commands of one SDN object kind.
+type sdnKindSpec struct {
+	kind     actions.SDNKind
+	short    string
+	idArg    string      // e.g. "<zone>"
+	required settingFlag // flag required on create (type or zone)
+	choices  []string    // valid values of required, if restricted
+	flags    []settingFlag
+	columns  []sdnColumn
+	examples string
+}
+
+var sdnKindSpecs = []sdnKindSpec{
+	{
+		kind:     actions.SDNZones,
+		short:    "Manage SDN zones",
+		idArg:    "<zone>",
+		required: settingFlag{flag: "type", key: "type", usage: "zone type: " + strings.Join(actions.SDNZoneTypes, ", ") + " (required)"},
+		choices:  actions.SDNZoneTypes,
+		flags: []settingFlag{
+			{flag: "bridge", key: "bridge", usage: "bridge the zone is built on (vlan, qinq)"},
+			{flag: "tag", key: "tag", usage: "service VLAN tag (qinq)"},
+			{flag: "vlan-protocol", key: "vlan-protocol", usage: "802.1q or 802.1ad (qinq)"},
+			{flag: "peers", key: "peers", usage: "peer addresses, comma-separated (vxlan)"},
+			{flag: "controller", key: "controller", usage: "EVPN controller (evpn)"},
+			{flag: "vrf-vxlan", key: "vrf-vxlan", usage: "VRF VXLAN tag (evpn)"},
+			{flag: "exitnodes", key: "exitnodes", usage: "exit nodes, comma-separated (evpn)"},
+			{flag: "mtu", key: "mtu", usage: "MTU"},
+			{flag: "nodes", key: "nodes", usage: "restrict the zone to these nodes, comma-separated"},
+			{flag: "ipam", key: "ipam", usage: "IPAM plugin (e.g. pve)"},
+			{flag: "dns", key: "dns", usage: "DNS plugin"},
+			{flag: "dnszone", key: "dnszone", usage: "DNS domain"},
+			{flag: "dhcp", key: "dhcp", usage: "automatic DHCP (dnsmasq)"},
+		},
+		columns: []sdnColumn{{"ZONE", "zone"}, {"TYPE", "type"}, {"BRIDGE/PEERS", "bridge|peers|controller"}, {"MTU", "mtu"}, {"NODES", "nodes"}, {"IPAM", "ipam"}},
+		examples: `  pxve sdn zone create lab --type simple --ipam pve --dhcp dnsmasq
+  pxve sdn zone create dc1 --type vlan --bridge vmbr0
+  pxve sdn zone create overlay --type vxlan --peers 10.0.0.1,10.0.0.2,10.0.0.3 --mtu 1450`,
+	},
+	{
+		kind:     actions.SDNVNets,
+		short:    "Manage SDN vnets (virtual networks guests attach to)",
+		idArg:    "<vnet>",
+		required: settingFlag{flag: "zone", key: "zone", usage: "zone of the vnet (required)"},
+		flags: []settingFlag{
+			{flag: "tag", key: "tag", usage: "VLAN or VXLAN tag"},
+			{flag: "alias", key: "alias", usage: "description"},
+			{flag: "vlan-aware", key: "vlanaware", usage: "allow guests to use VLAN tags inside the vnet", boolean: true},
+			{flag: "isolate-ports", key: "isolate-ports", usage: "isolate guest ports from each other", boolean: true},
+		},
+		columns:  []sdnColumn{{"VNET", "vnet"}, {"ZONE", "zone"}, {"TAG", "tag"}, {"VLAN-AWARE", "vlanaware"}, {"ALIAS", "alias"}},
+		examples: `  pxve sdn vnet create web --zone dc1 --tag 20 --alias "web tier"`,
+	},
+	{
+		kind:  actions.SDNSubnets,
+		short: "Manage subnets of an SDN vnet",
+		idArg: "<subnet>",
+		flags: []settingFlag{
+			{flag: "gateway", key: "gateway", usage: "gateway address"},
+			{flag: "snat", key: "snat", usage: "masquerade traffic leaving the subnet", boolean: true},
+			{flag: "dnszoneprefix", key: "dnszoneprefix", usage: "DNS zone prefix (e.g. adm -> <host>.adm.<domain>)"},
+		},
+		columns: []sdnColumn{{"SUBNET", "cidr"}, {"GATEWAY", "gateway"}, {"SNAT", "snat"}, {"ID", "subnet"}},
+		examples: `  pxve sdn subnet create web 10.20.0.0/24 --gateway 10.20.0.1 --snat
+  pxve sdn subnet create web 10.20.0.0/24 --set dhcp-range=start-address=10.20.0.100,end-address=10.20.0.200`,
+	},
+	{
+		kind:     actions.SDNControllers,
+		short:    "Manage SDN controllers (EVPN, BGP, ISIS)",
+		idArg:    "<controller>",
+		required: settingFlag{flag: "type", key: "type", usage: "controller type: " + strings.Join(actions.SDNControllerTypes, ", ") + " (required)"},
+		choices:  actions.SDNControllerTypes,
+		flags: []settingFlag{
+			{flag: "asn", key: "asn", usage: "autonomous system number"},
+			{flag: "peers", key: "peers", usage: "peer addresses, comma-separated"},
+			{flag: "node", key: "node", usage: "node the controller runs on (bgp, isis)"},
+			{flag: "ebgp", key: "ebgp", usage: "use eBGP (bgp)", boolean: true},
+			{flag: "loopback", key: "loopback", usage: "source loopback interface"},
+			{flag: "isis-domain", key: "isis-domain", usage: "ISIS domain (isis)"},
+			{flag: "isis-ifaces", key: "isis-ifaces", usage: "ISIS interfaces, comma-separated (isis)"},
+			{flag: "isis-net", key: "isis-net", usage: "ISIS network entity title (isis)"},
+		},
+		columns:  []sdnColumn{{"CONTROLLER", "controller"}, {"TYPE", "type"}, {"ASN", "asn"}, {"PEERS", "peers"}, {"NODE", "node"}},
+		examples: `  pxve sdn controller create evpn1 --type evpn --asn 65000 --peers 10.0.0.1,10.0.0.2`,
+	},
+}
+
+func sdnCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sdn",
+		Short: "Manage software-defined networking (zones, vnets, subnets, controllers)",
+		Long: `Manage Proxmox SDN. create, update, and delete only change the pending SDN
+configuration; "pxve sdn apply" deploys it to all nodes.`,
+	}
+	for _, spec := range sdnKindSpecs {
+		cmd.AddCommand(sdnKindCmd(spec))
+	}
+	cmd.AddCommand(sdnStatusCmd())
+	cmd.AddCommand(sdnApplyCmd())
+	return cmd
+}
+
+// sdnArgs returns the usage prefix and argument count of spec's commands
+// that take an object ID.
+func (spec sdnKindSpec) sdnArgs() (string, int) {
+	if spec.kind == actions.SDNSubnets {
+		return "<vnet> " + spec.idArg, 2
+	}
+	return spec.idArg, 1
+}
+
+// parent returns the vnet argument for subnets and "" otherwise.
+func (spec sdnKindSpec) parent(args []string) string {
+	if spec.kind == actions.SDNSubnets {
+		return args[0]
+	}
+	return ""
+}
+
+// resolveID returns the API ID of the object named by the last argument.
+// Subnets may be given by CIDR.
+func (spec sdnKindSpec) resolveID(ctx context.Context, args []string) (string, error) {
+	id := args[len(args)-1]
+	if spec.kind == actions.SDNSubnets {
+		return actions.ResolveSDNSubnet(ctx, proxmoxClient, args[0], id)
+	}
+	return id, nil
+}
+
+func sdnKindCmd(spec sdnKindSpec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   spec.kind.Name,
+		Short: spec.short,
+	}
+	cmd.AddCommand(sdnListCmd(spec))
+	cmd.AddCommand(sdnShowCmd(spec))
+	cmd.AddCommand(sdnCreateCmd(spec))
+	cmd.AddCommand(sdnUpdateCmd(spec))
+	cmd.AddCommand(sdnDeleteCmd(spec))
+	return cmd
+}
+
+func sdnListCmd(spec sdnKindSpec) *cobra.Command {
+	use, nargs := "list", 0
+	if spec.kind == actions.SDNSubnets {
+		use, nargs = "list <vnet>", 1
+	}
+	return &cobra.Command{
+		Use:   use,
+		Short: "List " + spec.kind.Name + "s, including pending changes",
+		Args:  cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading " + spec.kind.Name + "s...")
+			objs, err := actions.SDNObjects(ctx, proxmoxClient, spec.kind, spec.parent(args))
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if objs == nil {
+					objs = []actions.SDNObject{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(objs)
+			}
+
+			if len(objs) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo SDN %ss found.%s\n", colorGold, spec.kind.Name, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			for _, col := range spec.columns {
+				fmt.Fprintf(w, "%s\t", col.header)
+			}
+			fmt.Fprintln(w, "STATE")
+			pending := false
+			for _, o := range objs {
+				for _, col := range spec.columns {
+					v := ""
+					for _, key := range strings.Split(col.key, "|") {
+						if v = o.Str(key); v != "" {
+							break
+						}
+					}
+					if col.key == "vlanaware" || col.key == "snat" {
+						v = yesNoBool(v == "1")
+					}
+					fmt.Fprintf(w, "%s\t", dash(v))
+				}
+				fmt.Fprintln(w, dash(o.State))
+				pending = pending || o.State != ""
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if pending && stdoutIsTerminal() {
+				fmt.Fprintf(cmd.OutOrStdout(), "%sPending changes — deploy with 'pxve sdn apply'.%s\n", colorGold, colorReset)
+			}
+			return nil
+		},
+	}
+}
+
+func sdnShowCmd(spec sdnKindSpec) *cobra.Command {
+	use, nargs := spec.sdnArgs()
+	return &cobra.Command{
+		Use:   "show " + use,
+		Short: "Show all settings of a " + spec.kind.Name,
+		Args:  cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading...")
+			id, err := spec.resolveID(ctx, args)
+			var o actions.SDNObject
+			if err == nil {
+				o, err = actions.GetSDNObject(ctx, proxmoxClient, spec.kind, spec.parent(args), id)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(o)
+			}
+
+			keys := make([]string, 0, len(o.Fields))
+			for k := range o.Fields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			for _, k := range keys {
+				fmt.Fprintf(w, "%s:\t%v\n", k, o.Fields[k])
+			}
+			if o.State != "" {
+				fmt.Fprintf(w, "state:\t%s (not applied)\n", o.State)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func sdnCreateCmd(spec sdnKindSpec) *cobra.Command {
+	var (
+		required string
+		settings apiSettings
+	)
+	use, nargs := spec.sdnArgs()
+	if spec.kind == actions.SDNSubnets {
+		use = "<vnet> <cidr>"
+	}
+	cmd := &cobra.Command{
+		Use:     "create " + use,
+		Short:   "Add a " + spec.kind.Name + " to the pending SDN configuration",
+		Example: spec.examples,
+		Args:    cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params, err := settings.params(cmd)
+			if err != nil {
+				return err
+			}
+			if spec.required.flag != "" {
+				if required == "" {
+					return fmt.Errorf("--%s is required", spec.required.flag)
+				}
+				if len(spec.choices) > 0 {
+					valid := false
+					for _, c := range spec.choices {
+						valid = valid || c == required
+					}
+					if !valid {
+						return fmt.Errorf("invalid --%s %q (must be one of %s)", spec.required.flag, required, strings.Join(spec.choices, ", "))
+					}
+				}
+				params[spec.required.key] = required
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			id := args[len(args)-1]
+			ctx := context.Background()
+			s := startSpinner("Creating " + spec.kind.Name + "...")
+			err = actions.CreateSDNObject(ctx, proxmoxClient, spec.kind, spec.parent(args), id, params)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s added to the pending SDN configuration.\n", capitalize(spec.kind.Name), id)
+			printPendingSDNHint(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	if spec.required.flag != "" {
+		cmd.Flags().StringVar(&required, spec.required.flag, "", spec.required.usage)
+	}
+	addSettingFlags(cmd, &settings, spec.flags)
+	return cmd
+}
+
+func sdnUpdateCmd(spec sdnKindSpec) *cobra.Command {
+	var (
+		settings apiSettings
+		unset    []string
+	)
+	use, nargs := spec.sdnArgs()
+	cmd := &cobra.Command{
+		Use:   "update " + use,
+		Short: "Change a " + spec.kind.Name + " in the pending SDN configuration",
+		Args:  cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params, err := settings.params(cmd)
+			if err != nil {
+				return err
+			}
+			if len(params) == 0 && len(unset) == 0 {
+				return fmt.Errorf("nothing to change")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Updating " + spec.kind.Name + "...")
+			id, err := spec.resolveID(ctx, args)
+			if err == nil {
+				err = actions.UpdateSDNObject(ctx, proxmoxClient, spec.kind, spec.parent(args), id, params, unset)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s updated in the pending SDN configuration.\n", capitalize(spec.kind.Name), args[len(args)-1])
+			printPendingSDNHint(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	addSettingFlags(cmd, &settings, spec.flags)
+	cmd.Flags().StringSliceVar(&unset, "unset", nil, "comma-separated API parameters to remove")
+	return cmd
+}
+
+func sdnDeleteCmd(spec sdnKindSpec) *cobra.Command {
+	var force bool
+	use, nargs := spec.sdnArgs()
+	cmd := &cobra.Command{
+		Use:   "delete " + use,
+		Short: "Remove a " + spec.kind.Name + " from the pending SDN configuration",
+		Args:  cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[len(args)-1]
+			if !force {
+				fmt.Fprintf(cmd.OutOrStdout(), "Delete SDN %s %s? [y/N]: ", spec.kind.Name, name)
+				if !readYes(cmd) {
+					fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
+					return nil
+				}
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Deleting " + spec.kind.Name + "...")
+			id, err := spec.resolveID(ctx, args)
+			if err == nil {
+				err = actions.DeleteSDNObject(ctx, proxmoxClient, spec.kind, spec.parent(args), id)
+			}
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %s removed from the pending SDN configuration.\n", capitalize(spec.kind.Name), name)
+			printPendingSDNHint(cmd.OutOrStdout())
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
+
+func printPendingSDNHint(w io.Writer) {
+	fmt.Fprintln(w, "Deploy to all nodes with 'pxve sdn apply'.")
+}
+
+// sdnPendingChange is an SDN object with a pending change.
+type sdnPendingChange struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	VNet  string `json:"vnet,omitempty"`
+	State string `json:"state"`
+}
+
+// sdnPendingChanges lists every SDN object with a pending change.
+func sdnPendingChanges(ctx context.Context) ([]sdnPendingChange, error) {
+	var out []sdnPendingChange
+	for _, kind := range []actions.SDNKind{actions.SDNControllers, actions.SDNZones, actions.SDNVNets} {
+		objs, err := actions.SDNObjects(ctx, proxmoxClient, kind, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			if o.State != "" {
+				out = append(out, sdnPendingChange{Kind: kind.Name, ID: o.ID, State: o.State})
+			}
+			if kind != actions.SDNVNets {
+				continue
+			}
+			subnets, err := actions.SDNObjects(ctx, proxmoxClient, actions.SDNSubnets, o.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, sn := range subnets {
+				if sn.State != "" {
+					out = append(out, sdnPendingChange{Kind: "subnet", ID: dash(sn.Str("cidr")), VNet: o.ID, State: sn.State})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// printSDNPendingChanges prints pending changes as +/~/- lines, colored on a
+// terminal.
+func printSDNPendingChanges(w io.Writer, changes []sdnPendingChange) {
+	tty := stdoutIsTerminal()
+	for _, ch := range changes {
+		op, color := "~", colorGold
+		switch ch.State {
+		case "new":
+			op, color = "+", colorGreen
+		case "deleted":
+			op, color = "-", colorRed
+		}
+		name := ch.ID
+		if ch.VNet != "" {
+			name = ch.VNet + "/" + ch.ID
+		}
+		line := fmt.Sprintf("%s %s %s (%s)", op, ch.Kind, name, ch.State)
+		if tty {
+			line = color + line + colorReset
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// printSDNStatus prints the per-node zone status table.
+func printSDNStatus(w io.Writer, status []actions.SDNZoneStatus) error {
+	tty := stdoutIsTerminal()
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NODE\tZONE\tSTATUS")
+	for _, st := range status {
+		label := st.Status
+		if tty {
+			switch st.Status {
+			case "available":
+				label = colorGreen + label + colorReset
+			case "error":
+				label = colorRed + label + colorReset
+			default:
+				label = colorGold + label + colorReset
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", st.Node, st.Zone, label)
+	}
+	return tw.Flush()
+}
+
+func sdnStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of every SDN zone on every node",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Loading SDN status...")
+			status, err := actions.SDNStatus(context.Background(), proxmoxClient)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if status == nil {
+					status = []actions.SDNZoneStatus{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(status)
+			}
+
+			if len(status) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo SDN zones deployed.%s\n", colorGold, colorReset)
+				}
+				return nil
+			}
+			return printSDNStatus(cmd.OutOrStdout(), status)
+		},
+	}
+}
+
+func sdnApplyCmd() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Deploy the pending SDN configuration to all nodes",
+		Long: `List the pending SDN changes and, after confirmation, reload the SDN
+configuration on all nodes. Once the task finishes, the state of every zone
+on every node is reported; the command fails if any zone is in error.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading pending changes...")
+			changes, err := sdnPendingChanges(ctx)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			out := cmd.OutOrStdout()
+			if len(changes) == 0 {
+				fmt.Fprintln(out, "No pending SDN changes; redeploying the current configuration.")
+			} else {
+				printSDNPendingChanges(out, changes)
+			}
+			if !force {
+				fmt.Fprint(out, "Apply the SDN configuration to all nodes? [y/N]: ")
+				if !readYes(cmd) {
+					fmt.Fprintln(out, "Cancelled.")
+					return nil
+				}
+			}
+
+			s = startSpinner("Applying SDN configuration...")
+			task, err := actions.ApplySDN(ctx, proxmoxClient)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if detachTask(cmd, task) {
+				return nil
+			}
+			if err := watchTask(ctx, out, task); err != nil {
+				return handleErr(err)
+			}
+
+			s = startSpinner("Loading SDN status...")
+			status, err := actions.SDNStatus(ctx, proxmoxClient)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			if flagOutput == "json" {
+				if status == nil {
+					status = []actions.SDNZoneStatus{}
+				}
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(status); err != nil {
+					return err
+				}
+			} else if len(status) > 0 {
+				if err := printSDNStatus(out, status); err != nil {
+					return err
+				}
+			}
+			failed := 0
+			for _, st := range status {
+				if st.Status == "error" {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("SDN configuration applied, but %d zone(s) are in error", failed)
+			}
+			if flagOutput != "json" {
+				fmt.Fprintln(out, "SDN configuration applied.")
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "skip confirmation prompt")
+	return cmd
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+const selectorUsage = `guests to act on: comma-separated key=value terms, all of which must match
+(keys: tag, name (glob), node, pool, status, type (vm|ct), vmid (N or N-M); key!=value negates)`
+
+// addSelectorFlag adds --selector with completion of keys and tags.
+func addSelectorFlag(cmd *cobra.Command, p *string) {
+	cmd.Flags().StringVar(p, "selector", "", selectorUsage)
+	_ = cmd.RegisterFlagCompletionFunc("selector", selectorCompletion)
+}
+
+// selectorCompletion completes the last term of a selector: its key, or the
+// value of tag, status, and type terms.
+func selectorCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	directive := cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+	prefix, term := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, term = toComplete[:i+1], toComplete[i+1:]
+	}
+	key, _, hasValue := strings.Cut(term, "=")
+	if !hasValue {
+		var out []string
+		for _, k := range actions.SelectorKeys {
+			out = append(out, prefix+k+"=", prefix+k+"!=")
+		}
+		return out, directive
+	}
+	var values []string
+	switch strings.TrimSuffix(key, "!") {
+	case "status":
+		values = []string{"running", "stopped", "paused"}
+	case "type":
+		values = []string{"vm", "ct"}
+	case "tag":
+		if initClient(cmd) != nil {
+			return nil, directive
+		}
+		tags, err := actions.AllInstanceTags(context.Background(), proxmoxClient)
+		if err != nil {
+			return nil, directive
+		}
+		values = tags
+	}
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		out = append(out, prefix+key+"="+v)
+	}
+	return out, directive
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/client"
+	"github.com/chupakbra/proxmox-cli/internal/config"
+	"github.com/chupakbra/proxmox-cli/internal/exporter"
+)
+
+func serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run long-lived services backed by the configured instances",
+	}
+	cmd.AddCommand(serveMetricsCmd())
+	return cmd
+}
+
+func serveMetricsCmd() *cobra.Command {
+	var (
+		listen       string
+		interval     time.Duration
+		allInstances bool
+		instances    []string
+	)
+	cmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Serve cluster, node, guest, storage, and backup metrics for Prometheus",
+		Long: `Serve cluster, node, guest, storage, and backup metrics in the Prometheus
+text exposition format on /metrics.
+
+By default the current instance (or --instance / --url) is scraped. Use
+--instances or --all-instances to export several instances from ~/.pxve.yaml;
+each sample carries a pxve_instance label.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			targets, err := exporterTargets(cmd, allInstances, instances)
+			if err != nil {
+				return err
+			}
+
+			logger := log.New(cmd.ErrOrStderr(), "pxve: ", log.LstdFlags)
+			exp := exporter.New(targets, interval, logger)
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			srv := &http.Server{Addr: listen, Handler: exp.Handler(), ReadHeaderTimeout: 10 * time.Second}
+			go exp.Run(ctx)
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+
+			names := make([]string, len(targets))
+			for i, t := range targets {
+				names[i] = t.Name
+			}
+			logger.Printf("serving metrics for %v on %s/metrics", names, listen)
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&listen, "listen", ":9221", "address to listen on")
+	cmd.Flags().DurationVar(&interval, "interval", 0, "collect in the background at this interval and serve cached results (default: collect on every scrape)")
+	cmd.Flags().BoolVar(&allInstances, "all-instances", false, "export every instance in the config file")
+	cmd.Flags().StringSliceVar(&instances, "instances", nil, "comma-separated instance names to export")
+	return cmd
+}
+
+// exporterTargets builds one client per exported instance. Without
+// --instances/--all-instances it uses the same resolution as every other
+// command.
+func exporterTargets(cmd *cobra.Command, all bool, names []string) ([]exporter.Target, error) {
+	if !all && len(names) == 0 {
+		if err := initClient(cmd); err != nil {
+			return nil, err
+		}
+		name := flagInstance
+		if flagURL != "" {
+			if u, err := url.Parse(flagURL); err == nil && u.Host != "" {
+				name = u.Hostname()
+			} else {
+				name = flagURL
+			}
+		} else if _, resolved, err := resolvedConfig.Resolve(flagInstance); err == nil {
+			name = resolved
+		}
+		return []exporter.Target{{Name: name, Client: proxmoxClient}}, nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading config: %w", err)
+	}
+	if all {
+		names = names[:0]
+		for name := range cfg.Instances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no instances configured — run 'pxve instance add' first")
+		}
+	}
+
+	targets := make([]exporter.Target, 0, len(names))
+	for _, name := range names {
+		inst, ok := cfg.Instances[name]
+		if !ok {
+			return nil, fmt.Errorf("instance %q not found in config", name)
+		}
+		if flagSecure {
+			inst.VerifyTLS = true
+		}
+		c, err := client.New(&inst)
+		if err != nil {
+			return nil, fmt.Errorf("instance %s: %w", name, err)
+		}
+		targets = append(targets, exporter.Target{Name: name, Client: c})
+	}
+	return targets, nil
+}
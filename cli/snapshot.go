@@ -0,0 +1,239 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// printSnapshotTree prints the snapshots of a guest as a tree, marking where
+// the running state ("current") sits.
+func printSnapshotTree(out io.Writer, snaps []*actions.GuestSnapshot) error {
+	tree := actions.SnapshotTree(snaps)
+	if len(tree) <= 1 {
+		if stdoutIsTerminal() {
+			fmt.Fprintf(out, "%sNo snapshots.%s\n", colorGold, colorReset)
+		} else {
+			fmt.Fprintln(out, "No snapshots.")
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCREATED\tRAM\tDESCRIPTION")
+	for _, s := range tree {
+		if s.IsCurrent() {
+			mark := "you are here"
+			if stdoutIsTerminal() {
+				mark = colorGold + mark + colorReset
+			}
+			fmt.Fprintf(w, "%s● %s\t-\t-\t%s\n", s.Prefix, s.Name, mark)
+			continue
+		}
+		created := "-"
+		if s.Snaptime > 0 {
+			created = time.Unix(s.Snaptime, 0).Format("2006-01-02 15:04:05")
+		}
+		ram := "-"
+		if s.VMState {
+			ram = "yes"
+		}
+		desc, _, _ := strings.Cut(strings.TrimSpace(s.Description), "\n")
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", s.Prefix, s.Name, created, ram, desc)
+	}
+	return w.Flush()
+}
+
+// snapshotEditCmd returns "snapshot edit" for guests of type typ ("qemu" or
+// "lxc").
+func snapshotEditCmd(typ string) *cobra.Command {
+	var nodeName, description string
+	group, noun, idName := "vm", "VM", "vmid"
+	if typ == "lxc" {
+		group, noun, idName = "ct", "container", "ctid"
+	}
+	cmd := &cobra.Command{
+		Use:     fmt.Sprintf("edit <%s> <name>", idName),
+		Short:   fmt.Sprintf("Change the description of a %s snapshot", noun),
+		Args:    cobra.ExactArgs(2),
+		Example: fmt.Sprintf("  pxve %s snapshot edit 100 pre-upgrade --description \"before apt full-upgrade\"", group),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vmid, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid %s %q", strings.ToUpper(idName), args[0])
+			}
+			snapName := args[1]
+			if !cmd.Flags().Changed("description") {
+				return fmt.Errorf("nothing to change; use --description (an empty value clears it)")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			s := startSpinner("Updating snapshot...")
+			err = actions.SetGuestSnapshotDescription(context.Background(), proxmoxClient, typ, vmid, nodeName, snapName, description)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Snapshot %q updated.\n", snapName)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().StringVar(&description, "description", "", "new snapshot description")
+	return cmd
+}
+
+// snapshotCmd groups snapshot commands that act on many guests at once.
+func snapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Snapshot operations across VMs and containers",
+	}
+	cmd.AddCommand(snapshotRotateCmd())
+	return cmd
+}
+
+func snapshotRotateCmd() *cobra.Command {
+	var (
+		selector string
+		policy   actions.RotationPolicy
+		parallel int
+		dryRun   bool
+	)
+	cmd := &cobra.Command{
+		Use:   "rotate --selector <expr> [--keep-hourly N] [--keep-daily N]",
+		Short: "Take a timestamped snapshot of matching guests and prune old ones",
+		Long: `Take a snapshot named <prefix>-YYYYMMDD-HHMMSS (UTC) of every VM and
+container matching --selector, then delete that guest's older snapshots with
+the same prefix that the policy no longer keeps: the newest snapshot of each
+of the last --keep-hourly hours and of the last --keep-daily days that have
+one. Snapshots with any other name are never touched.
+
+Guests are handled in parallel, each one task at a time; if a guest's new
+snapshot fails, none of its snapshots are deleted. Each guest is reported,
+and the command exits non-zero if any guest failed, so it can run from cron.
+Templates are skipped.`,
+		Args: cobra.NoArgs,
+		Example: `  pxve snapshot rotate --selector tag=prod --keep-hourly 24 --keep-daily 7
+  pxve snapshot rotate --selector type=ct,node=pve1 --prefix nightly --keep-daily 14 --dry-run`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if selector == "" {
+				return fmt.Errorf("--selector is required")
+			}
+			sel, err := actions.ParseSelector(selector)
+			if err != nil {
+				return err
+			}
+			if err := policy.Validate(); err != nil {
+				return err
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Finding guests...")
+			matched, err := actions.SelectGuests(ctx, proxmoxClient, sel)
+			if err != nil {
+				s.Stop()
+				return handleErr(err)
+			}
+			var guests proxmox.ClusterResources
+			var skipped []string
+			for _, g := range matched {
+				if g.Template == 0 {
+					guests = append(guests, g)
+				} else {
+					skipped = append(skipped, strconv.FormatUint(g.VMID, 10))
+				}
+			}
+
+			wait := func(ctx context.Context, task *proxmox.Task) error {
+				started := time.Now()
+				defer runTaskHooks(task, started)
+				ctx, cancel := taskContext(ctx)
+				defer cancel()
+				return waitTask(ctx, task)
+			}
+			finished := 0
+			s.SetMessage(fmt.Sprintf("Rotating snapshots (0/%d)...", len(guests)))
+			results := actions.RotateGuests(ctx, proxmoxClient, guests, policy, dryRun, parallel, wait, func(*actions.RotationResult) {
+				finished++
+				s.SetMessage(fmt.Sprintf("Rotating snapshots (%d/%d)...", finished, len(guests)))
+			})
+			s.Stop()
+
+			out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+			if len(skipped) > 0 {
+				fmt.Fprintf(errOut, "Skipping %d matching template(s): %s\n", len(skipped), strings.Join(skipped, ", "))
+			}
+			failed := 0
+			for _, r := range results {
+				if r.Error != "" {
+					failed++
+				}
+			}
+			if flagOutput == "json" {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(results); err != nil {
+					return err
+				}
+			} else if len(results) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(out, "%sNo guests match the selector.%s\n", colorGold, colorReset)
+				} else {
+					fmt.Fprintln(out, "No guests match the selector.")
+				}
+				return nil
+			} else {
+				if dryRun {
+					fmt.Fprintln(errOut, "Dry run: no snapshots were created or deleted.")
+				}
+				if err := printRotation(out, errOut, results); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("snapshot rotation failed on %d of %d guests", failed, len(results))
+			}
+			return nil
+		},
+	}
+	addSelectorFlag(cmd, &selector)
+	cmd.Flags().StringVar(&policy.Prefix, "prefix", "auto", "name prefix of the snapshots to take and rotate")
+	cmd.Flags().IntVar(&policy.KeepHourly, "keep-hourly", 0, "keep the newest snapshot of each of the last N hours")
+	cmd.Flags().IntVar(&policy.KeepDaily, "keep-daily", 0, "keep the newest snapshot of each of the last N days")
+	cmd.Flags().IntVar(&parallel, "parallel", 4, "number of guests to work on at once")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be created and deleted without changing anything")
+	return cmd
+}
+
+// printRotation prints one line per guest; guests that failed are also
+// reported on errOut.
+func printRotation(out, errOut io.Writer, results []*actions.RotationResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VMID\tNAME\tTYPE\tNODE\tCREATED\tDELETED\tKEPT\tSTATUS")
+	for _, r := range results {
+		typ := "VM"
+		if r.Type == "lxc" {
+			typ = "CT"
+		}
+		status := "ok"
+		if r.Error != "" {
+			status = "error"
+			fmt.Fprintf(errOut, "%s %d (%s): %s\n", typ, r.VMID, r.Name, r.Error)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", r.VMID, r.Name, typ, r.Node, dash(r.Created), dash(strings.Join(r.Deleted, ", ")), len(r.Kept), status)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+func taskCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "Inspect and wait for Proxmox tasks",
+	}
+	cmd.AddCommand(taskStatusCmd())
+	cmd.AddCommand(taskWaitCmd())
+	return cmd
+}
+
+func taskStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <upid>...",
+		Short: "Show the status of one or more tasks",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading tasks...")
+			var tasks proxmox.Tasks
+			for _, upid := range args {
+				task, err := actions.GetTask(ctx, proxmoxClient, upid)
+				if err != nil {
+					s.Stop()
+					return handleErr(err)
+				}
+				tasks = append(tasks, task)
+			}
+			s.Stop()
+
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(tasks)
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "UPID\tNODE\tTYPE\tID\tUSER\tSTATUS")
+			for _, t := range tasks {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.UPID, t.Node, t.Type, t.ID, t.User, taskStatusString(t))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func taskWaitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "wait [upid...]",
+		Short: "Wait for tasks to finish",
+		Long: `Wait for one or more tasks to finish and report their exit status.
+
+Without arguments, task handles are read from stdin, one per line. Each line
+may be a bare UPID or a JSON handle as printed by --no-wait --output json.
+The global --timeout flag bounds the total wait. Exits non-zero if any task
+failed.`,
+		Example: `  pxve task wait UPID:pve:0000ABCD:...
+  pxve vm start 100 --no-wait > tasks.txt
+  pxve vm start 101 --no-wait >> tasks.txt
+  pxve task wait < tasks.txt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			upids := args
+			if len(upids) == 0 {
+				var err error
+				upids, err = readTaskHandles(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+			}
+			if len(upids) == 0 {
+				return fmt.Errorf("no tasks given")
+			}
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx, cancel := taskContext(context.Background())
+			defer cancel()
+
+			s := startSpinner(fmt.Sprintf("Waiting for %d task(s)...", len(upids)))
+			var tasks proxmox.Tasks
+			var waitErr error
+			for _, upid := range upids {
+				task, err := actions.GetTask(ctx, proxmoxClient, upid)
+				if err != nil {
+					waitErr = err
+					break
+				}
+				if err := waitTask(ctx, task); err != nil && !task.IsFailed {
+					waitErr = err
+					break
+				}
+				tasks = append(tasks, task)
+			}
+			s.Stop()
+			if waitErr != nil {
+				return handleErr(waitErr)
+			}
+
+			failed := 0
+			for _, t := range tasks {
+				if t.IsFailed {
+					failed++
+				}
+			}
+			if flagOutput == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(tasks); err != nil {
+					return err
+				}
+			} else {
+				w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "UPID\tNODE\tTYPE\tSTATUS")
+				for _, t := range tasks {
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.UPID, t.Node, t.Type, taskStatusString(t))
+				}
+				w.Flush()
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d task(s) failed", failed, len(tasks))
+			}
+			return nil
+		},
+	}
+}
+
+// readTaskHandles reads one task handle per line from r. Lines may hold a bare
+// UPID or a JSON taskHandle; blank lines are skipped.
+func readTaskHandles(r io.Reader) ([]string, error) {
+	var upids []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "{") {
+			var h taskHandle
+			if err := json.Unmarshal([]byte(line), &h); err != nil {
+				return nil, fmt.Errorf("parsing task handle %q: %w", line, err)
+			}
+			line = h.UPID
+		}
+		upids = append(upids, line)
+	}
+	return upids, sc.Err()
+}
+
+// taskStatusString returns "running" for active tasks and the exit status
+// (e.g. "OK" or an error message) for finished ones.
+func taskStatusString(t *proxmox.Task) string {
+	if t.Status == proxmox.TaskRunning || t.ExitStatus == "" {
+		return t.Status
+	}
+	return t.ExitStatus
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,7 +9,6 @@ import (
 	"strconv"
 	"strings"
 	"text/tabwriter"
-	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -48,22 +46,45 @@ func vmCmd() *cobra.Command {
 	cmd.AddCommand(vmSnapshotCmd())
 	cmd.AddCommand(vmTemplateCmd())
 	cmd.AddCommand(vmDiskCmd())
+	cmd.AddCommand(vmNICCmd())
+	cmd.AddCommand(vmHostPCICmd())
+	cmd.AddCommand(vmUSBCmd())
+	cmd.AddCommand(vmSerialCmd())
 	cmd.AddCommand(vmTagCmd())
 	cmd.AddCommand(vmConfigCmd())
+	cmd.AddCommand(guestPendingCmd("qemu"))
 	cmd.AddCommand(vmAgentCmd())
+	cmd.AddCommand(vmFactsCmd())
+	cmd.AddCommand(vmConsoleCmd(), vmSpiceCmd(), vmVNCURLCmd())
+	cmd.AddCommand(vmMetricsCmd())
 	return cmd
 }
 
 // vmListCmd lists VMs.
 func vmListCmd() *cobra.Command {
-	var nodeName string
+	var nodeName, watch string
+	var showIP bool
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List virtual machines",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			interval, err := resolveWatch(watch, args)
+			if err != nil {
+				return err
+			}
 			if err := initClient(cmd); err != nil {
 				return err
 			}
+			if showIP {
+				return listGuestsWithIPs(cmd, interval, "VMs", func(ctx context.Context) (proxmox.ClusterResources, error) {
+					return actions.ListVMs(ctx, proxmoxClient, nodeName)
+				}, vmTable)
+			}
+			if interval > 0 {
+				return runWatch(cmd, interval, func(ctx context.Context) (proxmox.ClusterResources, error) {
+					return actions.ListVMs(ctx, proxmoxClient, nodeName)
+				}, vmTable)
+			}
 			ctx := context.Background()
 			s := startSpinner("Loading VMs...")
 			vms, err := actions.ListVMs(ctx, proxmoxClient, nodeName)
@@ -75,6 +96,8 @@ func vmListCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "filter by node name")
+	addWatchFlag(cmd, &watch)
+	addShowIPFlag(cmd, &showIP)
 	return cmd
 }
 
@@ -94,41 +117,36 @@ func printVMs(cmd *cobra.Command, vms proxmox.ClusterResources) error {
 		return nil
 	}
 
-	// Write to a buffer first so tabwriter aligns columns before we apply color.
-	var buf bytes.Buffer
-	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "VMID\tNAME\tNODE\tSTATUS\tTMPL\tCPU\tMEM\tDISK\tUPTIME")
+	return writeTable(cmd.OutOrStdout(), vmTable(vms), nil)
+}
+
+// vmTable builds the VM list table; templates are drawn in red.
+func vmTable(vms proxmox.ClusterResources) listTable {
+	t := listTable{header: "VMID\tNAME\tNODE\tSTATUS\tTMPL\tCPU\tMEM\tDISK\tUPTIME"}
 	for _, vm := range vms {
-		tmpl := ""
+		tmpl, color := "", ""
 		if vm.Template == 1 {
-			tmpl = "yes"
-		}
-		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-			vm.VMID,
-			vm.Name,
-			vm.Node,
-			vm.Status,
-			tmpl,
-			formatCPUPercent(vm.CPU),
-			formatBytes(vm.Mem),
-			formatBytes(vm.Disk),
-			formatUptime(vm.Uptime),
-		)
-	}
-	w.Flush()
-
-	useColor := stdoutIsTerminal()
-	out := cmd.OutOrStdout()
-	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
-	for i, line := range lines {
-		// Line 0 is the header; data rows start at index 1.
-		if useColor && i > 0 && vms[i-1].Template == 1 {
-			fmt.Fprintf(out, "%s%s%s\n", colorRed, line, colorReset)
-		} else {
-			fmt.Fprintln(out, line)
+			tmpl, color = "yes", colorRed
 		}
+		usage := fmt.Sprintf("%s\t%s\t%s\t%s", vm.Status, formatCPUPercent(vm.CPU), formatBytes(vm.Mem), formatBytes(vm.Disk))
+		t.rows = append(t.rows, listRow{
+			line: fmt.Sprintf("%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s",
+				vm.VMID,
+				vm.Name,
+				vm.Node,
+				vm.Status,
+				tmpl,
+				formatCPUPercent(vm.CPU),
+				formatBytes(vm.Mem),
+				formatBytes(vm.Disk),
+				formatUptime(vm.Uptime),
+			),
+			key:   vm.ID,
+			sig:   usage,
+			color: color,
+		})
 	}
-	return nil
+	return t
 }
 
 // vmStartCmd starts a VM.
@@ -153,6 +171,9 @@ func vmStartCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Starting VM %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -187,6 +208,9 @@ func vmStopCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Stopping VM %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -221,6 +245,9 @@ func vmShutdownCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Shutting down VM %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -255,6 +282,9 @@ func vmRebootCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Rebooting VM %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -336,7 +366,8 @@ func vmConfigCmd() *cobra.Command {
 		Long: `View or modify a virtual machine's configuration.
 
 Without modification flags, displays the current config.
-With flags, updates the specified configuration options.`,
+With flags, updates the specified configuration options. The get, set
+and unset subcommands read and change any other option.`,
 		Args: cobra.ExactArgs(1),
 		Example: `  pxve vm config 100
   pxve vm config 100 --output json
@@ -397,6 +428,9 @@ With flags, updates the specified configuration options.`,
 				if err != nil {
 					return handleErr(err)
 				}
+				if detachTask(cmd, task) {
+					return nil
+				}
 				fmt.Fprintf(cmd.OutOrStdout(), "Updating VM %d config...\n", vmid)
 				if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 					return handleErr(err)
@@ -452,6 +486,8 @@ With flags, updates the specified configuration options.`,
 	cmd.Flags().BoolVar(&noOnboot, "no-onboot", false, "disable start on boot")
 	cmd.Flags().BoolVar(&protection, "protection", false, "enable protection")
 	cmd.Flags().BoolVar(&noProtect, "no-protection", false, "disable protection")
+	cmd.AddCommand(guestConfigGetCmd("qemu"), guestConfigSetCmd("qemu"), guestConfigUnsetCmd("qemu"),
+		guestConfigExportCmd("qemu"), guestConfigImportCmd("qemu"))
 	return cmd
 }
 
@@ -476,6 +512,9 @@ func vmDeleteCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Deleting VM %d...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -515,6 +554,9 @@ func vmCloneCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Cloning VM %d to %q (ID %d)...\n", vmid, name, clonedID)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -564,6 +606,9 @@ This operation is irreversible — the VM becomes read-only and can only be clon
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Converting VM %d to template...\n", vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -583,7 +628,7 @@ func vmDiskCmd() *cobra.Command {
 		Use:   "disk",
 		Short: "Disk operations",
 	}
-	cmd.AddCommand(vmDiskResizeCmd(), vmDiskMoveCmd(), vmDiskDetachCmd())
+	cmd.AddCommand(vmDiskResizeCmd(), vmDiskMoveCmd(), vmDiskDetachCmd(), vmDiskAddCmd(), vmDiskAttachCmd())
 	return cmd
 }
 
@@ -619,6 +664,9 @@ size is a delta with a unit suffix, e.g. 10G, 512M. The '+' prefix is added auto
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Resizing disk %s on VM %d by %s...\n", disk, vmid, size)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -697,6 +745,9 @@ Moving a disk on a running VM is supported (live migration).`,
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Moving disk %s on VM %d to %q...\n", disk, vmid, storage)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -756,6 +807,9 @@ A confirmation prompt is shown when --delete is set, unless --force is also give
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Detaching disk %s from VM %d...\n", disk, vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -870,6 +924,9 @@ func vmTagAddCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
 			}
@@ -903,6 +960,9 @@ func vmTagRemoveCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
 			}
@@ -924,6 +984,7 @@ func vmSnapshotCmd() *cobra.Command {
 	cmd.AddCommand(vmSnapshotCreateCmd())
 	cmd.AddCommand(vmSnapshotRollbackCmd())
 	cmd.AddCommand(vmSnapshotDeleteCmd())
+	cmd.AddCommand(snapshotEditCmd("qemu"))
 	return cmd
 }
 
@@ -955,16 +1016,7 @@ func vmSnapshotListCmd() *cobra.Command {
 				return enc.Encode(snaps)
 			}
 
-			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
-			fmt.Fprintln(w, "NAME\tPARENT\tCREATED\tDESCRIPTION")
-			for _, s := range snaps {
-				created := "-"
-				if s.Snaptime > 0 {
-					created = time.Unix(s.Snaptime, 0).Format("2006-01-02 15:04:05")
-				}
-				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Name, s.Parent, created, s.Description)
-			}
-			return w.Flush()
+			return printSnapshotTree(cmd.OutOrStdout(), snaps)
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
@@ -973,10 +1025,13 @@ func vmSnapshotListCmd() *cobra.Command {
 
 func vmSnapshotCreateCmd() *cobra.Command {
 	var nodeName string
+	var opts actions.SnapshotOptions
 	cmd := &cobra.Command{
 		Use:   "create <vmid> <name>",
 		Short: "Create a snapshot of a VM",
 		Args:  cobra.ExactArgs(2),
+		Example: `  pxve vm snapshot create 100 pre-upgrade --description "before apt full-upgrade"
+  pxve vm snapshot create 100 live --vmstate`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			vmid, err := strconv.Atoi(args[0])
 			if err != nil {
@@ -988,11 +1043,14 @@ func vmSnapshotCreateCmd() *cobra.Command {
 			}
 			ctx := context.Background()
 			s := startSpinner("Connecting...")
-			task, err := actions.CreateVMSnapshot(ctx, proxmoxClient, vmid, nodeName, snapName)
+			task, err := actions.CreateVMSnapshot(ctx, proxmoxClient, vmid, nodeName, snapName, opts)
 			s.Stop()
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Creating snapshot %q for VM %d...\n", snapName, vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -1002,6 +1060,8 @@ func vmSnapshotCreateCmd() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().StringVar(&opts.Description, "description", "", "snapshot description")
+	cmd.Flags().BoolVar(&opts.VMState, "vmstate", false, "include the RAM of the running VM, so a rollback resumes it where it was")
 	return cmd
 }
 
@@ -1027,6 +1087,9 @@ func vmSnapshotDeleteCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Deleting snapshot %q for VM %d...\n", snapName, vmid)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -1061,6 +1124,9 @@ func vmSnapshotRollbackCmd() *cobra.Command {
 			if err != nil {
 				return handleErr(err)
 			}
+			if detachTask(cmd, task) {
+				return nil
+			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Rolling back VM %d to snapshot %q...\n", vmid, snapName)
 			if err := watchTask(ctx, cmd.OutOrStdout(), task); err != nil {
 				return handleErr(err)
@@ -1079,7 +1145,11 @@ func vmAgentCmd() *cobra.Command {
 		Use:   "agent",
 		Short: "Guest agent operations (requires qemu-guest-agent)",
 	}
-	cmd.AddCommand(vmAgentExecCmd(), vmAgentOsInfoCmd(), vmAgentNetworksCmd(), vmAgentSetPasswordCmd())
+	cmd.AddCommand(vmAgentExecCmd(), vmAgentOsInfoCmd(), vmAgentNetworksCmd(), vmAgentSetPasswordCmd(),
+		vmAgentRunCmd(), vmAgentShellCmd(), vmAgentInfoCmd(), vmAgentFileReadCmd(), vmAgentFileWriteCmd(),
+		vmAgentFSFreezeCmd("status", "Show whether the VM filesystems are frozen"),
+		vmAgentFSFreezeCmd("freeze", "Freeze the VM filesystems (e.g. before a storage snapshot)"),
+		vmAgentFSFreezeCmd("thaw", "Thaw the VM filesystems"))
 	return cmd
 }
 
@@ -0,0 +1,725 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// diskBusSlots is the number of disk slots per VM bus.
+var diskBusSlots = map[string]int{"scsi": 31, "virtio": 16, "sata": 6, "ide": 4}
+
+// deviceSlot returns the config key for a new device: prefix<slot>, or the
+// first free one when slot is negative.
+func deviceSlot(cfg *actions.GuestConfig, prefix string, max, slot int) (string, error) {
+	if slot < 0 {
+		return actions.FreeSlot(cfg.Values, prefix, max)
+	}
+	if slot >= max {
+		return "", fmt.Errorf("invalid slot %d (%s supports 0-%d)", slot, prefix, max-1)
+	}
+	key := prefix + strconv.Itoa(slot)
+	if v, used := cfg.Values[key]; used {
+		return "", fmt.Errorf("%s is already in use (%s)", key, v)
+	}
+	return key, nil
+}
+
+// diskSizeGiB converts a size such as 32G, 512M or 1T to the GiB count
+// Proxmox expects when allocating a disk ("local-lvm:32").
+func diskSizeGiB(size string) (string, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "T"):
+		mult, s = 1024, strings.TrimSuffix(s, "T")
+	case strings.HasSuffix(s, "G"):
+		s = strings.TrimSuffix(s, "G")
+	case strings.HasSuffix(s, "M"):
+		mult, s = 1.0/1024, strings.TrimSuffix(s, "M")
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return "", fmt.Errorf("invalid size %q (e.g. 32G, 512M, 1T)", size)
+	}
+	return strconv.FormatFloat(n*mult, 'f', -1, 64), nil
+}
+
+// diskOptionFlags are the drive options shared by disk add and attach.
+type diskOptionFlags struct {
+	bus      string
+	slot     int
+	cache    string
+	discard  bool
+	ssd      bool
+	iothread bool
+	noBackup bool
+}
+
+func addDiskOptionFlags(cmd *cobra.Command, f *diskOptionFlags) {
+	cmd.Flags().StringVar(&f.bus, "bus", "scsi", "bus: scsi, virtio, sata, ide")
+	cmd.Flags().IntVar(&f.slot, "slot", -1, "slot number on the bus (default: first free)")
+	cmd.Flags().StringVar(&f.cache, "cache", "", "cache mode (none, writeback, writethrough, directsync, unsafe)")
+	cmd.Flags().BoolVar(&f.discard, "discard", false, "pass discard/TRIM requests to the storage")
+	cmd.Flags().BoolVar(&f.ssd, "ssd", false, "present the disk as an SSD (not on virtio)")
+	cmd.Flags().BoolVar(&f.iothread, "iothread", false, "use a dedicated I/O thread (virtio, or scsi with virtio-scsi-single)")
+	cmd.Flags().BoolVar(&f.noBackup, "no-backup", false, "exclude the disk from backups")
+}
+
+// drive returns the config key and value for volume on the chosen bus.
+func (f *diskOptionFlags) drive(cfg *actions.GuestConfig, volume string) (string, string, error) {
+	max, ok := diskBusSlots[f.bus]
+	if !ok {
+		return "", "", fmt.Errorf("invalid --bus %q (must be scsi, virtio, sata or ide)", f.bus)
+	}
+	key, err := deviceSlot(cfg, f.bus, max, f.slot)
+	if err != nil {
+		return "", "", err
+	}
+	p := actions.PropertyString{{Value: volume}}
+	if f.cache != "" {
+		p = p.Set("cache", f.cache)
+	}
+	if f.discard {
+		p = p.Set("discard", "on")
+	}
+	if f.ssd {
+		p = p.Set("ssd", "1")
+	}
+	if f.iothread {
+		p = p.Set("iothread", "1")
+	}
+	if f.noBackup {
+		p = p.Set("backup", "0")
+	}
+	return key, p.String(), nil
+}
+
+func vmDiskAddCmd() *cobra.Command {
+	var (
+		o       configEditOpts
+		disk    diskOptionFlags
+		storage string
+		size    string
+		format  string
+	)
+	cmd := &cobra.Command{
+		Use:   "add <vmid> --storage <storage> --size <size>",
+		Short: "Allocate a new disk and attach it to a VM",
+		Long: `Allocate a new disk on a storage and attach it to the VM.
+
+The disk goes into the first free slot of the bus unless --slot is given.
+The resulting config change is shown for confirmation before it is applied.`,
+		Args: cobra.ExactArgs(1),
+		Example: `  pxve vm disk add 100 --storage local-lvm --size 32G
+  pxve vm disk add 100 --storage ceph --size 100G --bus virtio --iothread --discard
+  pxve vm disk add 100 --storage local --size 8G --format qcow2 --slot 5`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if storage == "" || size == "" {
+				return fmt.Errorf("--storage and --size are required")
+			}
+			gib, err := diskSizeGiB(size)
+			if err != nil {
+				return err
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key, value, err := disk.drive(cfg, storage+":"+gib)
+				if err != nil {
+					return nil, nil, err
+				}
+				if format != "" {
+					value += ",format=" + format
+				}
+				return map[string]string{key: value}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&storage, "storage", "", "storage to allocate the disk on (required)")
+	cmd.Flags().StringVar(&size, "size", "", "disk size, e.g. 32G, 512M, 1T (required)")
+	cmd.Flags().StringVar(&format, "format", "", "image format on file storages (raw, qcow2, vmdk)")
+	addDiskOptionFlags(cmd, &disk)
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+func vmDiskAttachCmd() *cobra.Command {
+	var (
+		o    configEditOpts
+		disk diskOptionFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "attach <vmid> [unusedN]",
+		Short: "Attach an unused disk to a VM",
+		Long: `Attach an unused disk (e.g. one detached with "vm disk detach") to a bus.
+
+If unusedN is omitted, the disk is auto-selected when the VM has only one
+unused disk; otherwise a prompt is shown.`,
+		Args: cobra.RangeArgs(1, 2),
+		Example: `  pxve vm disk attach 100                       # auto-select the unused disk
+  pxve vm disk attach 100 unused0 --bus virtio --slot 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				var unused string
+				if len(args) == 2 {
+					unused = args[1]
+					if err := requireConfigKey(cfg, unused, "unused"); err != nil {
+						return nil, nil, err
+					}
+				} else {
+					items := map[string]string{}
+					for k, v := range cfg.Values {
+						if strings.HasPrefix(k, "unused") {
+							items[k] = v
+						}
+					}
+					if len(items) == 0 {
+						return nil, nil, fmt.Errorf("VM %d has no unused disks", cfg.VMID)
+					}
+					var err error
+					if unused, err = selectFromList(cmd, items, "unused disk"); err != nil {
+						return nil, nil, err
+					}
+				}
+				key, value, err := disk.drive(cfg, cfg.Values[unused])
+				if err != nil {
+					return nil, nil, err
+				}
+				// Proxmox drops the unusedN entry itself once the volume
+				// is attached.
+				return map[string]string{key: value}, nil, nil
+			})
+		},
+	}
+	addDiskOptionFlags(cmd, &disk)
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// vmNICCmd groups network device sub-commands.
+func vmNICCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "nic",
+		Short: "Add, change, or remove VM network devices",
+	}
+	cmd.AddCommand(vmNICAddCmd(), vmNICSetCmd(), guestDeviceRemoveCmd("qemu", "net", "network device"))
+	return cmd
+}
+
+// nicFlags are the network device settings of nic add and set.
+type nicFlags struct {
+	model    string
+	mac      string
+	bridge   string
+	tag      int
+	firewall bool
+	rate     string
+	mtu      int
+	queues   int
+	linkDown bool
+}
+
+func addNICFlags(cmd *cobra.Command, f *nicFlags) {
+	cmd.Flags().StringVar(&f.model, "model", "virtio", "card model: "+strings.Join(actions.NICModels[:5], ", ")+", ...")
+	cmd.Flags().StringVar(&f.mac, "mac", "", "MAC address (default: generated)")
+	cmd.Flags().StringVar(&f.bridge, "bridge", "", "bridge or SDN vnet to attach to")
+	cmd.Flags().IntVar(&f.tag, "tag", 0, "VLAN tag (0 removes it)")
+	cmd.Flags().BoolVar(&f.firewall, "firewall", false, "enable the Proxmox firewall on this device")
+	cmd.Flags().StringVar(&f.rate, "rate", "", "rate limit in MB/s (0 removes it)")
+	cmd.Flags().IntVar(&f.mtu, "mtu", 0, "MTU (virtio only; 1 = use the bridge MTU, 0 removes it)")
+	cmd.Flags().IntVar(&f.queues, "queues", 0, "multiqueue count (virtio only; 0 removes it)")
+	cmd.Flags().BoolVar(&f.linkDown, "link-down", false, "disconnect the device")
+}
+
+// apply sets the flags that were given on a network device property string.
+func (f *nicFlags) apply(cmd *cobra.Command, p actions.PropertyString) (actions.PropertyString, error) {
+	changed := cmd.Flags().Changed
+	isModel := func(s string) bool {
+		for _, m := range actions.NICModels {
+			if m == s {
+				return true
+			}
+		}
+		return false
+	}
+	if changed("model") && !isModel(f.model) {
+		return nil, fmt.Errorf("invalid --model %q (must be one of %s)", f.model, strings.Join(actions.NICModels, ", "))
+	}
+	// The model is either "model=MAC" (how Proxmox writes it), a bare
+	// "model", or "model=<model>" with a separate macaddr.
+	for i, part := range p {
+		switch {
+		case isModel(part.Key):
+			if changed("model") {
+				p[i].Key = f.model
+			}
+			if changed("mac") {
+				p[i].Value = f.mac
+			}
+		case part.Key == "" && isModel(part.Value), part.Key == "model":
+			if changed("model") {
+				p[i].Value = f.model
+			}
+			if changed("mac") {
+				p = p.Set("macaddr", f.mac)
+			}
+		default:
+			continue
+		}
+		break
+	}
+	if changed("bridge") {
+		p = p.Set("bridge", f.bridge)
+	}
+	setOrDel := func(flag, key, value string, del bool) {
+		if !changed(flag) {
+			return
+		}
+		if del {
+			p = p.Del(key)
+		} else {
+			p = p.Set(key, value)
+		}
+	}
+	setOrDel("tag", "tag", strconv.Itoa(f.tag), f.tag == 0)
+	setOrDel("firewall", "firewall", "1", !f.firewall)
+	setOrDel("rate", "rate", f.rate, f.rate == "" || f.rate == "0")
+	setOrDel("mtu", "mtu", strconv.Itoa(f.mtu), f.mtu == 0)
+	setOrDel("queues", "queues", strconv.Itoa(f.queues), f.queues == 0)
+	setOrDel("link-down", "link_down", "1", !f.linkDown)
+	return p, nil
+}
+
+func vmNICAddCmd() *cobra.Command {
+	var (
+		o    configEditOpts
+		nic  nicFlags
+		slot int
+	)
+	cmd := &cobra.Command{
+		Use:   "add <vmid> --bridge <bridge>",
+		Short: "Add a network device to a VM",
+		Args:  cobra.ExactArgs(1),
+		Example: `  pxve vm nic add 100 --bridge vmbr0 --firewall
+  pxve vm nic add 100 --bridge vmbr1 --tag 20 --model e1000 --rate 50
+  pxve vm nic add 100 --bridge web                 # SDN vnet`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if nic.bridge == "" {
+				return fmt.Errorf("--bridge is required")
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key, err := deviceSlot(cfg, "net", 32, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				p := actions.PropertyString{{Value: nic.model}}
+				if nic.mac != "" {
+					p = actions.PropertyString{{Key: nic.model, Value: nic.mac}}
+				}
+				if p, err = nic.apply(cmd, p); err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	addNICFlags(cmd, &nic)
+	cmd.Flags().IntVar(&slot, "slot", -1, "device number, netN (default: first free)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+func vmNICSetCmd() *cobra.Command {
+	var (
+		o   configEditOpts
+		nic nicFlags
+	)
+	cmd := &cobra.Command{
+		Use:   "set <vmid> <netN>",
+		Short: "Change a VM network device",
+		Long: `Change settings of a network device. Settings not given are kept,
+including the MAC address when only the model changes.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  pxve vm nic set 100 net0 --bridge vmbr1 --tag 30
+  pxve vm nic set 100 net0 --tag 0 --rate 0          # remove tag and rate limit
+  pxve vm nic set 100 net1 --link-down`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if countChanged(cmd, "model", "mac", "bridge", "tag", "firewall", "rate", "mtu", "queues", "link-down") == 0 {
+				return fmt.Errorf("nothing to change")
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key := args[1]
+				if err := requireConfigKey(cfg, key, "net"); err != nil {
+					return nil, nil, err
+				}
+				p, err := nic.apply(cmd, actions.ParsePropertyString(cfg.Values[key]))
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	addNICFlags(cmd, &nic)
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// countChanged returns how many of the named flags were given.
+func countChanged(cmd *cobra.Command, names ...string) int {
+	n := 0
+	for _, name := range names {
+		if cmd.Flags().Changed(name) {
+			n++
+		}
+	}
+	return n
+}
+
+// guestDeviceRemoveCmd returns a "remove <id> <prefixN>" command for guests
+// of type typ ("qemu" or "lxc").
+func guestDeviceRemoveCmd(typ, prefix, what string) *cobra.Command {
+	var o configEditOpts
+	id, noun := guestIDArg(typ)
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("remove %s <%sN>", id, prefix),
+		Short: "Remove a " + what + " from a " + noun,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return editGuestConfig(cmd, typ, args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				if err := requireConfigKey(cfg, args[1], prefix); err != nil {
+					return nil, nil, err
+				}
+				return nil, []string{args[1]}, nil
+			})
+		},
+	}
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// vmNodeName returns the node of VM idArg, or nodeName if given.
+func vmNodeName(ctx context.Context, idArg, nodeName string) (string, error) {
+	vmid, err := strconv.Atoi(idArg)
+	if err != nil {
+		return "", fmt.Errorf("invalid VMID %q", idArg)
+	}
+	if nodeName != "" {
+		return nodeName, nil
+	}
+	vm, err := actions.FindVM(ctx, proxmoxClient, vmid, "")
+	if err != nil {
+		return "", handleErr(err)
+	}
+	return vm.Node, nil
+}
+
+// vmHostPCICmd groups PCI passthrough sub-commands.
+func vmHostPCICmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hostpci",
+		Short: "Pass host PCI devices through to a VM",
+	}
+	cmd.AddCommand(vmHostPCIDevicesCmd(), vmHostPCIAddCmd(), guestDeviceRemoveCmd("qemu", "hostpci", "PCI passthrough device"))
+	return cmd
+}
+
+func vmHostPCIDevicesCmd() *cobra.Command {
+	var (
+		nodeName string
+		all      bool
+	)
+	cmd := &cobra.Command{
+		Use:   "devices <vmid>",
+		Short: "List the PCI devices of the VM's node",
+		Long: `List the PCI devices of the node the VM runs on, with their IOMMU group.
+PCI bridges are hidden unless --all is given. Devices in the same IOMMU
+group can only be passed through together.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading PCI devices...")
+			node, err := vmNodeName(ctx, args[0], nodeName)
+			if err != nil {
+				s.Stop()
+				return err
+			}
+			devices, err := actions.NodePCIDevices(ctx, proxmoxClient, node)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+			var shown []actions.PCIDevice
+			for _, d := range devices {
+				if all || !strings.HasPrefix(d.Class, "0x06") {
+					shown = append(shown, d)
+				}
+			}
+
+			if flagOutput == "json" {
+				if shown == nil {
+					shown = []actions.PCIDevice{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(shown)
+			}
+
+			if len(shown) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo PCI devices found on %s.%s\n", colorGold, node, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tIOMMU\tVENDOR\tDEVICE\tMDEV")
+			for _, d := range shown {
+				group := strconv.Itoa(d.IOMMUGroup)
+				if d.IOMMUGroup < 0 {
+					group = "-"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.ID, group, dash(d.Vendor), dash(d.Device), yesNoBool(d.MDev))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	cmd.Flags().BoolVar(&all, "all", false, "include PCI bridges")
+	return cmd
+}
+
+func vmHostPCIAddCmd() *cobra.Command {
+	var (
+		o            configEditOpts
+		slot         int
+		mapping      string
+		pcie         bool
+		xvga         bool
+		rombar       bool
+		mdev         string
+		allFunctions bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add <vmid> [device]",
+		Short: "Pass a host PCI device through to a VM",
+		Long: `Pass a host PCI device (e.g. 0000:01:00.0, see "hostpci devices") or a
+cluster resource mapping (--mapping) through to the VM. The change takes
+effect at the next VM start.`,
+		Args: cobra.RangeArgs(1, 2),
+		Example: `  pxve vm hostpci devices 100
+  pxve vm hostpci add 100 0000:01:00.0 --pcie --all-functions --x-vga
+  pxve vm hostpci add 100 --mapping gpu0 --pcie`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 2) == (mapping != "") {
+				return fmt.Errorf("give either a device or --mapping")
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				if pcie && !strings.Contains(cfg.Values["machine"], "q35") {
+					return nil, nil, fmt.Errorf("--pcie requires the q35 machine type (VM %d uses %s)", cfg.VMID, dash(cfg.Values["machine"]))
+				}
+				key, err := deviceSlot(cfg, "hostpci", 16, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				var p actions.PropertyString
+				if mapping != "" {
+					p = p.Set("mapping", mapping)
+				} else {
+					dev := args[1]
+					if allFunctions {
+						if i := strings.LastIndex(dev, "."); i > strings.LastIndex(dev, ":") {
+							dev = dev[:i]
+						}
+					}
+					p = actions.PropertyString{{Value: dev}}
+				}
+				if pcie {
+					p = p.Set("pcie", "1")
+				}
+				if xvga {
+					p = p.Set("x-vga", "1")
+				}
+				if cmd.Flags().Changed("rombar") && !rombar {
+					p = p.Set("rombar", "0")
+				}
+				if mdev != "" {
+					p = p.Set("mdev", mdev)
+				}
+				return map[string]string{key: p.String()}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&slot, "slot", -1, "device number, hostpciN (default: first free)")
+	cmd.Flags().StringVar(&mapping, "mapping", "", "cluster PCI resource mapping instead of a device ID")
+	cmd.Flags().BoolVar(&pcie, "pcie", false, "attach as PCI Express (q35 machines only)")
+	cmd.Flags().BoolVar(&xvga, "x-vga", false, "make it the primary GPU")
+	cmd.Flags().BoolVar(&rombar, "rombar", true, "expose the device ROM (--rombar=false to hide it)")
+	cmd.Flags().StringVar(&mdev, "mdev", "", "mediated device type (vGPU)")
+	cmd.Flags().BoolVar(&allFunctions, "all-functions", false, "pass all functions of the device (e.g. GPU and its audio)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// vmUSBCmd groups USB passthrough sub-commands.
+func vmUSBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "usb",
+		Short: "Pass host USB devices through to a VM",
+	}
+	cmd.AddCommand(vmUSBDevicesCmd(), vmUSBAddCmd(), guestDeviceRemoveCmd("qemu", "usb", "USB device"))
+	return cmd
+}
+
+func vmUSBDevicesCmd() *cobra.Command {
+	var nodeName string
+	cmd := &cobra.Command{
+		Use:   "devices <vmid>",
+		Short: "List the USB devices plugged into the VM's node",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := initClient(cmd); err != nil {
+				return err
+			}
+			ctx := context.Background()
+			s := startSpinner("Loading USB devices...")
+			node, err := vmNodeName(ctx, args[0], nodeName)
+			if err != nil {
+				s.Stop()
+				return err
+			}
+			devices, err := actions.NodeUSBDevices(ctx, proxmoxClient, node)
+			s.Stop()
+			if err != nil {
+				return handleErr(err)
+			}
+
+			if flagOutput == "json" {
+				if devices == nil {
+					devices = []actions.USBDevice{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(devices)
+			}
+
+			if len(devices) == 0 {
+				if stdoutIsTerminal() {
+					fmt.Fprintf(cmd.OutOrStdout(), "%sNo USB devices found on %s.%s\n", colorGold, node, colorReset)
+				}
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tPORT\tVENDOR\tPRODUCT\tSPEED")
+			for _, d := range devices {
+				fmt.Fprintf(w, "%s\t%d-%s\t%s\t%s\t%s\n", d.HostID(), d.BusNum, d.Port, dash(d.Vendor), dash(d.Product), dash(d.Speed))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&nodeName, "node", "", "node name")
+	return cmd
+}
+
+// usbPortRe matches a USB port path such as 1-1.2.
+var usbPortRe = regexp.MustCompile(`^\d+-[\d.]+$`)
+
+func vmUSBAddCmd() *cobra.Command {
+	var (
+		o    configEditOpts
+		slot int
+		usb3 bool
+	)
+	cmd := &cobra.Command{
+		Use:   "add <vmid> <vendor:product|bus-port|spice>",
+		Short: "Pass a host USB device or port through to a VM",
+		Long: `Pass a USB device through to the VM by vendor:product ID (follows the
+device between ports) or by bus-port (whatever is plugged into that port),
+or add a SPICE USB redirection channel.`,
+		Args: cobra.ExactArgs(2),
+		Example: `  pxve vm usb devices 100
+  pxve vm usb add 100 0951:1666 --usb3
+  pxve vm usb add 100 1-1.2
+  pxve vm usb add 100 spice`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dev := args[1]
+			var value string
+			switch {
+			case dev == "spice":
+				value = "spice"
+			case strings.Count(dev, ":") == 1, usbPortRe.MatchString(dev):
+				value = "host=" + dev
+			default:
+				return fmt.Errorf("invalid USB device %q (expected vendor:product, bus-port or spice)", dev)
+			}
+			if usb3 {
+				value += ",usb3=1"
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key, err := deviceSlot(cfg, "usb", 14, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: value}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&slot, "slot", -1, "device number, usbN (default: first free)")
+	cmd.Flags().BoolVar(&usb3, "usb3", false, "attach to a USB 3 controller")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
+
+// vmSerialCmd groups serial port sub-commands.
+func vmSerialCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serial",
+		Short: "Add or remove VM serial ports",
+	}
+	cmd.AddCommand(vmSerialAddCmd(), guestDeviceRemoveCmd("qemu", "serial", "serial port"))
+	return cmd
+}
+
+func vmSerialAddCmd() *cobra.Command {
+	var (
+		o    configEditOpts
+		slot int
+	)
+	cmd := &cobra.Command{
+		Use:   "add <vmid> [socket|/dev/<device>]",
+		Short: "Add a serial port to a VM",
+		Long: `Add a serial port backed by a socket (the default; reachable with a
+serial terminal console) or by a host device such as /dev/ttyS0.`,
+		Args: cobra.RangeArgs(1, 2),
+		Example: `  pxve vm serial add 100
+  pxve vm serial add 100 /dev/ttyUSB0 --slot 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value := "socket"
+			if len(args) == 2 {
+				value = args[1]
+			}
+			if value != "socket" && !strings.HasPrefix(value, "/dev/") {
+				return fmt.Errorf("invalid serial backend %q (expected socket or /dev/<device>)", value)
+			}
+			return editGuestConfig(cmd, "qemu", args[0], o, func(cfg *actions.GuestConfig) (map[string]string, []string, error) {
+				key, err := deviceSlot(cfg, "serial", 4, slot)
+				if err != nil {
+					return nil, nil, err
+				}
+				return map[string]string{key: value}, nil, nil
+			})
+		},
+	}
+	cmd.Flags().IntVar(&slot, "slot", -1, "port number, serialN (default: first free)")
+	addConfigEditFlags(cmd, &o)
+	return cmd
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultWatchInterval = "2s"
+
+// listTable is a list command's table before alignment: a tab-separated
+// header and one entry per data row.
+type listTable struct {
+	header string
+	rows   []listRow
+}
+
+// listRow is one tab-separated table row. key identifies the row across
+// refreshes and sig holds the fields whose change --watch highlights.
+type listRow struct {
+	line  string
+	key   string
+	sig   string
+	color string // base colour, e.g. colorRed for templates; "" for none
+}
+
+// writeTable aligns t with tabwriter and writes it to out. Rows in highlight
+// are drawn in colorChanged, others in their base colour; colours are only
+// used when stdout is a terminal.
+func writeTable(out io.Writer, t listTable, highlight map[int]bool) error {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, t.header)
+	for _, r := range t.rows {
+		fmt.Fprintln(w, r.line)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	useColor := stdoutIsTerminal()
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	for i, line := range lines {
+		// Line 0 is the header; data rows start at index 1.
+		color := ""
+		if useColor && i > 0 {
+			color = t.rows[i-1].color
+			if highlight[i-1] {
+				color = colorChanged
+			}
+		}
+		if color != "" {
+			fmt.Fprintf(out, "%s%s%s\n", color, line, colorReset)
+		} else {
+			fmt.Fprintln(out, line)
+		}
+	}
+	return nil
+}
+
+// addWatchFlag registers --watch [interval] on a list command.
+func addWatchFlag(cmd *cobra.Command, watch *string) {
+	cmd.Flags().StringVar(watch, "watch", "", "refresh every interval until Ctrl-C (e.g. --watch, --watch 5s; default "+defaultWatchInterval+")")
+	cmd.Flags().Lookup("watch").NoOptDefVal = defaultWatchInterval
+}
+
+// resolveWatch returns the --watch interval, or 0 when watching is off.
+// Optional flag values must be written --watch=5s, so a lone positional
+// argument following a bare --watch is accepted as the interval too.
+func resolveWatch(watch string, args []string) (time.Duration, error) {
+	if watch == "" {
+		if len(args) > 0 {
+			return 0, fmt.Errorf("unexpected argument %q", args[0])
+		}
+		return 0, nil
+	}
+	if len(args) > 1 || (len(args) == 1 && watch != defaultWatchInterval) {
+		return 0, fmt.Errorf("unexpected argument %q", args[len(args)-1])
+	}
+	if len(args) == 1 {
+		watch = args[0]
+	}
+	return parseWatchInterval(watch)
+}
+
+// parseWatchInterval accepts a Go duration ("5s", "1m") or plain seconds ("5").
+func parseWatchInterval(s string) (time.Duration, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		s = fmt.Sprintf("%ds", n)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid watch interval %q", s)
+	}
+	return d, nil
+}
+
+// runWatch refreshes a list every interval until Ctrl-C. Table output is
+// redrawn in place with rows that changed since the previous refresh
+// highlighted; JSON output emits one compact document per refresh (NDJSON).
+// Fetch errors are reported and retried on the next refresh.
+func runWatch[T any](cmd *cobra.Command, interval time.Duration, fetch func(ctx context.Context) (T, error), table func(T) listTable) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := cmd.OutOrStdout()
+	tty := stdoutIsTerminal()
+	if tty && flagOutput != "json" {
+		fmt.Fprint(out, "\033[?25l")       // hide cursor while redrawing
+		defer fmt.Fprint(out, "\033[?25h") // and restore it on exit
+	}
+
+	var prev map[string]string
+	for frame := 0; ; frame++ {
+		data, err := fetch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if flagOutput == "json" {
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", handleErr(err))
+			} else if err := json.NewEncoder(out).Encode(data); err != nil {
+				return err
+			}
+		} else {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "Every %s: %s    %s\n\n", interval, cmd.CommandPath(), time.Now().Format("2006-01-02 15:04:05"))
+			if err != nil {
+				fmt.Fprintf(&buf, "Error: %v\n", handleErr(err))
+			} else {
+				t := table(data)
+				changed := make(map[int]bool)
+				cur := make(map[string]string, len(t.rows))
+				for i, r := range t.rows {
+					cur[r.key] = r.sig
+					if old, ok := prev[r.key]; prev != nil && (!ok || old != r.sig) {
+						changed[i] = true
+					}
+				}
+				prev = cur
+				if err := writeTable(&buf, t, changed); err != nil {
+					return err
+				}
+			}
+
+			switch {
+			case tty:
+				// Home the cursor and overwrite in place, clearing leftovers from
+				// longer previous lines and frames, to avoid flicker.
+				body := strings.ReplaceAll(buf.String(), "\n", "\033[K\n")
+				fmt.Fprint(out, "\033[H"+body+"\033[J")
+			case frame > 0:
+				fmt.Fprint(out, "\n"+buf.String())
+			default:
+				fmt.Fprint(out, buf.String())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
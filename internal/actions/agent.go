@@ -0,0 +1,355 @@
+package actions
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// AgentChunkSize is the largest piece of a file sent to the guest in one
+// request: its base64 encoding fills the 60 KiB the API accepts for
+// file-write content and exec input.
+const AgentChunkSize = 45 * 1024
+
+// agentReadChunkSize is how much of a file beyond the first file-read is
+// fetched per exec call, well below the agent's 16 MiB output cap.
+const agentReadChunkSize = 4 << 20
+
+// agentExecTimeout bounds each helper command run for chunked transfers.
+const agentExecTimeout = 60
+
+// AgentCommand is a guest agent command and whether it is enabled.
+type AgentCommand struct {
+	Name            string `json:"name"`
+	Enabled         bool   `json:"enabled"`
+	SuccessResponse bool   `json:"success-response"`
+}
+
+// AgentInfo is the guest agent's version and its supported commands.
+type AgentInfo struct {
+	Version  string         `json:"version"`
+	Commands []AgentCommand `json:"supported_commands"`
+}
+
+// VMAgentInfo returns the guest agent version and supported commands.
+func VMAgentInfo(ctx context.Context, c *proxmox.Client, vmid int, nodeName string) (*AgentInfo, error) {
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	var info AgentInfo
+	if err := agentResult(ctx, c, vm, "info", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// VMAgentFSFreeze runs fsfreeze-status, fsfreeze-freeze, or fsfreeze-thaw
+// (action "status", "freeze", or "thaw") and returns the agent's result: the
+// freeze state for status, the number of filesystems affected otherwise.
+func VMAgentFSFreeze(ctx context.Context, c *proxmox.Client, vmid int, nodeName, action string) (string, error) {
+	switch action {
+	case "status", "freeze", "thaw":
+	default:
+		return "", fmt.Errorf("invalid fsfreeze action %q", action)
+	}
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Result interface{} `json:"result"`
+	}
+	if err := c.Post(ctx, agentPath(vm, "fsfreeze-"+action), nil, &resp); err != nil {
+		return "", err
+	}
+	return configString(resp.Result), nil
+}
+
+// VMAgentFileRead copies the guest file path to w and returns the number of
+// bytes copied. The first 16 MiB come from the agent's file-read; the rest,
+// if any, is read in chunks with dd and base64 run through the agent, which
+// needs a POSIX shell in the guest.
+func VMAgentFileRead(ctx context.Context, c *proxmox.Client, vmid int, nodeName, path string, w io.Writer) (int64, error) {
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return 0, err
+	}
+	var resp struct {
+		Content   string            `json:"content"`
+		Truncated proxmox.IntOrBool `json:"truncated"`
+	}
+	if err := c.Get(ctx, agentPath(vm, "file-read")+"?file="+url.QueryEscape(path), &resp); err != nil {
+		return 0, err
+	}
+	data := agentFileBytes(resp.Content)
+	if _, err := w.Write(data); err != nil {
+		return 0, err
+	}
+	total := int64(len(data))
+	if !bool(resp.Truncated) {
+		return total, nil
+	}
+
+	// file-read stops at 16 MiB, a whole number of chunks, so the rest can
+	// be read in blocks of the chunk size.
+	script := `dd if="$1" bs=` + strconv.Itoa(agentReadChunkSize) + ` skip="$2" count=1 2>/dev/null | base64`
+	for {
+		if total%agentReadChunkSize != 0 {
+			return total, fmt.Errorf("reading %s: unexpected file-read size %d", path, total)
+		}
+		st, err := agentSh(ctx, c, vm, []string{script, path, strconv.FormatInt(total/agentReadChunkSize, 10)}, "")
+		if err != nil {
+			return total, fmt.Errorf("reading %s at offset %d: %w", path, total, err)
+		}
+		chunk, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(st.OutData), ""))
+		if err != nil {
+			return total, fmt.Errorf("reading %s at offset %d: %w", path, total, err)
+		}
+		if len(chunk) == 0 {
+			return total, nil
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return total, err
+		}
+		total += int64(len(chunk))
+		if len(chunk) < agentReadChunkSize {
+			return total, nil
+		}
+	}
+}
+
+// VMAgentFileWrite writes data to the guest file path, replacing it. Data
+// larger than AgentChunkSize is sent in base64 chunks: the first with the
+// agent's file-write, the others appended by base64 run through the agent,
+// which needs a POSIX shell in the guest. progress, if not nil, is called
+// after each chunk with the bytes written so far.
+func VMAgentFileWrite(ctx context.Context, c *proxmox.Client, vmid int, nodeName, path string, data []byte, progress func(done int)) error {
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return err
+	}
+	for off := 0; ; {
+		end := min(off+AgentChunkSize, len(data))
+		b64 := base64.StdEncoding.EncodeToString(data[off:end])
+		if off == 0 {
+			body := map[string]interface{}{"file": path, "content": b64, "encode": 0}
+			if err := c.Post(ctx, agentPath(vm, "file-write"), body, nil); err != nil {
+				return err
+			}
+		} else if _, err := agentSh(ctx, c, vm, []string{`base64 -d >> "$1"`, path}, b64); err != nil {
+			return fmt.Errorf("appending to %s at offset %d: %w", path, off, err)
+		}
+		off = end
+		if progress != nil {
+			progress(off)
+		}
+		if off >= len(data) {
+			return nil
+		}
+	}
+}
+
+// AgentRunResult is the outcome of a command run on one VM by VMAgentRun.
+// Error is set when the command could not be run or did not finish in time.
+type AgentRunResult struct {
+	VMID     uint64        `json:"vmid"`
+	Name     string        `json:"name"`
+	Node     string        `json:"node"`
+	ExitCode int           `json:"exitcode"`
+	Stdout   string        `json:"stdout,omitempty"`
+	Stderr   string        `json:"stderr,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"-"`
+}
+
+// Failed reports whether the command failed to run or exited non-zero.
+func (r *AgentRunResult) Failed() bool {
+	return r.Error != "" || r.ExitCode != 0
+}
+
+// VMAgentRun runs command through the guest agent on each VM in vms, at most
+// parallel at a time, waiting up to timeoutSecs for each. done, if not nil,
+// is called with each result as it finishes, never concurrently. The results
+// are returned in the order of vms.
+func VMAgentRun(ctx context.Context, c *proxmox.Client, vms proxmox.ClusterResources, command []string, inputData string, timeoutSecs, parallel int, done func(*AgentRunResult)) []*AgentRunResult {
+	sem := make(chan struct{}, max(parallel, 1))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]*AgentRunResult, len(vms))
+	for i, r := range vms {
+		wg.Add(1)
+		go func(i int, r *proxmox.ClusterResource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res := &AgentRunResult{VMID: r.VMID, Name: r.Name, Node: r.Node}
+			start := time.Now()
+			st, err := VMAgentExec(ctx, c, int(r.VMID), r.Node, command, inputData, timeoutSecs)
+			res.Duration = time.Since(start)
+			if errors.Is(err, proxmox.ErrTimeout) {
+				res.Error = fmt.Sprintf("timed out after %ds", timeoutSecs)
+			} else if err != nil {
+				res.Error = err.Error()
+			} else {
+				res.ExitCode, res.Stdout, res.Stderr = st.ExitCode, st.OutData, st.ErrData
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = res
+			if done != nil {
+				done(res)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	return results
+}
+
+// AgentShell runs shell command lines in a VM through the guest agent. Each
+// line is a separate sh process, so only the working directory carries over
+// from one line to the next, not variables or other shell state.
+type AgentShell struct {
+	VMID    int
+	Name    string // VM name
+	Dir     string // working directory in the guest
+	Timeout int    // seconds to wait for each line
+
+	c  *proxmox.Client
+	vm *proxmox.VirtualMachine
+}
+
+// agentShellDirMark separates a line's output from the working directory the
+// wrapper script prints after it.
+const agentShellDirMark = "\x1e"
+
+// agentShellScript runs $2 in directory $1 with eval and reports the
+// directory it ends in, so "cd" works across lines. A line that exits the
+// shell leaves the directory unchanged.
+const agentShellScript = `cd "$1" || exit 125; eval "$2"; __pxve_rc=$?; printf '\036%s' "$(pwd)"; exit $__pxve_rc`
+
+// NewAgentShell returns a shell on the VM, starting in the guest agent's
+// working directory (usually /).
+func NewAgentShell(ctx context.Context, c *proxmox.Client, vmid int, nodeName string, timeoutSecs int) (*AgentShell, error) {
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	sh := &AgentShell{VMID: vmid, Name: vm.Name, Timeout: timeoutSecs, c: c, vm: vm}
+	st, err := agentSh(ctx, c, vm, []string{"pwd"}, "")
+	if err != nil {
+		return nil, err
+	}
+	sh.Dir = strings.TrimSpace(st.OutData)
+	if sh.Dir == "" {
+		sh.Dir = "/"
+	}
+	return sh, nil
+}
+
+// Run runs line with sh in the current directory and returns its status,
+// with the directory it ended in taken out of the output and kept for the
+// next line. If ctx ends first, the command keeps running in the guest.
+func (s *AgentShell) Run(ctx context.Context, line string) (*proxmox.AgentExecStatus, error) {
+	pid, err := agentExec(ctx, s.c, s.vm, []string{"sh", "-c", agentShellScript, "sh", s.Dir, line}, "")
+	if err != nil {
+		return nil, err
+	}
+	st, err := s.vm.WaitForAgentExecExit(ctx, pid, s.Timeout)
+	if errors.Is(err, proxmox.ErrTimeout) {
+		return nil, fmt.Errorf("timed out after %ds; the command is still running in the guest (pid %d)", s.Timeout, pid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if i := strings.LastIndex(st.OutData, agentShellDirMark); i >= 0 {
+		if dir := st.OutData[i+1:]; dir != "" {
+			s.Dir = dir
+		}
+		st.OutData = st.OutData[:i]
+	}
+	return st, nil
+}
+
+// Path resolves a guest path relative to the current directory.
+func (s *AgentShell) Path(p string) string {
+	if strings.HasPrefix(p, "/") {
+		return path.Clean(p)
+	}
+	return path.Join(s.Dir, p)
+}
+
+// Get copies the guest file p to w; see VMAgentFileRead.
+func (s *AgentShell) Get(ctx context.Context, p string, w io.Writer) (int64, error) {
+	return VMAgentFileRead(ctx, s.c, s.VMID, s.vm.Node, s.Path(p), w)
+}
+
+// Put writes data to the guest file p; see VMAgentFileWrite.
+func (s *AgentShell) Put(ctx context.Context, p string, data []byte, progress func(done int)) error {
+	return VMAgentFileWrite(ctx, s.c, s.VMID, s.vm.Node, s.Path(p), data, progress)
+}
+
+// agentExec starts command in the guest and returns its PID. Unlike
+// VirtualMachine.AgentExec, it passes on the API error (e.g. that the agent
+// is not running) rather than reporting a missing PID.
+func agentExec(ctx context.Context, c *proxmox.Client, vm *proxmox.VirtualMachine, command []string, input string) (int, error) {
+	body := map[string]interface{}{"command": command}
+	if input != "" {
+		body["input-data"] = input
+	}
+	var resp struct {
+		PID int `json:"pid"`
+	}
+	if err := c.Post(ctx, agentPath(vm, "exec"), body, &resp); err != nil {
+		return 0, err
+	}
+	return resp.PID, nil
+}
+
+func agentPath(vm *proxmox.VirtualMachine, command string) string {
+	return fmt.Sprintf("/nodes/%s/qemu/%d/agent/%s", url.PathEscape(vm.Node), vm.VMID, command)
+}
+
+// agentSh runs script with sh -c in the guest, args becoming $1..., and
+// fails unless it exits 0.
+func agentSh(ctx context.Context, c *proxmox.Client, vm *proxmox.VirtualMachine, args []string, input string) (*proxmox.AgentExecStatus, error) {
+	command := append([]string{"sh", "-c", args[0], "sh"}, args[1:]...)
+	pid, err := agentExec(ctx, c, vm, command, input)
+	if err != nil {
+		return nil, err
+	}
+	st, err := vm.WaitForAgentExecExit(ctx, pid, agentExecTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if st.ExitCode != 0 {
+		return nil, fmt.Errorf("guest command exited with code %d: %s", st.ExitCode, st.ErrData)
+	}
+	return st, nil
+}
+
+// agentFileBytes recovers the file bytes from file-read content. Proxmox
+// decodes the agent's base64 and sends each byte as one character, so a
+// string of characters below U+0100 maps back byte for byte; anything else
+// is taken as already-decoded text.
+func agentFileBytes(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff || r == utf8.RuneError {
+			return []byte(s)
+		}
+		out = append(out, byte(r))
+	}
+	return out
+}
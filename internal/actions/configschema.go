@@ -0,0 +1,315 @@
+package actions
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OptionKind is the value type of a configuration option.
+type OptionKind int
+
+// Option kinds.
+const (
+	OptString OptionKind = iota
+	OptInt
+	OptNumber
+	OptBool
+	OptEnum
+	OptProps // property string, e.g. "virtio,bridge=vmbr0"
+)
+
+// ConfigOption describes one VM or container configuration option as
+// documented for qm.conf and pct.conf.
+type ConfigOption struct {
+	Key   string // "cores", or "net" for the numbered keys net0..net<Slots-1>
+	Slots int    // number of numbered keys, 0 for plain keys
+	Kind  OptionKind
+	Enum  []string // OptEnum values
+	Min   float64  // OptInt/OptNumber bounds; Max 0 means unbounded
+	Max   float64
+	Props []string // OptProps keys; parts without "=" are always accepted
+	Help  string
+}
+
+// Name returns the key as shown in help, e.g. "net[n]".
+func (o ConfigOption) Name() string {
+	if o.Slots > 0 {
+		return o.Key + "[n]"
+	}
+	return o.Key
+}
+
+// Keys returns the concrete config keys of the option (net0, net1, ...).
+func (o ConfigOption) Keys() []string {
+	if o.Slots == 0 {
+		return []string{o.Key}
+	}
+	keys := make([]string, o.Slots)
+	for i := range keys {
+		keys[i] = o.Key + strconv.Itoa(i)
+	}
+	return keys
+}
+
+// Property-string keys shared by several options.
+var (
+	qemuDiskProps = []string{"aio", "backup", "bps", "bps_max_length", "bps_rd", "bps_rd_max_length", "bps_wr", "bps_wr_max_length",
+		"cache", "cyls", "detect_zeroes", "discard", "file", "format", "heads", "import-from", "iops", "iops_max", "iops_max_length",
+		"iops_rd", "iops_rd_max", "iops_rd_max_length", "iops_wr", "iops_wr_max", "iops_wr_max_length", "iothread", "mbps", "mbps_max",
+		"mbps_rd", "mbps_rd_max", "mbps_wr", "mbps_wr_max", "media", "model", "product", "queues", "replicate", "rerror", "ro",
+		"scsiblock", "secs", "serial", "shared", "size", "snapshot", "ssd", "trans", "vendor", "werror", "wwn"}
+	qemuNetProps  = append([]string{"bridge", "firewall", "link_down", "macaddr", "model", "mtu", "queues", "rate", "tag", "trunks"}, NICModels...)
+	lxcMountProps = []string{"acl", "backup", "mountoptions", "mp", "quota", "replicate", "ro", "shared", "size", "volume"}
+)
+
+// NICModels are the QEMU network card models.
+var NICModels = []string{"virtio", "e1000", "e1000e", "rtl8139", "vmxnet3", "ne2k_pci", "pcnet", "i82551", "i82557b", "i82559er", "ne2k_isa"}
+
+var qemuConfigSchema = []ConfigOption{
+	{Key: "acpi", Kind: OptBool, Help: "enable ACPI"},
+	{Key: "affinity", Help: "host cores the VM may run on, e.g. 0,5,8-11"},
+	{Key: "agent", Kind: OptProps, Props: []string{"enabled", "freeze-fs-on-backup", "fstrim_cloned_disks", "type"}, Help: "QEMU guest agent, e.g. 1,fstrim_cloned_disks=1"},
+	{Key: "arch", Kind: OptEnum, Enum: []string{"x86_64", "aarch64"}, Help: "virtual processor architecture"},
+	{Key: "args", Help: "extra arguments passed to kvm (root only)"},
+	{Key: "audio", Slots: 1, Kind: OptProps, Props: []string{"device", "driver"}, Help: "audio device"},
+	{Key: "autostart", Kind: OptBool, Help: "restart automatically after a crash"},
+	{Key: "balloon", Kind: OptInt, Help: "minimum memory in MiB for the balloon device (0 disables it)"},
+	{Key: "bios", Kind: OptEnum, Enum: []string{"seabios", "ovmf"}, Help: "BIOS implementation"},
+	{Key: "boot", Help: "boot order, e.g. order=scsi0;net0"},
+	{Key: "cicustom", Kind: OptProps, Props: []string{"meta", "network", "user", "vendor"}, Help: "cloud-init custom snippet files"},
+	{Key: "cipassword", Help: "cloud-init user password"},
+	{Key: "citype", Kind: OptEnum, Enum: []string{"configdrive2", "nocloud", "opennebula"}, Help: "cloud-init configuration format"},
+	{Key: "ciupgrade", Kind: OptBool, Help: "cloud-init: upgrade packages on first boot"},
+	{Key: "ciuser", Help: "cloud-init user name"},
+	{Key: "cores", Kind: OptInt, Min: 1, Help: "CPU cores per socket"},
+	{Key: "cpu", Kind: OptProps, Props: []string{"cputype", "flags", "hidden", "hv-vendor-id", "phys-bits", "reported-model"}, Help: "emulated CPU type, e.g. host or x86-64-v2-AES"},
+	{Key: "cpulimit", Kind: OptNumber, Max: 128, Help: "CPU usage limit in cores (0 = unlimited)"},
+	{Key: "cpuunits", Kind: OptInt, Min: 1, Max: 262144, Help: "CPU weight relative to other VMs"},
+	{Key: "description", Help: "notes shown in the web UI"},
+	{Key: "efidisk", Slots: 1, Kind: OptProps, Props: []string{"efitype", "file", "format", "import-from", "pre-enrolled-keys", "size"}, Help: "EFI vars disk"},
+	{Key: "freeze", Kind: OptBool, Help: "freeze the CPU at startup"},
+	{Key: "hookscript", Help: "hook script volume, e.g. local:snippets/hook.pl"},
+	{Key: "hostpci", Slots: 16, Kind: OptProps, Props: []string{"device-id", "host", "legacy-igd", "mapping", "mdev", "pcie", "rombar", "romfile", "sub-device-id", "sub-vendor-id", "vendor-id", "x-vga"}, Help: "host PCI device passthrough"},
+	{Key: "hotplug", Help: "hotplug features, e.g. network,disk,usb (0 disables, 1 = default set)"},
+	{Key: "hugepages", Kind: OptEnum, Enum: []string{"any", "2", "1024"}, Help: "hugepage size in MiB"},
+	{Key: "ide", Slots: 4, Kind: OptProps, Props: qemuDiskProps, Help: "IDE drive"},
+	{Key: "ipconfig", Slots: 32, Kind: OptProps, Props: []string{"gw", "gw6", "ip", "ip6"}, Help: "cloud-init IP config for netN, e.g. ip=dhcp"},
+	{Key: "ivshmem", Kind: OptProps, Props: []string{"name", "size"}, Help: "inter-VM shared memory"},
+	{Key: "keephugepages", Kind: OptBool, Help: "keep hugepages allocated after shutdown"},
+	{Key: "keyboard", Kind: OptEnum, Enum: []string{"de", "de-ch", "da", "en-gb", "en-us", "es", "fi", "fr", "fr-be", "fr-ca", "fr-ch", "hu", "is", "it", "ja", "lt", "mk", "nl", "no", "pl", "pt", "pt-br", "sv", "sl", "tr"}, Help: "VNC keyboard layout"},
+	{Key: "kvm", Kind: OptBool, Help: "enable hardware virtualization"},
+	{Key: "localtime", Kind: OptBool, Help: "set the RTC to local time"},
+	{Key: "lock", Kind: OptEnum, Enum: []string{"backup", "clone", "create", "migrate", "rollback", "snapshot", "snapshot-delete", "suspending", "suspended"}, Help: "config lock (normally managed by Proxmox)"},
+	{Key: "machine", Help: "machine type, e.g. q35 or pc-i440fx-8.1"},
+	{Key: "memory", Kind: OptInt, Min: 16, Help: "memory in MiB"},
+	{Key: "migrate_downtime", Kind: OptNumber, Help: "maximum migration downtime in seconds"},
+	{Key: "migrate_speed", Kind: OptInt, Help: "maximum migration speed in MB/s (0 = unlimited)"},
+	{Key: "name", Help: "VM name (DNS name)"},
+	{Key: "nameserver", Help: "cloud-init DNS servers"},
+	{Key: "net", Slots: 32, Kind: OptProps, Props: qemuNetProps, Help: "network device, e.g. virtio,bridge=vmbr0"},
+	{Key: "numa", Kind: OptBool, Help: "enable NUMA"},
+	{Key: "numa", Slots: 8, Kind: OptProps, Props: []string{"cpus", "hostnodes", "memory", "policy"}, Help: "NUMA topology"},
+	{Key: "onboot", Kind: OptBool, Help: "start at node boot"},
+	{Key: "ostype", Kind: OptEnum, Enum: []string{"other", "wxp", "w2k", "w2k3", "w2k8", "wvista", "win7", "win8", "win10", "win11", "l24", "l26", "solaris"}, Help: "guest OS type"},
+	{Key: "parallel", Slots: 3, Help: "host parallel device, e.g. /dev/parport0"},
+	{Key: "protection", Kind: OptBool, Help: "prevent removal of the VM and its disks"},
+	{Key: "reboot", Kind: OptBool, Help: "allow reboots (0 = exit on reboot)"},
+	{Key: "rng", Slots: 1, Kind: OptProps, Props: []string{"max_bytes", "period", "source"}, Help: "VirtIO RNG device"},
+	{Key: "sata", Slots: 6, Kind: OptProps, Props: qemuDiskProps, Help: "SATA drive"},
+	{Key: "scsi", Slots: 31, Kind: OptProps, Props: qemuDiskProps, Help: "SCSI drive"},
+	{Key: "scsihw", Kind: OptEnum, Enum: []string{"lsi", "lsi53c810", "virtio-scsi-pci", "virtio-scsi-single", "megasas", "pvscsi"}, Help: "SCSI controller model"},
+	{Key: "searchdomain", Help: "cloud-init DNS search domains"},
+	{Key: "serial", Slots: 4, Help: "serial port: socket or a host device"},
+	{Key: "shares", Kind: OptInt, Max: 50000, Help: "auto-ballooning memory shares"},
+	{Key: "smbios1", Kind: OptProps, Props: []string{"base64", "family", "manufacturer", "product", "serial", "sku", "uuid", "version"}, Help: "SMBIOS type 1 fields"},
+	{Key: "smp", Kind: OptInt, Min: 1, Help: "number of CPUs (deprecated, use sockets)"},
+	{Key: "sockets", Kind: OptInt, Min: 1, Help: "CPU sockets"},
+	{Key: "spice_enhancements", Kind: OptProps, Props: []string{"foldersharing", "videostreaming"}, Help: "SPICE enhancements"},
+	{Key: "sshkeys", Help: "cloud-init public SSH keys (URL-encoded)"},
+	{Key: "startdate", Help: "initial RTC date, now or YYYY-MM-DD"},
+	{Key: "startup", Kind: OptProps, Props: []string{"order", "up", "down"}, Help: "startup order and delays, e.g. order=1,up=30"},
+	{Key: "tablet", Kind: OptBool, Help: "enable the USB tablet device"},
+	{Key: "tags", Help: "tags separated by ';'"},
+	{Key: "tdf", Kind: OptBool, Help: "enable time drift fix"},
+	{Key: "template", Kind: OptBool, Help: "VM is a template"},
+	{Key: "tpmstate", Slots: 1, Kind: OptProps, Props: []string{"file", "import-from", "size", "version"}, Help: "TPM state disk"},
+	{Key: "unused", Slots: 256, Help: "unused volume"},
+	{Key: "usb", Slots: 14, Kind: OptProps, Props: []string{"host", "mapping", "usb3"}, Help: "USB device, e.g. host=0951:1666"},
+	{Key: "vcpus", Kind: OptInt, Help: "hotplugged vCPUs"},
+	{Key: "vga", Kind: OptProps, Props: []string{"clipboard", "memory", "type"}, Help: "display, e.g. std, virtio, qxl, serial0, none"},
+	{Key: "virtio", Slots: 16, Kind: OptProps, Props: qemuDiskProps, Help: "VirtIO block drive"},
+	{Key: "vmgenid", Help: "VM generation ID (1 generates a new one)"},
+	{Key: "vmstatestorage", Help: "default storage for VM state volumes"},
+	{Key: "watchdog", Kind: OptProps, Props: []string{"action", "model"}, Help: "watchdog device, e.g. model=i6300esb,action=reset"},
+}
+
+var lxcConfigSchema = []ConfigOption{
+	{Key: "arch", Kind: OptEnum, Enum: []string{"amd64", "i386", "arm64", "armhf", "riscv32", "riscv64"}, Help: "OS architecture"},
+	{Key: "cmode", Kind: OptEnum, Enum: []string{"shell", "console", "tty"}, Help: "console mode"},
+	{Key: "console", Kind: OptBool, Help: "attach a console device"},
+	{Key: "cores", Kind: OptInt, Min: 1, Max: 8192, Help: "CPU cores"},
+	{Key: "cpulimit", Kind: OptNumber, Max: 8192, Help: "CPU usage limit in cores (0 = unlimited)"},
+	{Key: "cpuunits", Kind: OptInt, Max: 500000, Help: "CPU weight relative to other containers"},
+	{Key: "debug", Kind: OptBool, Help: "verbose LXC logging at start"},
+	{Key: "description", Help: "notes shown in the web UI"},
+	{Key: "dev", Slots: 256, Kind: OptProps, Props: []string{"deny-write", "gid", "mode", "path", "uid"}, Help: "device passthrough, e.g. /dev/ttyUSB0,mode=0660"},
+	{Key: "features", Kind: OptProps, Props: []string{"force_rw_sys", "fuse", "keyctl", "mknod", "mount", "nesting"}, Help: "LXC features, e.g. nesting=1,keyctl=1"},
+	{Key: "hookscript", Help: "hook script volume, e.g. local:snippets/hook.pl"},
+	{Key: "hostname", Help: "container host name"},
+	{Key: "lock", Kind: OptEnum, Enum: []string{"backup", "create", "destroyed", "disk", "fstrim", "migrate", "mounted", "rollback", "snapshot", "snapshot-delete"}, Help: "config lock (normally managed by Proxmox)"},
+	{Key: "memory", Kind: OptInt, Min: 16, Help: "memory in MiB"},
+	{Key: "mp", Slots: 256, Kind: OptProps, Props: lxcMountProps, Help: "mount point, e.g. local-lvm:8,mp=/srv"},
+	{Key: "nameserver", Help: "DNS servers"},
+	{Key: "net", Slots: 32, Kind: OptProps, Props: []string{"bridge", "firewall", "gw", "gw6", "hwaddr", "ip", "ip6", "link_down", "mtu", "name", "rate", "tag", "trunks", "type"}, Help: "network interface, e.g. name=eth0,bridge=vmbr0,ip=dhcp"},
+	{Key: "onboot", Kind: OptBool, Help: "start at node boot"},
+	{Key: "ostype", Kind: OptEnum, Enum: []string{"debian", "devuan", "ubuntu", "centos", "fedora", "opensuse", "archlinux", "alpine", "gentoo", "nixos", "unmanaged"}, Help: "OS type"},
+	{Key: "protection", Kind: OptBool, Help: "prevent removal of the container and its disks"},
+	{Key: "rootfs", Kind: OptProps, Props: lxcMountProps, Help: "root volume"},
+	{Key: "searchdomain", Help: "DNS search domains"},
+	{Key: "startup", Kind: OptProps, Props: []string{"order", "up", "down"}, Help: "startup order and delays, e.g. order=1,up=30"},
+	{Key: "swap", Kind: OptInt, Help: "swap in MiB"},
+	{Key: "tags", Help: "tags separated by ';'"},
+	{Key: "template", Kind: OptBool, Help: "container is a template"},
+	{Key: "timezone", Help: "time zone, e.g. Europe/Vienna, or host"},
+	{Key: "tty", Kind: OptInt, Max: 6, Help: "number of ttys"},
+	{Key: "unprivileged", Kind: OptBool, Help: "run as an unprivileged user (set at creation)"},
+	{Key: "unused", Slots: 256, Help: "unused volume"},
+}
+
+// ConfigSchema returns the known options of guest type typ ("qemu" or
+// "lxc") sorted by key.
+func ConfigSchema(typ string) []ConfigOption {
+	schema := qemuConfigSchema
+	if typ == "lxc" {
+		schema = lxcConfigSchema
+	}
+	out := append([]ConfigOption(nil), schema...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// reservedConfigKeys are API parameters that are not configuration options.
+var reservedConfigKeys = map[string]bool{"digest": true, "delete": true, "revert": true, "skiplock": true, "node": true, "vmid": true}
+
+// LookupConfigOption returns the option describing key for guest type typ.
+func LookupConfigOption(typ, key string) (ConfigOption, error) {
+	if reservedConfigKeys[key] {
+		return ConfigOption{}, fmt.Errorf("%q is not a configuration option", key)
+	}
+	prefix, n := splitSlotKey(key)
+	schema := ConfigSchema(typ)
+	for _, o := range schema {
+		if o.Slots == 0 && o.Key == key {
+			return o, nil
+		}
+	}
+	if n >= 0 {
+		for _, o := range schema {
+			if o.Slots > 0 && o.Key == prefix {
+				if n >= o.Slots {
+					return ConfigOption{}, fmt.Errorf("%s: index out of range (%s0-%s%d)", key, prefix, prefix, o.Slots-1)
+				}
+				return o, nil
+			}
+		}
+	}
+	msg := fmt.Sprintf("unknown option %q", key)
+	if s := suggestConfigKey(schema, key); s != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", s)
+	}
+	return ConfigOption{}, fmt.Errorf("%s", msg)
+}
+
+// suggestConfigKey returns the option name closest to key, or "" when none
+// is close.
+func suggestConfigKey(schema []ConfigOption, key string) string {
+	prefix, _ := splitSlotKey(key)
+	best, bestDist := "", 3
+	for _, o := range schema {
+		target := key
+		if o.Slots > 0 {
+			target = prefix
+		}
+		if d := editDistance(target, o.Key); d < bestDist {
+			best, bestDist = o.Name(), d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// ValidateConfigValue checks value against the option for key and returns it
+// normalized (booleans become 0/1).
+func ValidateConfigValue(typ, key, value string) (string, error) {
+	o, err := LookupConfigOption(typ, key)
+	if err != nil {
+		return "", err
+	}
+	switch o.Kind {
+	case OptBool:
+		switch strings.ToLower(value) {
+		case "1", "true", "yes", "on":
+			return "1", nil
+		case "0", "false", "no", "off":
+			return "0", nil
+		}
+		return "", fmt.Errorf("%s: expected a boolean (0/1), got %q", key, value)
+	case OptInt, OptNumber:
+		var f float64
+		if o.Kind == OptInt {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return "", fmt.Errorf("%s: expected an integer, got %q", key, value)
+			}
+			f = float64(n)
+		} else if f, err = strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("%s: expected a number, got %q", key, value)
+		}
+		if f < o.Min || (o.Max > 0 && f > o.Max) {
+			if o.Max > 0 {
+				return "", fmt.Errorf("%s: %s is out of range (%g-%g)", key, value, o.Min, o.Max)
+			}
+			return "", fmt.Errorf("%s: %s is below the minimum of %g", key, value, o.Min)
+		}
+	case OptEnum:
+		if slices.Contains(o.Enum, value) {
+			return value, nil
+		}
+		return "", fmt.Errorf("%s: invalid value %q (must be one of %s)", key, value, strings.Join(o.Enum, ", "))
+	case OptProps:
+		for _, part := range ParsePropertyString(value) {
+			if part.Key == "" {
+				continue
+			}
+			if !slices.Contains(o.Props, part.Key) {
+				return "", fmt.Errorf("%s: unknown property %q (known: %s)", key, part.Key, strings.Join(o.Props, ", "))
+			}
+		}
+	}
+	if value == "" {
+		return "", fmt.Errorf("%s: empty value (use unset to remove an option)", key)
+	}
+	return value, nil
+}
@@ -0,0 +1,162 @@
+package actions
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/client"
+)
+
+// TermProxy is a terminal proxy started on a node: the websocket URL to
+// connect to and the credentials for it. Proxmox stops the proxy if nobody
+// connects within a few seconds, so connect right away.
+type TermProxy struct {
+	URL    string
+	Header http.Header // API authentication for the websocket handshake
+	TLS    *tls.Config
+	User   string
+	Ticket string
+}
+
+// OpenNodeShell starts a login shell terminal proxy on nodeName.
+func OpenNodeShell(ctx context.Context, c *proxmox.Client, nodeName string) (*TermProxy, error) {
+	return openTermProxy(ctx, c, "/nodes/"+url.PathEscape(nodeName), nil)
+}
+
+// OpenGuestConsole starts a terminal proxy to the console of guest vmid of
+// type typ ("qemu" or "lxc") on nodeName. For a VM, serial names the serial
+// port to attach to (e.g. "serial0").
+func OpenGuestConsole(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName, serial string) (*TermProxy, error) {
+	var body map[string]string
+	if typ == "qemu" && serial != "" {
+		body = map[string]string{"serial": serial}
+	}
+	return openTermProxy(ctx, c, fmt.Sprintf("/nodes/%s/%s/%d", url.PathEscape(nodeName), typ, vmid), body)
+}
+
+// openTermProxy starts the terminal proxy of the API object at base and
+// builds the URL of its websocket from the endpoint the request went to, so
+// it reaches the same server with the same authentication.
+func openTermProxy(ctx context.Context, c *proxmox.Client, base string, body map[string]string) (*TermProxy, error) {
+	var ep client.Endpoint
+	var resp struct {
+		Port   proxmox.StringOrInt `json:"port"`
+		Ticket string              `json:"ticket"`
+		User   string              `json:"user"`
+	}
+	if err := c.Post(client.WithEndpoint(ctx, &ep), base+"/termproxy", body, &resp); err != nil {
+		return nil, err
+	}
+	if ep.URL == nil {
+		return nil, fmt.Errorf("termproxy: API endpoint unknown")
+	}
+	u := *ep.URL
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/termproxy") + "/vncwebsocket"
+	u.RawPath = ""
+	u.RawQuery = url.Values{
+		"port":      {strconv.Itoa(int(resp.Port))},
+		"vncticket": {resp.Ticket},
+	}.Encode()
+
+	header := http.Header{}
+	for _, k := range []string{"Authorization", "Cookie", "User-Agent"} {
+		if v := ep.Header.Get(k); v != "" {
+			header.Set(k, v)
+		}
+	}
+	return &TermProxy{URL: u.String(), Header: header, TLS: ep.TLS, User: resp.User, Ticket: resp.Ticket}, nil
+}
+
+// guestEndpoint reads g's run state and name, recording the endpoint the
+// request went to.
+func guestEndpoint(ctx context.Context, c *proxmox.Client, g *GuestConfig) (status, name string, ep client.Endpoint, err error) {
+	var st struct {
+		Status string `json:"status"`
+		Name   string `json:"name"`
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d/status/current", url.PathEscape(g.Node), g.Type, g.VMID)
+	if err := c.Get(client.WithEndpoint(ctx, &ep), path, &st); err != nil {
+		return "", "", ep, err
+	}
+	if ep.URL == nil {
+		return "", "", ep, fmt.Errorf("API endpoint unknown")
+	}
+	return st.Status, st.Name, ep, nil
+}
+
+// NoVNCURL returns the web UI address that opens the noVNC console of g's
+// guest, as the web UI's Console button does. Opening it asks the server for
+// a fresh one-time VNC ticket under the browser's web UI login.
+func NoVNCURL(ctx context.Context, c *proxmox.Client, g *GuestConfig) (string, error) {
+	_, name, ep, err := guestEndpoint(ctx, c, g)
+	if err != nil {
+		return "", err
+	}
+	console := "kvm"
+	if g.Type == "lxc" {
+		console = "lxc"
+	}
+	u := url.URL{Scheme: ep.URL.Scheme, Host: ep.URL.Host, Path: "/"}
+	u.RawQuery = url.Values{
+		"console": {console},
+		"novnc":   {"1"},
+		"vmid":    {strconv.Itoa(g.VMID)},
+		"vmname":  {name},
+		"node":    {g.Node},
+		"resize":  {"scale"},
+	}.Encode()
+	return u.String(), nil
+}
+
+// HasSpiceDisplay reports whether the VM config in g uses a SPICE (qxl)
+// display.
+func HasSpiceDisplay(g *GuestConfig) bool {
+	return strings.HasPrefix(g.Values["vga"], "qxl")
+}
+
+// SpiceViewerFile requests a SPICE ticket for the running VM in g and
+// returns it as a virt-viewer (.vv) connection file. proxy is the host the
+// viewer connects through; empty uses the host of the API URL, so the file
+// works from wherever the API is reachable. The ticket expires shortly.
+func SpiceViewerFile(ctx context.Context, c *proxmox.Client, g *GuestConfig, proxy string) ([]byte, error) {
+	status, _, ep, err := guestEndpoint(ctx, c, g)
+	if err != nil {
+		return nil, err
+	}
+	if status != "running" {
+		return nil, fmt.Errorf("VM %d is not running", g.VMID)
+	}
+	if proxy == "" {
+		proxy = ep.URL.Hostname()
+	}
+	var resp map[string]interface{}
+	path := fmt.Sprintf("/nodes/%s/qemu/%d/spiceproxy", url.PathEscape(g.Node), g.VMID)
+	if err := c.Post(ctx, path, map[string]string{"proxy": proxy}, &resp); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(resp))
+	for k := range resp {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString("[virt-viewer]\n")
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, configString(resp[k]))
+	}
+	return []byte(b.String()), nil
+}
@@ -87,27 +87,24 @@ func RebootContainer(ctx context.Context, c *proxmox.Client, ctid int, nodeName
 	return ct.Reboot(ctx)
 }
 
-// ContainerSnapshots returns the list of snapshots for a container, sorted newest first.
-func ContainerSnapshots(ctx context.Context, c *proxmox.Client, ctid int, nodeName string) ([]*proxmox.ContainerSnapshot, error) {
+// ContainerSnapshots returns the snapshots of a container, oldest first,
+// followed by CurrentSnapshot. Use SnapshotTree to arrange them by parent.
+func ContainerSnapshots(ctx context.Context, c *proxmox.Client, ctid int, nodeName string) ([]*GuestSnapshot, error) {
 	ct, err := FindContainer(ctx, c, ctid, nodeName)
 	if err != nil {
 		return nil, err
 	}
-	snaps, err := ct.Snapshots(ctx)
-	if err != nil {
-		return nil, err
-	}
-	sort.Slice(snaps, func(i, j int) bool { return snaps[i].SnapshotCreationTime > snaps[j].SnapshotCreationTime })
-	return snaps, nil
+	return guestSnapshots(ctx, c, "lxc", ctid, ct.Node)
 }
 
-// CreateContainerSnapshot creates a snapshot for a container and returns the task.
-func CreateContainerSnapshot(ctx context.Context, c *proxmox.Client, ctid int, nodeName, name string) (*proxmox.Task, error) {
+// CreateContainerSnapshot creates a snapshot for a container and returns the
+// task. Containers cannot save RAM state, so opts.VMState must be false.
+func CreateContainerSnapshot(ctx context.Context, c *proxmox.Client, ctid int, nodeName, name string, opts SnapshotOptions) (*proxmox.Task, error) {
 	ct, err := FindContainer(ctx, c, ctid, nodeName)
 	if err != nil {
 		return nil, err
 	}
-	return ct.NewSnapshot(ctx, name)
+	return createGuestSnapshot(ctx, c, "lxc", ctid, ct.Node, name, opts)
 }
 
 // RollbackContainerSnapshot rolls back a container to a snapshot and returns the task.
@@ -0,0 +1,254 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/cache"
+)
+
+// VMFacts is an inventory document for one VM, gathered through the guest
+// agent. Error is set when the agent could not be reached at all; Errors
+// holds the sections that failed on their own (e.g. a command the agent
+// has disabled), keyed by section name.
+type VMFacts struct {
+	VMID        uint64            `json:"vmid"`
+	Name        string            `json:"name"`
+	Node        string            `json:"node"`
+	CollectedAt time.Time         `json:"collected_at"`
+	Hostname    string            `json:"hostname,omitempty"`
+	OS          *FactsOS          `json:"os,omitempty"`
+	Timezone    *FactsTimezone    `json:"timezone,omitempty"`
+	Users       []FactsUser       `json:"users,omitempty"`
+	Filesystems []FactsFilesystem `json:"filesystems,omitempty"`
+	Interfaces  []FactsInterface  `json:"interfaces,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	Errors      map[string]string `json:"errors,omitempty"`
+}
+
+// FactsOS describes the guest operating system.
+type FactsOS struct {
+	ID            string `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	PrettyName    string `json:"pretty_name,omitempty"`
+	Version       string `json:"version,omitempty"`
+	VersionID     string `json:"version_id,omitempty"`
+	Kernel        string `json:"kernel,omitempty"`
+	KernelVersion string `json:"kernel_version,omitempty"`
+	Arch          string `json:"arch,omitempty"`
+}
+
+// FactsTimezone is the guest's time zone and its offset from UTC.
+type FactsTimezone struct {
+	Zone          string `json:"zone,omitempty"`
+	OffsetSeconds int    `json:"offset_seconds"`
+}
+
+// FactsUser is a user logged in to the guest.
+type FactsUser struct {
+	Name      string    `json:"name"`
+	Domain    string    `json:"domain,omitempty"`
+	LoginTime time.Time `json:"login_time"`
+}
+
+// FactsFilesystem is a mounted guest filesystem. Sizes are zero when the
+// agent does not report them.
+type FactsFilesystem struct {
+	Mountpoint string   `json:"mountpoint"`
+	Type       string   `json:"type"`
+	Device     string   `json:"device,omitempty"`
+	TotalBytes uint64   `json:"total_bytes,omitempty"`
+	UsedBytes  uint64   `json:"used_bytes,omitempty"`
+	Disks      []string `json:"disks,omitempty"`
+}
+
+// FactsInterface is a guest network interface with its addresses in CIDR
+// notation. The loopback interface is left out.
+type FactsInterface struct {
+	Name string   `json:"name"`
+	MAC  string   `json:"mac,omitempty"`
+	IPv4 []string `json:"ipv4,omitempty"`
+	IPv6 []string `json:"ipv6,omitempty"`
+}
+
+// factsParallel bounds how many VMs are queried at once.
+const factsParallel = 8
+
+// CollectVMFacts queries the guest agent of the VM r for its facts.
+func CollectVMFacts(ctx context.Context, c *proxmox.Client, r *proxmox.ClusterResource) *VMFacts {
+	f := &VMFacts{VMID: r.VMID, Name: r.Name, Node: r.Node, CollectedAt: time.Now().UTC().Truncate(time.Second)}
+	vm, err := FindVM(ctx, c, int(r.VMID), r.Node)
+	if err != nil {
+		f.Error = err.Error()
+		return f
+	}
+	if f.Name == "" {
+		f.Name = vm.Name
+	}
+
+	// The OS query doubles as the check that the agent is there; if it
+	// fails, the other queries would fail the same way.
+	var osInfo proxmox.AgentOsInfo
+	if err := agentResult(ctx, c, vm, "get-osinfo", &osInfo); err != nil {
+		f.Error = err.Error()
+		return f
+	}
+	f.OS = &FactsOS{
+		ID: osInfo.ID, Name: osInfo.Name, PrettyName: osInfo.PrettyName,
+		Version: osInfo.Version, VersionID: osInfo.VersionID,
+		Kernel: osInfo.KernelRelease, KernelVersion: osInfo.KernelVersion, Arch: osInfo.Machine,
+	}
+
+	fail := func(section string, err error) {
+		if f.Errors == nil {
+			f.Errors = map[string]string{}
+		}
+		f.Errors[section] = err.Error()
+	}
+
+	var host struct {
+		HostName string `json:"host-name"`
+	}
+	if err := agentResult(ctx, c, vm, "get-host-name", &host); err != nil {
+		fail("hostname", err)
+	}
+	f.Hostname = host.HostName
+
+	var tz struct {
+		Zone   string `json:"zone"`
+		Offset int    `json:"offset"`
+	}
+	if err := agentResult(ctx, c, vm, "get-timezone", &tz); err != nil {
+		fail("timezone", err)
+	} else {
+		f.Timezone = &FactsTimezone{Zone: tz.Zone, OffsetSeconds: tz.Offset}
+	}
+
+	var users []struct {
+		User      string  `json:"user"`
+		Domain    string  `json:"domain"`
+		LoginTime float64 `json:"login-time"`
+	}
+	if err := agentResult(ctx, c, vm, "get-users", &users); err != nil {
+		fail("users", err)
+	}
+	for _, u := range users {
+		f.Users = append(f.Users, FactsUser{Name: u.User, Domain: u.Domain, LoginTime: time.Unix(int64(u.LoginTime), 0).UTC()})
+	}
+
+	var fss []struct {
+		Name       string `json:"name"`
+		Mountpoint string `json:"mountpoint"`
+		Type       string `json:"type"`
+		TotalBytes uint64 `json:"total-bytes"`
+		UsedBytes  uint64 `json:"used-bytes"`
+		Disk       []struct {
+			Dev string `json:"dev"`
+		} `json:"disk"`
+	}
+	if err := agentResult(ctx, c, vm, "get-fsinfo", &fss); err != nil {
+		fail("filesystems", err)
+	}
+	for _, fs := range fss {
+		out := FactsFilesystem{Mountpoint: fs.Mountpoint, Type: fs.Type, Device: fs.Name, TotalBytes: fs.TotalBytes, UsedBytes: fs.UsedBytes}
+		for _, d := range fs.Disk {
+			if d.Dev != "" {
+				out.Disks = append(out.Disks, d.Dev)
+			}
+		}
+		f.Filesystems = append(f.Filesystems, out)
+	}
+	sort.Slice(f.Filesystems, func(i, j int) bool { return f.Filesystems[i].Mountpoint < f.Filesystems[j].Mountpoint })
+
+	ifaces, err := vm.AgentGetNetworkIFaces(ctx)
+	if err != nil {
+		fail("interfaces", err)
+	}
+	for _, iface := range ifaces {
+		if isLoopbackIface(iface) {
+			continue
+		}
+		out := FactsInterface{Name: iface.Name, MAC: iface.HardwareAddress}
+		for _, a := range iface.IPAddresses {
+			cidr := a.IPAddress + "/" + strconv.Itoa(a.Prefix)
+			if a.IPAddressType == "ipv6" {
+				out.IPv6 = append(out.IPv6, cidr)
+			} else {
+				out.IPv4 = append(out.IPv4, cidr)
+			}
+		}
+		f.Interfaces = append(f.Interfaces, out)
+	}
+	return f
+}
+
+// isLoopbackIface reports whether iface is the loopback interface: named lo,
+// or carrying only loopback addresses.
+func isLoopbackIface(iface *proxmox.AgentNetworkIface) bool {
+	if iface.Name == "lo" {
+		return true
+	}
+	if len(iface.IPAddresses) == 0 {
+		return false
+	}
+	for _, a := range iface.IPAddresses {
+		if ip := net.ParseIP(a.IPAddress); ip == nil || !ip.IsLoopback() {
+			return false
+		}
+	}
+	return true
+}
+
+// VMFactsCached returns the facts of the VM r from the local cache of the
+// instance at instURL if they are at most maxAge old, and otherwise collects
+// them and caches them. Facts that could not be collected are not cached.
+func VMFactsCached(ctx context.Context, c *proxmox.Client, instURL string, r *proxmox.ClusterResource, maxAge time.Duration) *VMFacts {
+	dir, err := cache.Dir(instURL, "facts")
+	path := filepath.Join(dir, fmt.Sprintf("%d.json", r.VMID))
+	if err == nil && maxAge > 0 {
+		var f VMFacts
+		if _, ok := cache.Load(path, maxAge, &f); ok && f.VMID == r.VMID {
+			return &f
+		}
+	}
+	f := CollectVMFacts(ctx, c, r)
+	if err == nil && f.Error == "" {
+		_ = cache.Save(path, f)
+	}
+	return f
+}
+
+// CollectFacts returns the facts of each VM in vms, in order, using the cache
+// as VMFactsCached does and querying several VMs at once.
+func CollectFacts(ctx context.Context, c *proxmox.Client, instURL string, vms proxmox.ClusterResources, maxAge time.Duration) []*VMFacts {
+	sem := make(chan struct{}, factsParallel)
+	var wg sync.WaitGroup
+	out := make([]*VMFacts, len(vms))
+	for i, r := range vms {
+		wg.Add(1)
+		go func(i int, r *proxmox.ClusterResource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			out[i] = VMFactsCached(ctx, c, instURL, r, maxAge)
+		}(i, r)
+	}
+	wg.Wait()
+	return out
+}
+
+// agentResult runs the read-only agent command and decodes its result into v.
+func agentResult(ctx context.Context, c *proxmox.Client, vm *proxmox.VirtualMachine, command string, v interface{}) error {
+	resp := struct {
+		Result interface{} `json:"result"`
+	}{Result: v}
+	return c.Get(ctx, agentPath(vm, command), &resp)
+}
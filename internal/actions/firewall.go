@@ -0,0 +1,508 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// FirewallScope identifies a firewall configuration: the cluster, a node, a
+// guest, or a cluster security group (rules only).
+type FirewallScope struct {
+	Node      string // node name for node and guest scopes
+	VMID      int    // guest ID; 0 unless this is a guest scope
+	GuestType string // "qemu" or "lxc" for guest scopes
+	Group     string // security group name for group scopes
+}
+
+// ClusterFirewall is the datacenter-level firewall scope.
+var ClusterFirewall = FirewallScope{}
+
+// IsCluster reports whether s is the datacenter scope.
+func (s FirewallScope) IsCluster() bool { return s == ClusterFirewall }
+
+// IsGuest reports whether s is a VM or container scope.
+func (s FirewallScope) IsGuest() bool { return s.VMID != 0 }
+
+// IsNode reports whether s is a node scope.
+func (s FirewallScope) IsNode() bool { return s.Node != "" && s.VMID == 0 }
+
+// IsGroup reports whether s is a security group scope.
+func (s FirewallScope) IsGroup() bool { return s.Group != "" }
+
+func (s FirewallScope) String() string {
+	switch {
+	case s.IsGroup():
+		return "security group " + s.Group
+	case s.IsGuest() && s.GuestType == "lxc":
+		return fmt.Sprintf("CT %d", s.VMID)
+	case s.IsGuest():
+		return fmt.Sprintf("VM %d", s.VMID)
+	case s.IsNode():
+		return "node " + s.Node
+	default:
+		return "cluster"
+	}
+}
+
+// path is the API base path of the scope's firewall.
+func (s FirewallScope) path() string {
+	switch {
+	case s.IsGroup():
+		return "/cluster/firewall/groups/" + url.PathEscape(s.Group)
+	case s.IsGuest():
+		return fmt.Sprintf("/nodes/%s/%s/%d/firewall", s.Node, s.GuestType, s.VMID)
+	case s.IsNode():
+		return fmt.Sprintf("/nodes/%s/firewall", s.Node)
+	default:
+		return "/cluster/firewall"
+	}
+}
+
+// rulesPath is where the scope's rule list lives. Security group rules sit
+// directly under the group.
+func (s FirewallScope) rulesPath() string {
+	if s.IsGroup() {
+		return s.path()
+	}
+	return s.path() + "/rules"
+}
+
+// GuestFirewall returns the firewall scope of guest vmid, looking up its type
+// and node from the cluster resources. nodeName, when set, must match.
+func GuestFirewall(ctx context.Context, c *proxmox.Client, vmid int, nodeName string) (FirewallScope, error) {
+	resources, err := ClusterResources(ctx, c, "vm")
+	if err != nil {
+		return FirewallScope{}, err
+	}
+	for _, r := range resources {
+		if int(r.VMID) != vmid || (r.Type != "qemu" && r.Type != "lxc") {
+			continue
+		}
+		if nodeName != "" && r.Node != nodeName {
+			return FirewallScope{}, fmt.Errorf("guest %d is on node %s, not %s", vmid, r.Node, nodeName)
+		}
+		return FirewallScope{Node: r.Node, VMID: vmid, GuestType: r.Type}, nil
+	}
+	return FirewallScope{}, fmt.Errorf("guest %d not found", vmid)
+}
+
+// FirewallRule is a rule as returned by the API. Type is "in", "out" or
+// "group"; for group rules Action holds the security group name.
+type FirewallRule struct {
+	Pos      int    `json:"pos" yaml:"-"`
+	Type     string `json:"type" yaml:"type"`
+	Action   string `json:"action" yaml:"action"`
+	Enable   int    `json:"enable" yaml:"-"` // omitted by the API when 0
+	Macro    string `json:"macro,omitempty" yaml:"macro,omitempty"`
+	Proto    string `json:"proto,omitempty" yaml:"proto,omitempty"`
+	Source   string `json:"source,omitempty" yaml:"source,omitempty"`
+	Dest     string `json:"dest,omitempty" yaml:"dest,omitempty"`
+	Sport    string `json:"sport,omitempty" yaml:"sport,omitempty"`
+	Dport    string `json:"dport,omitempty" yaml:"dport,omitempty"`
+	IcmpType string `json:"icmp-type,omitempty" yaml:"icmp-type,omitempty"`
+	Iface    string `json:"iface,omitempty" yaml:"iface,omitempty"`
+	Log      string `json:"log,omitempty" yaml:"log,omitempty"`
+	Comment  string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// ruleFields lists the optional rule fields in API order, paired with
+// accessors, so create and update can send or clear them uniformly.
+var ruleFields = []struct {
+	name string
+	get  func(r FirewallRule) string
+}{
+	{"macro", func(r FirewallRule) string { return r.Macro }},
+	{"proto", func(r FirewallRule) string { return r.Proto }},
+	{"source", func(r FirewallRule) string { return r.Source }},
+	{"dest", func(r FirewallRule) string { return r.Dest }},
+	{"sport", func(r FirewallRule) string { return r.Sport }},
+	{"dport", func(r FirewallRule) string { return r.Dport }},
+	{"icmp-type", func(r FirewallRule) string { return r.IcmpType }},
+	{"iface", func(r FirewallRule) string { return r.Iface }},
+	{"log", func(r FirewallRule) string { return r.Log }},
+	{"comment", func(r FirewallRule) string { return r.Comment }},
+}
+
+// FirewallRules returns the scope's rules in evaluation order.
+func FirewallRules(ctx context.Context, c *proxmox.Client, s FirewallScope) ([]FirewallRule, error) {
+	var rules []FirewallRule
+	if err := c.Get(ctx, s.rulesPath(), &rules); err != nil {
+		return nil, err
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Pos < rules[j].Pos })
+	return rules, nil
+}
+
+// AddFirewallRule creates r. pos inserts the rule at that position; a
+// negative pos appends it to the end of the list.
+func AddFirewallRule(ctx context.Context, c *proxmox.Client, s FirewallScope, r FirewallRule, pos int) error {
+	params := map[string]interface{}{
+		"type":   r.Type,
+		"action": r.Action,
+		"enable": r.Enable,
+	}
+	for _, f := range ruleFields {
+		if v := f.get(r); v != "" {
+			params[f.name] = v
+		}
+	}
+	if pos == 0 {
+		return c.Post(ctx, s.rulesPath(), params, nil)
+	}
+	// Proxmox ignores "pos" on create and always inserts the new rule at the
+	// top, so create it there and then move it into place.
+	rules, err := FirewallRules(ctx, c, s)
+	if err != nil {
+		return err
+	}
+	if pos < 0 || pos > len(rules) {
+		pos = len(rules)
+	}
+	if err := c.Post(ctx, s.rulesPath(), params, nil); err != nil {
+		return err
+	}
+	if pos == 0 {
+		return nil
+	}
+	return MoveFirewallRule(ctx, c, s, 0, pos)
+}
+
+// UpdateFirewallRule replaces the rule at pos with r, clearing fields that
+// are empty in r.
+func UpdateFirewallRule(ctx context.Context, c *proxmox.Client, s FirewallScope, pos int, r FirewallRule) error {
+	params := map[string]interface{}{
+		"type":   r.Type,
+		"action": r.Action,
+		"enable": r.Enable,
+	}
+	var unset []string
+	for _, f := range ruleFields {
+		if v := f.get(r); v != "" {
+			params[f.name] = v
+		} else {
+			unset = append(unset, f.name)
+		}
+	}
+	if len(unset) > 0 {
+		params["delete"] = strings.Join(unset, ",")
+	}
+	return c.Put(ctx, fmt.Sprintf("%s/%d", s.rulesPath(), pos), params, nil)
+}
+
+// DeleteFirewallRule removes the rule at pos.
+func DeleteFirewallRule(ctx context.Context, c *proxmox.Client, s FirewallScope, pos int) error {
+	return c.Delete(ctx, fmt.Sprintf("%s/%d", s.rulesPath(), pos), nil)
+}
+
+// MoveFirewallRule moves the rule at pos so that it ends up at position to.
+func MoveFirewallRule(ctx context.Context, c *proxmox.Client, s FirewallScope, pos, to int) error {
+	// Proxmox removes the rule first and then inserts it before the rule
+	// currently at "moveto", so moving down needs one extra step.
+	moveto := to
+	if to > pos {
+		moveto = to + 1
+	}
+	return c.Put(ctx, fmt.Sprintf("%s/%d", s.rulesPath(), pos), map[string]interface{}{"moveto": moveto}, nil)
+}
+
+// FirewallOptions returns the scope's firewall options (enable, policy_in,
+// policy_out, ...). Values are as returned by the API.
+func FirewallOptions(ctx context.Context, c *proxmox.Client, s FirewallScope) (map[string]interface{}, error) {
+	if s.IsGroup() {
+		return nil, fmt.Errorf("security groups have no options")
+	}
+	opts := map[string]interface{}{}
+	if err := c.Get(ctx, s.path()+"/options", &opts); err != nil {
+		return nil, err
+	}
+	delete(opts, "digest")
+	return opts, nil
+}
+
+// SetFirewallOptions updates the given options; keys in unset are reset to
+// their defaults.
+func SetFirewallOptions(ctx context.Context, c *proxmox.Client, s FirewallScope, set map[string]string, unset []string) error {
+	if s.IsGroup() {
+		return fmt.Errorf("security groups have no options")
+	}
+	params := map[string]interface{}{}
+	for k, v := range set {
+		params[k] = v
+	}
+	if len(unset) > 0 {
+		params["delete"] = strings.Join(unset, ",")
+	}
+	return c.Put(ctx, s.path()+"/options", params, nil)
+}
+
+// FirewallIPSet is an IP set with its entries.
+type FirewallIPSet struct {
+	Name    string               `json:"name" yaml:"name"`
+	Comment string               `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Entries []FirewallIPSetEntry `json:"entries,omitempty" yaml:"entries,omitempty"`
+}
+
+// FirewallIPSetEntry is one CIDR (or alias) in an IP set.
+type FirewallIPSetEntry struct {
+	CIDR    string `json:"cidr" yaml:"cidr"`
+	NoMatch bool   `json:"nomatch,omitempty" yaml:"nomatch,omitempty"`
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+func checkSetScope(s FirewallScope, what string) error {
+	if s.IsNode() || s.IsGroup() {
+		return fmt.Errorf("%s are only available for the cluster and guests", what)
+	}
+	return nil
+}
+
+// FirewallIPSets returns the scope's IP sets including their entries, sorted
+// by name.
+func FirewallIPSets(ctx context.Context, c *proxmox.Client, s FirewallScope) ([]FirewallIPSet, error) {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return nil, err
+	}
+	var sets []FirewallIPSet
+	if err := c.Get(ctx, s.path()+"/ipset", &sets); err != nil {
+		return nil, err
+	}
+	for i := range sets {
+		entries, err := FirewallIPSetEntries(ctx, c, s, sets[i].Name)
+		if err != nil {
+			return nil, err
+		}
+		sets[i].Entries = entries
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].Name < sets[j].Name })
+	return sets, nil
+}
+
+// FirewallIPSetEntries returns the entries of IP set name.
+func FirewallIPSetEntries(ctx context.Context, c *proxmox.Client, s FirewallScope, name string) ([]FirewallIPSetEntry, error) {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return nil, err
+	}
+	var raw []struct {
+		CIDR    string      `json:"cidr"`
+		NoMatch interface{} `json:"nomatch"`
+		Comment string      `json:"comment"`
+	}
+	if err := c.Get(ctx, s.path()+"/ipset/"+url.PathEscape(name), &raw); err != nil {
+		return nil, err
+	}
+	entries := make([]FirewallIPSetEntry, len(raw))
+	for i, e := range raw {
+		entries[i] = FirewallIPSetEntry{CIDR: e.CIDR, NoMatch: truthy(e.NoMatch), Comment: e.Comment}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CIDR < entries[j].CIDR })
+	return entries, nil
+}
+
+// CreateFirewallIPSet creates an empty IP set.
+func CreateFirewallIPSet(ctx context.Context, c *proxmox.Client, s FirewallScope, name, comment string) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	params := map[string]interface{}{"name": name}
+	if comment != "" {
+		params["comment"] = comment
+	}
+	return c.Post(ctx, s.path()+"/ipset", params, nil)
+}
+
+// UpdateFirewallIPSet changes the comment of IP set name.
+func UpdateFirewallIPSet(ctx context.Context, c *proxmox.Client, s FirewallScope, name, comment string) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	// Renaming a set to its own name is how the API updates the comment.
+	params := map[string]interface{}{"name": name, "rename": name, "comment": comment}
+	return c.Post(ctx, s.path()+"/ipset", params, nil)
+}
+
+// DeleteFirewallIPSet deletes IP set name. Proxmox refuses to delete a
+// non-empty set unless force is set.
+func DeleteFirewallIPSet(ctx context.Context, c *proxmox.Client, s FirewallScope, name string, force bool) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	path := s.path() + "/ipset/" + url.PathEscape(name)
+	if force {
+		path += "?force=1"
+	}
+	return c.Delete(ctx, path, nil)
+}
+
+// AddFirewallIPSetEntry adds e to IP set name.
+func AddFirewallIPSetEntry(ctx context.Context, c *proxmox.Client, s FirewallScope, name string, e FirewallIPSetEntry) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	params := map[string]interface{}{"cidr": e.CIDR}
+	if e.NoMatch {
+		params["nomatch"] = 1
+	}
+	if e.Comment != "" {
+		params["comment"] = e.Comment
+	}
+	return c.Post(ctx, s.path()+"/ipset/"+url.PathEscape(name), params, nil)
+}
+
+// UpdateFirewallIPSetEntry changes the nomatch flag and comment of entry
+// e.CIDR in IP set name.
+func UpdateFirewallIPSetEntry(ctx context.Context, c *proxmox.Client, s FirewallScope, name string, e FirewallIPSetEntry) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	params := map[string]interface{}{"comment": e.Comment, "nomatch": 0}
+	if e.NoMatch {
+		params["nomatch"] = 1
+	}
+	return c.Put(ctx, s.path()+"/ipset/"+url.PathEscape(name)+"/"+url.PathEscape(e.CIDR), params, nil)
+}
+
+// RemoveFirewallIPSetEntry removes cidr from IP set name.
+func RemoveFirewallIPSetEntry(ctx context.Context, c *proxmox.Client, s FirewallScope, name, cidr string) error {
+	if err := checkSetScope(s, "IP sets"); err != nil {
+		return err
+	}
+	return c.Delete(ctx, s.path()+"/ipset/"+url.PathEscape(name)+"/"+url.PathEscape(cidr), nil)
+}
+
+// FirewallAlias names a network or address for use in rules and IP sets.
+type FirewallAlias struct {
+	Name    string `json:"name" yaml:"name"`
+	CIDR    string `json:"cidr" yaml:"cidr"`
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// FirewallAliases returns the scope's aliases sorted by name.
+func FirewallAliases(ctx context.Context, c *proxmox.Client, s FirewallScope) ([]FirewallAlias, error) {
+	if err := checkSetScope(s, "aliases"); err != nil {
+		return nil, err
+	}
+	var aliases []FirewallAlias
+	if err := c.Get(ctx, s.path()+"/aliases", &aliases); err != nil {
+		return nil, err
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	return aliases, nil
+}
+
+// CreateFirewallAlias creates alias a.
+func CreateFirewallAlias(ctx context.Context, c *proxmox.Client, s FirewallScope, a FirewallAlias) error {
+	if err := checkSetScope(s, "aliases"); err != nil {
+		return err
+	}
+	params := map[string]interface{}{"name": a.Name, "cidr": a.CIDR}
+	if a.Comment != "" {
+		params["comment"] = a.Comment
+	}
+	return c.Post(ctx, s.path()+"/aliases", params, nil)
+}
+
+// UpdateFirewallAlias changes the address and comment of alias a.Name.
+func UpdateFirewallAlias(ctx context.Context, c *proxmox.Client, s FirewallScope, a FirewallAlias) error {
+	if err := checkSetScope(s, "aliases"); err != nil {
+		return err
+	}
+	params := map[string]interface{}{"cidr": a.CIDR, "comment": a.Comment}
+	return c.Put(ctx, s.path()+"/aliases/"+url.PathEscape(a.Name), params, nil)
+}
+
+// DeleteFirewallAlias deletes alias name.
+func DeleteFirewallAlias(ctx context.Context, c *proxmox.Client, s FirewallScope, name string) error {
+	if err := checkSetScope(s, "aliases"); err != nil {
+		return err
+	}
+	return c.Delete(ctx, s.path()+"/aliases/"+url.PathEscape(name), nil)
+}
+
+// FirewallGroup is a cluster security group.
+type FirewallGroup struct {
+	Group   string `json:"group"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// FirewallGroups returns the cluster's security groups sorted by name.
+func FirewallGroups(ctx context.Context, c *proxmox.Client) ([]FirewallGroup, error) {
+	var groups []FirewallGroup
+	if err := c.Get(ctx, "/cluster/firewall/groups", &groups); err != nil {
+		return nil, err
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Group < groups[j].Group })
+	return groups, nil
+}
+
+// CreateFirewallGroup creates an empty security group.
+func CreateFirewallGroup(ctx context.Context, c *proxmox.Client, name, comment string) error {
+	params := map[string]interface{}{"group": name}
+	if comment != "" {
+		params["comment"] = comment
+	}
+	return c.Post(ctx, "/cluster/firewall/groups", params, nil)
+}
+
+// DeleteFirewallGroup deletes a security group. Proxmox refuses while the
+// group still has rules or is referenced.
+func DeleteFirewallGroup(ctx context.Context, c *proxmox.Client, name string) error {
+	return c.Delete(ctx, "/cluster/firewall/groups/"+url.PathEscape(name), nil)
+}
+
+// EffectiveFirewallRule is a guest rule as evaluated, with security group
+// references expanded in place.
+type EffectiveFirewallRule struct {
+	FirewallRule
+	Group string // security group the rule came from; "" for the guest's own rules
+}
+
+// EffectiveGuestFirewall returns a guest's rules in evaluation order with
+// group rules expanded. A disabled group reference disables its rules too.
+func EffectiveGuestFirewall(ctx context.Context, c *proxmox.Client, s FirewallScope) ([]EffectiveFirewallRule, error) {
+	rules, err := FirewallRules(ctx, c, s)
+	if err != nil {
+		return nil, err
+	}
+	var out []EffectiveFirewallRule
+	for _, r := range rules {
+		if r.Type != "group" {
+			out = append(out, EffectiveFirewallRule{FirewallRule: r})
+			continue
+		}
+		out = append(out, EffectiveFirewallRule{FirewallRule: r})
+		groupRules, err := FirewallRules(ctx, c, FirewallScope{Group: r.Action})
+		if err != nil {
+			return nil, fmt.Errorf("security group %s: %w", r.Action, err)
+		}
+		for _, gr := range groupRules {
+			if r.Enable == 0 {
+				gr.Enable = 0
+			}
+			if r.Iface != "" && gr.Iface == "" {
+				gr.Iface = r.Iface
+			}
+			out = append(out, EffectiveFirewallRule{FirewallRule: gr, Group: r.Action})
+		}
+	}
+	return out, nil
+}
+
+// truthy interprets the API's loosely typed booleans (1, "1", true).
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		n, err := strconv.Atoi(x)
+		return err == nil && n != 0
+	}
+	return false
+}
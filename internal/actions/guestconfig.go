@@ -0,0 +1,563 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// GuestConfig is the raw configuration of a VM ("qemu") or container
+// ("lxc"): every key as the API returns it, rendered as strings. Digest
+// identifies the revision it was read at.
+type GuestConfig struct {
+	Type   string
+	VMID   int
+	Node   string
+	Digest string
+	Values map[string]string
+}
+
+// Noun returns "VM" or "container" for messages.
+func (g *GuestConfig) Noun() string {
+	if g.Type == "lxc" {
+		return "container"
+	}
+	return "VM"
+}
+
+func (g *GuestConfig) path() string {
+	return fmt.Sprintf("/nodes/%s/%s/%d/config", url.PathEscape(g.Node), g.Type, g.VMID)
+}
+
+// configString renders a decoded JSON config value the way Proxmox writes
+// it in the config file.
+func configString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		if t {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+// GetGuestConfig returns the current configuration of guest vmid of type typ
+// ("qemu" or "lxc"). If nodeName is empty, the guest's node is looked up.
+func GetGuestConfig(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName string) (*GuestConfig, error) {
+	nodeName, err := guestNode(ctx, c, typ, vmid, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	g := &GuestConfig{Type: typ, VMID: vmid, Node: nodeName, Values: map[string]string{}}
+	var raw map[string]interface{}
+	if err := c.Get(ctx, g.path(), &raw); err != nil {
+		return nil, err
+	}
+	for k, v := range raw {
+		if k == "digest" {
+			g.Digest = configString(v)
+			continue
+		}
+		g.Values[k] = configString(v)
+	}
+	return g, nil
+}
+
+// guestNode returns nodeName, or the node of guest vmid of type typ if
+// nodeName is empty.
+func guestNode(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName string) (string, error) {
+	if nodeName != "" {
+		return nodeName, nil
+	}
+	if typ == "lxc" {
+		ct, err := FindContainer(ctx, c, vmid, "")
+		if err != nil {
+			return "", err
+		}
+		return ct.Node, nil
+	}
+	vm, err := FindVM(ctx, c, vmid, "")
+	if err != nil {
+		return "", err
+	}
+	return vm.Node, nil
+}
+
+// UpdateGuestConfig sets the keys in set and removes the keys in del. When
+// g.Digest is set, Proxmox rejects the update if the configuration changed
+// since g was read (see IsConfigChanged). VM updates run as a task, which
+// is returned; container updates are synchronous and return a nil task.
+func UpdateGuestConfig(ctx context.Context, c *proxmox.Client, g *GuestConfig, set map[string]string, del []string) (*proxmox.Task, error) {
+	body := map[string]interface{}{}
+	for k, v := range set {
+		body[k] = v
+	}
+	if len(del) > 0 {
+		body["delete"] = strings.Join(del, ",")
+	}
+	if g.Digest != "" {
+		body["digest"] = g.Digest
+	}
+	if g.Type == "lxc" {
+		return nil, c.Put(ctx, g.path(), body, nil)
+	}
+	var upid proxmox.UPID
+	if err := c.Post(ctx, g.path(), body, &upid); err != nil {
+		return nil, err
+	}
+	if upid == "" {
+		return nil, nil
+	}
+	return proxmox.NewTask(upid, c), nil
+}
+
+// IsConfigChanged reports whether err is Proxmox rejecting an update because
+// the configuration digest no longer matches.
+func IsConfigChanged(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "file changed by other user")
+}
+
+// ConfigChange is one key changed by a configuration update. Old is empty
+// for added keys and New is empty for removed ones.
+type ConfigChange struct {
+	Key string `json:"key"`
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// DiffGuestConfig returns the changes setting set and removing del would
+// make to cur, sorted by key. Keys set to their current value and removed
+// keys that are not present are left out.
+func DiffGuestConfig(cur map[string]string, set map[string]string, del []string) []ConfigChange {
+	var out []ConfigChange
+	for k, v := range set {
+		if old, ok := cur[k]; !ok || old != v {
+			out = append(out, ConfigChange{Key: k, Old: cur[k], New: v})
+		}
+	}
+	for _, k := range del {
+		if old, ok := cur[k]; ok {
+			out = append(out, ConfigChange{Key: k, Old: old})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return ConfigKeyLess(out[i].Key, out[j].Key) })
+	return out
+}
+
+// ConfigKeyLess orders config keys by name with numbered slots in numeric
+// order (net2 before net10).
+func ConfigKeyLess(a, b string) bool {
+	pa, na := splitSlotKey(a)
+	pb, nb := splitSlotKey(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return na < nb
+}
+
+func splitSlotKey(k string) (string, int) {
+	i := len(k)
+	for i > 0 && k[i-1] >= '0' && k[i-1] <= '9' {
+		i--
+	}
+	if i == len(k) {
+		return k, -1
+	}
+	n, _ := strconv.Atoi(k[i:])
+	return k[:i], n
+}
+
+// FreeSlot returns the first key prefix0..prefix<max-1> not used in cfg,
+// e.g. FreeSlot(cfg, "net", 32) → "net1".
+func FreeSlot(cfg map[string]string, prefix string, max int) (string, error) {
+	for i := 0; i < max; i++ {
+		k := prefix + strconv.Itoa(i)
+		if _, used := cfg[k]; !used {
+			return k, nil
+		}
+	}
+	return "", fmt.Errorf("no free %s slot (all %d in use)", prefix, max)
+}
+
+// PendingChange is a configuration change of a running guest that takes
+// effect at its next restart. Value is the running value ("" if the key is
+// new) and Pending the new one; Delete is set when the key will be removed.
+type PendingChange struct {
+	Key     string `json:"key"`
+	Value   string `json:"value,omitempty"`
+	Pending string `json:"pending,omitempty"`
+	Delete  bool   `json:"delete,omitempty"`
+}
+
+// GuestPending returns the pending changes of g's guest sorted by key.
+func GuestPending(ctx context.Context, c *proxmox.Client, g *GuestConfig) ([]PendingChange, error) {
+	var raw []struct {
+		Key     string      `json:"key"`
+		Value   interface{} `json:"value"`
+		Pending interface{} `json:"pending"`
+		Delete  int         `json:"delete"`
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d/pending", url.PathEscape(g.Node), g.Type, g.VMID)
+	if err := c.Get(ctx, path, &raw); err != nil {
+		return nil, err
+	}
+	var out []PendingChange
+	for _, r := range raw {
+		if r.Key == "digest" || (r.Pending == nil && r.Delete == 0) {
+			continue
+		}
+		out = append(out, PendingChange{Key: r.Key, Value: configString(r.Value), Pending: configString(r.Pending), Delete: r.Delete > 0})
+	}
+	sort.Slice(out, func(i, j int) bool { return ConfigKeyLess(out[i].Key, out[j].Key) })
+	return out, nil
+}
+
+// RevertGuestPending drops the pending changes to keys, leaving the running
+// values in place. Like UpdateGuestConfig it carries g.Digest and returns
+// the task of a VM update.
+func RevertGuestPending(ctx context.Context, c *proxmox.Client, g *GuestConfig, keys []string) (*proxmox.Task, error) {
+	body := map[string]interface{}{"revert": strings.Join(keys, ",")}
+	if g.Digest != "" {
+		body["digest"] = g.Digest
+	}
+	if g.Type == "lxc" {
+		return nil, c.Put(ctx, g.path(), body, nil)
+	}
+	var upid proxmox.UPID
+	if err := c.Post(ctx, g.path(), body, &upid); err != nil {
+		return nil, err
+	}
+	if upid == "" {
+		return nil, nil
+	}
+	return proxmox.NewTask(upid, c), nil
+}
+
+// GuestStatus returns the run state ("running", "stopped", ...) of g's guest.
+func GuestStatus(ctx context.Context, c *proxmox.Client, g *GuestConfig) (string, error) {
+	var st struct {
+		Status string `json:"status"`
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d/status/current", url.PathEscape(g.Node), g.Type, g.VMID)
+	if err := c.Get(ctx, path, &st); err != nil {
+		return "", err
+	}
+	return st.Status, nil
+}
+
+// GuestsWithPending returns the VMIDs of the running guests in resources
+// that have pending changes. Stopped guests and templates apply changes
+// directly and are not queried; guests whose query fails are left out.
+func GuestsWithPending(ctx context.Context, c *proxmox.Client, resources proxmox.ClusterResources) map[uint64]bool {
+	const workers = 8
+	sem := make(chan struct{}, workers)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	out := map[uint64]bool{}
+	for _, r := range resources {
+		if r.Status != "running" || r.Template == 1 || (r.Type != "qemu" && r.Type != "lxc") {
+			continue
+		}
+		wg.Add(1)
+		go func(r *proxmox.ClusterResource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			pending, err := GuestPending(ctx, c, &GuestConfig{Type: r.Type, VMID: int(r.VMID), Node: r.Node})
+			if err != nil || len(pending) == 0 {
+				return
+			}
+			mu.Lock()
+			out[r.VMID] = true
+			mu.Unlock()
+		}(r)
+	}
+	wg.Wait()
+	return out
+}
+
+// ConfigFile is a guest configuration as written by "config export". Config
+// holds the values including pending changes; Pending lists the keys whose
+// value is not live yet.
+type ConfigFile struct {
+	Type    string                 `yaml:"type" json:"type"`
+	VMID    int                    `yaml:"vmid" json:"vmid"`
+	Node    string                 `yaml:"node,omitempty" json:"node,omitempty"`
+	Config  map[string]interface{} `yaml:"config" json:"config"`
+	Pending []string               `yaml:"pending,omitempty" json:"pending,omitempty"`
+}
+
+// unmanagedConfigKeys are maintained by Proxmox and never changed by an
+// import. Unused volumes are left alone too: deleting unusedN destroys the
+// volume.
+var unmanagedConfigKeys = map[string]bool{"lock": true, "meta": true, "parent": true, "digest": true}
+
+func isUnmanagedConfigKey(k string) bool {
+	p, _ := splitSlotKey(k)
+	return unmanagedConfigKeys[k] || p == "unused"
+}
+
+// guestIdentityKeys identify a VM to its guest OS and are never copied from
+// the config of another guest.
+var guestIdentityKeys = map[string]bool{"smbios1": true, "vmgenid": true}
+
+// isGuestOwnedKey reports whether k belongs to one guest and must not be
+// copied from the config of another: its identity, or a disk or mount point
+// whose volume is that guest's.
+func isGuestOwnedKey(k string) bool {
+	if guestIdentityKeys[k] || k == "rootfs" {
+		return true
+	}
+	switch p, n := splitSlotKey(k); p {
+	case "scsi", "virtio", "sata", "ide", "efidisk", "tpmstate", "mp":
+		return n >= 0
+	}
+	return false
+}
+
+// netMAC returns the MAC address in the NIC property string v, or "".
+func netMAC(v string) string {
+	for _, part := range ParsePropertyString(v) {
+		if _, err := net.ParseMAC(part.Value); err == nil && part.Key != "" {
+			return part.Value
+		}
+	}
+	return ""
+}
+
+// setNetMAC returns the NIC property string v of a guest of type typ with
+// its MAC address replaced by mac, or removed if mac is empty so that
+// Proxmox generates one. VM NICs carry the MAC as the model's value
+// ("virtio=BC:24:11:..."), container NICs as hwaddr.
+func setNetMAC(typ, v, mac string) string {
+	var out PropertyString
+	replaced := false
+	for _, part := range ParsePropertyString(v) {
+		if _, err := net.ParseMAC(part.Value); err == nil && part.Key != "" {
+			switch {
+			case mac != "":
+				part.Value = mac
+			case part.Key == "macaddr" || part.Key == "hwaddr":
+				continue
+			default:
+				part = PropertyPart{Value: part.Key} // bare model
+			}
+			replaced = true
+		}
+		out = append(out, part)
+	}
+	if !replaced && mac != "" {
+		if typ == "lxc" {
+			out = out.Set("hwaddr", mac)
+		} else {
+			out = out.Set("macaddr", mac)
+		}
+	}
+	return out.String()
+}
+
+// ExportGuestConfig returns g as a ConfigFile. Numeric values are written as
+// numbers so the file reads naturally.
+func ExportGuestConfig(ctx context.Context, c *proxmox.Client, g *GuestConfig) (*ConfigFile, error) {
+	f := &ConfigFile{Type: g.Type, VMID: g.VMID, Node: g.Node, Config: make(map[string]interface{}, len(g.Values))}
+	for k, v := range g.Values {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && strconv.FormatInt(n, 10) == v {
+			f.Config[k] = n
+		} else {
+			f.Config[k] = v
+		}
+	}
+	pending, err := GuestPending(ctx, c, g)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range pending {
+		f.Pending = append(f.Pending, p.Key)
+	}
+	return f, nil
+}
+
+// PlanConfigImport returns the keys to set and remove to make cur match the
+// config in f. Keys missing from f or left empty are removed; keys Proxmox
+// maintains itself and unused volumes are skipped. With fromOther, f is the
+// config of another guest: its identity, disk, and mount point keys are
+// skipped too, and NICs keep cur's MAC addresses (new NICs get new ones).
+func PlanConfigImport(cur *GuestConfig, f *ConfigFile, fromOther bool) (map[string]string, []string) {
+	skip := func(k string) bool {
+		return isUnmanagedConfigKey(k) || (fromOther && isGuestOwnedKey(k))
+	}
+	set := map[string]string{}
+	for k, v := range f.Config {
+		if v == nil || skip(k) {
+			continue
+		}
+		s := configString(v)
+		if p, n := splitSlotKey(k); fromOther && p == "net" && n >= 0 {
+			s = setNetMAC(cur.Type, s, netMAC(cur.Values[k]))
+		}
+		if cur.Values[k] != s {
+			set[k] = s
+		}
+	}
+	var del []string
+	for k := range cur.Values {
+		if f.Config[k] == nil && !skip(k) {
+			del = append(del, k)
+		}
+	}
+	sort.Slice(del, func(i, j int) bool { return ConfigKeyLess(del[i], del[j]) })
+	return set, del
+}
+
+// PropertyString is a Proxmox property string such as
+// "virtio=BC:24:11:00:00:01,bridge=vmbr0,firewall=1", kept in order.
+// Parts without "=" (e.g. a volume name) have an empty key.
+type PropertyString []PropertyPart
+
+// PropertyPart is one comma-separated part of a PropertyString.
+type PropertyPart struct {
+	Key, Value string
+}
+
+// ParsePropertyString splits s into its parts.
+func ParsePropertyString(s string) PropertyString {
+	var p PropertyString
+	if s == "" {
+		return p
+	}
+	for _, part := range strings.Split(s, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			p = append(p, PropertyPart{Key: k, Value: v})
+		} else {
+			p = append(p, PropertyPart{Value: part})
+		}
+	}
+	return p
+}
+
+// Get returns the value of key, or "" when absent.
+func (p PropertyString) Get(key string) string {
+	for _, part := range p {
+		if part.Key == key {
+			return part.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of key in place, or appends it.
+func (p PropertyString) Set(key, value string) PropertyString {
+	for i, part := range p {
+		if part.Key == key {
+			p[i].Value = value
+			return p
+		}
+	}
+	return append(p, PropertyPart{Key: key, Value: value})
+}
+
+// Del removes key.
+func (p PropertyString) Del(key string) PropertyString {
+	out := p[:0]
+	for _, part := range p {
+		if part.Key != key {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func (p PropertyString) String() string {
+	parts := make([]string, len(p))
+	for i, part := range p {
+		if part.Key == "" {
+			parts[i] = part.Value
+		} else {
+			parts[i] = part.Key + "=" + part.Value
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// PCIDevice is a PCI device of a node, for passthrough.
+type PCIDevice struct {
+	ID         string `json:"id"`
+	Class      string `json:"class"`
+	Vendor     string `json:"vendor_name"`
+	Device     string `json:"device_name"`
+	IOMMUGroup int    `json:"iommugroup"`
+	MDev       bool   `json:"mdev"`
+}
+
+// NodePCIDevices returns the PCI devices of nodeName sorted by ID.
+func NodePCIDevices(ctx context.Context, c *proxmox.Client, nodeName string) ([]PCIDevice, error) {
+	var raw []struct {
+		PCIDevice
+		MDev interface{} `json:"mdev"`
+	}
+	if err := c.Get(ctx, "/nodes/"+url.PathEscape(nodeName)+"/hardware/pci", &raw); err != nil {
+		return nil, err
+	}
+	out := make([]PCIDevice, len(raw))
+	for i, r := range raw {
+		out[i] = r.PCIDevice
+		out[i].MDev = truthy(r.MDev)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// USBDevice is a USB device plugged into a node.
+type USBDevice struct {
+	BusNum  int    `json:"busnum"`
+	DevNum  int    `json:"devnum"`
+	Port    string `json:"usbpath"`
+	VendID  string `json:"vendid"`
+	ProdID  string `json:"prodid"`
+	Vendor  string `json:"manufacturer"`
+	Product string `json:"product"`
+	Speed   string `json:"speed"`
+	Class   int    `json:"class"`
+}
+
+// HostID returns the vendor:product ID used to pass the device through.
+func (u USBDevice) HostID() string {
+	return strings.TrimPrefix(u.VendID, "0x") + ":" + strings.TrimPrefix(u.ProdID, "0x")
+}
+
+// NodeUSBDevices returns the USB devices of nodeName, hubs excluded.
+func NodeUSBDevices(ctx context.Context, c *proxmox.Client, nodeName string) ([]USBDevice, error) {
+	var raw []USBDevice
+	if err := c.Get(ctx, "/nodes/"+url.PathEscape(nodeName)+"/hardware/usb", &raw); err != nil {
+		return nil, err
+	}
+	var out []USBDevice
+	for _, u := range raw {
+		if u.Class == 9 { // hub
+			continue
+		}
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].BusNum != out[j].BusNum {
+			return out[i].BusNum < out[j].BusNum
+		}
+		return out[i].DevNum < out[j].DevNum
+	})
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/cache"
+)
+
+// GuestIPTimeout bounds the address lookup of a single guest, so that one
+// unresponsive guest agent doesn't hold up a whole list.
+const GuestIPTimeout = 3 * time.Second
+
+// guestIPMaxAge is how long looked-up addresses, including the absence of
+// one, are reused. Failed lookups are not cached.
+const guestIPMaxAge = 5 * time.Minute
+
+// guestIPParallel bounds how many guests are queried at once.
+const guestIPParallel = 16
+
+// virtualIfacePrefixes name interfaces that carry addresses internal to the
+// guest (container bridges and the like) rather than its own.
+var virtualIfacePrefixes = []string{"lo", "docker", "veth", "br-", "virbr", "cni", "flannel", "cali"}
+
+// guestIPEntry is the cached result of one guest's lookup; IP is empty if
+// the guest reported no address.
+type guestIPEntry struct {
+	IP string `json:"ip"`
+}
+
+// GuestIPs returns the primary IPv4 address of each running guest in
+// resources: from the guest agent for VMs, from the container's interfaces
+// for CTs. Guests are queried concurrently, each for at most GuestIPTimeout,
+// and results are cached for a few minutes per instance (instURL). VM facts
+// cached by "vm facts" are used too. Guests without a known address are left
+// out.
+func GuestIPs(ctx context.Context, c *proxmox.Client, instURL string, resources proxmox.ClusterResources) map[uint64]string {
+	dir, dirErr := cache.Dir(instURL, "ip")
+	factsDir, _ := cache.Dir(instURL, "facts")
+	sem := make(chan struct{}, guestIPParallel)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	out := map[uint64]string{}
+	for _, r := range resources {
+		if r.Status != "running" || r.Template == 1 || (r.Type != "qemu" && r.Type != "lxc") {
+			continue
+		}
+		wg.Add(1)
+		go func(r *proxmox.ClusterResource) {
+			defer wg.Done()
+			name := fmt.Sprintf("%d.json", r.VMID)
+			var e guestIPEntry
+			var f VMFacts
+			switch {
+			case dirErr == nil && cacheHit(filepath.Join(dir, name), &e):
+			case r.Type == "qemu" && factsDir != "" && cacheHit(filepath.Join(factsDir, name), &f) && f.VMID == r.VMID:
+				e.IP = f.PrimaryIPv4()
+			default:
+				sem <- struct{}{}
+				ip, err := lookupGuestIP(ctx, c, r)
+				<-sem
+				e.IP = ip
+				// Only real answers are cached: a slow or still booting agent
+				// is asked again next time.
+				if err == nil && dirErr == nil {
+					_ = cache.Save(filepath.Join(dir, name), e)
+				}
+			}
+			if e.IP != "" {
+				mu.Lock()
+				out[r.VMID] = e.IP
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return out
+}
+
+func cacheHit(path string, v interface{}) bool {
+	_, ok := cache.Load(path, guestIPMaxAge, v)
+	return ok
+}
+
+// lookupGuestIP asks the guest agent (VMs) or the container interfaces
+// (CTs) for r's primary IPv4 address, which is empty if it has none.
+func lookupGuestIP(ctx context.Context, c *proxmox.Client, r *proxmox.ClusterResource) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, GuestIPTimeout)
+	defer cancel()
+	var ifaces []ipIface
+	if r.Type == "lxc" {
+		var cts []struct {
+			Name string `json:"name"`
+			Inet string `json:"inet"`
+		}
+		path := fmt.Sprintf("/nodes/%s/lxc/%d/interfaces", url.PathEscape(r.Node), r.VMID)
+		if err := c.Get(ctx, path, &cts); err != nil {
+			return "", err
+		}
+		for _, ct := range cts {
+			ifaces = append(ifaces, ipIface{ct.Name, []string{ct.Inet}})
+		}
+	} else {
+		var resp struct {
+			Result []*proxmox.AgentNetworkIface `json:"result"`
+		}
+		path := fmt.Sprintf("/nodes/%s/qemu/%d/agent/network-get-interfaces", url.PathEscape(r.Node), r.VMID)
+		if err := c.Get(ctx, path, &resp); err != nil {
+			return "", err
+		}
+		ifaces = agentIPIfaces(resp.Result)
+	}
+	return primaryIPv4(ifaces), nil
+}
+
+// ipIface is an interface name and its addresses, bare or in CIDR notation.
+type ipIface struct {
+	name  string
+	addrs []string
+}
+
+func agentIPIfaces(ifaces []*proxmox.AgentNetworkIface) []ipIface {
+	var out []ipIface
+	for _, iface := range ifaces {
+		i := ipIface{name: iface.Name}
+		for _, a := range iface.IPAddresses {
+			if a.IPAddressType == "ipv4" {
+				i.addrs = append(i.addrs, a.IPAddress)
+			}
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+// PrimaryIPv4 returns the guest's primary IPv4 address, as the IP columns
+// of the lists show it, or "" if it has none.
+func (f *VMFacts) PrimaryIPv4() string {
+	var ifaces []ipIface
+	for _, iface := range f.Interfaces {
+		ifaces = append(ifaces, ipIface{iface.Name, iface.IPv4})
+	}
+	return primaryIPv4(ifaces)
+}
+
+// PrimaryAgentIPv4 picks the primary IPv4 address from the guest agent's
+// interface list; see GuestIPs.
+func PrimaryAgentIPv4(ifaces []*proxmox.AgentNetworkIface) string {
+	return primaryIPv4(agentIPIfaces(ifaces))
+}
+
+// primaryIPv4 returns the first global unicast IPv4 address, skipping
+// loopback, link-local, and the interfaces of virtual bridges inside the
+// guest, or "" if there is none.
+func primaryIPv4(ifaces []ipIface) string {
+next:
+	for _, iface := range ifaces {
+		for _, p := range virtualIfacePrefixes {
+			if strings.HasPrefix(iface.name, p) {
+				continue next
+			}
+		}
+		for _, a := range iface.addrs {
+			a, _, _ = strings.Cut(a, "/")
+			if ip := net.ParseIP(a); ip != nil && ip.To4() != nil && ip.IsGlobalUnicast() {
+				return a
+			}
+		}
+	}
+	return ""
+}
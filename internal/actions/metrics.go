@@ -0,0 +1,130 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// MetricTimeframes lists the RRD timeframes accepted by Proxmox.
+var MetricTimeframes = []string{"hour", "day", "week", "month", "year"}
+
+// MetricConsolidations lists the RRD consolidation functions accepted by Proxmox.
+var MetricConsolidations = []string{"AVERAGE", "MAX"}
+
+// MetricPoint is a single RRD sample for a node or guest. Values the RRD has
+// no data for (e.g. while a guest was stopped) are nil.
+type MetricPoint struct {
+	Time      int64    `json:"time"`
+	CPU       *float64 `json:"cpu"`       // fraction of allocated CPUs, 0..1
+	MemUsed   *float64 `json:"mem_used"`  // bytes
+	MemTotal  *float64 `json:"mem_total"` // bytes
+	NetIn     *float64 `json:"net_in"`    // bytes/s
+	NetOut    *float64 `json:"net_out"`   // bytes/s
+	DiskRead  *float64 `json:"disk_read,omitempty"`
+	DiskWrite *float64 `json:"disk_write,omitempty"`
+	IOWait    *float64 `json:"io_wait,omitempty"`  // nodes only, fraction 0..1
+	LoadAvg   *float64 `json:"load_avg,omitempty"` // nodes only
+}
+
+// ValidateMetricParams checks timeframe and cf against the values Proxmox
+// accepts and returns cf upper-cased.
+func ValidateMetricParams(timeframe, cf string) (string, error) {
+	if !containsString(MetricTimeframes, timeframe) {
+		return "", fmt.Errorf("invalid timeframe %q (must be one of: %s)", timeframe, strings.Join(MetricTimeframes, ", "))
+	}
+	cf = strings.ToUpper(cf)
+	if !containsString(MetricConsolidations, cf) {
+		return "", fmt.Errorf("invalid consolidation function %q (must be AVERAGE or MAX)", cf)
+	}
+	return cf, nil
+}
+
+// NodeMetrics returns RRD history for a node.
+func NodeMetrics(ctx context.Context, c *proxmox.Client, nodeName, timeframe, cf string) ([]MetricPoint, error) {
+	return fetchRRD(ctx, c, fmt.Sprintf("/nodes/%s/rrddata", nodeName), timeframe, cf)
+}
+
+// VMMetrics returns RRD history for a VM.
+func VMMetrics(ctx context.Context, c *proxmox.Client, vmid int, nodeName, timeframe, cf string) ([]MetricPoint, error) {
+	vm, err := FindVM(ctx, c, vmid, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return fetchRRD(ctx, c, fmt.Sprintf("/nodes/%s/qemu/%d/rrddata", vm.Node, vmid), timeframe, cf)
+}
+
+// ContainerMetrics returns RRD history for a container. The API is called
+// directly because go-proxmox builds a wrong path for container RRD data.
+func ContainerMetrics(ctx context.Context, c *proxmox.Client, ctid int, nodeName, timeframe, cf string) ([]MetricPoint, error) {
+	ct, err := FindContainer(ctx, c, ctid, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return fetchRRD(ctx, c, fmt.Sprintf("/nodes/%s/lxc/%d/rrddata", ct.Node, ctid), timeframe, cf)
+}
+
+func fetchRRD(ctx context.Context, c *proxmox.Client, path, timeframe, cf string) ([]MetricPoint, error) {
+	params := url.Values{}
+	params.Set("timeframe", timeframe)
+	if cf != "" {
+		params.Set("cf", cf)
+	}
+	var raw []map[string]interface{}
+	if err := c.Get(ctx, path+"?"+params.Encode(), &raw); err != nil {
+		return nil, err
+	}
+
+	points := make([]MetricPoint, 0, len(raw))
+	for _, r := range raw {
+		t := rrdValue(r, "time")
+		if t == nil {
+			continue
+		}
+		p := MetricPoint{
+			Time:      int64(*t),
+			CPU:       rrdValue(r, "cpu"),
+			NetIn:     rrdValue(r, "netin"),
+			NetOut:    rrdValue(r, "netout"),
+			DiskRead:  rrdValue(r, "diskread"),
+			DiskWrite: rrdValue(r, "diskwrite"),
+			IOWait:    rrdValue(r, "iowait"),
+			LoadAvg:   rrdValue(r, "loadavg"),
+		}
+		// Nodes report memused/memtotal, guests mem/maxmem.
+		if p.MemUsed = rrdValue(r, "memused"); p.MemUsed == nil {
+			p.MemUsed = rrdValue(r, "mem")
+		}
+		if p.MemTotal = rrdValue(r, "memtotal"); p.MemTotal == nil {
+			p.MemTotal = rrdValue(r, "maxmem")
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// rrdValue returns the numeric value of key, or nil when the RRD has no data.
+func rrdValue(r map[string]interface{}, key string) *float64 {
+	switch v := r[key].(type) {
+	case float64:
+		return &v
+	case string:
+		var f float64
+		if _, err := fmt.Sscan(v, &f); err == nil {
+			return &f
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/client"
+)
+
+// NetworkTypes are the interface types accepted when creating a node
+// network interface.
+var NetworkTypes = []string{"bridge", "bond", "eth", "alias", "vlan", "OVSBridge", "OVSBond", "OVSPort", "OVSIntPort"}
+
+// NodeInterface is a node network interface as configured, including
+// changes that have not been applied yet. Fields holds every attribute the
+// API returned; the named fields are the common ones, for tables.
+type NodeInterface struct {
+	Iface       string
+	Type        string
+	Active      bool
+	Autostart   bool
+	Method      string
+	CIDR        string
+	Gateway     string
+	CIDR6       string
+	BridgePorts string
+	Slaves      string
+	Comments    string
+	Fields      map[string]interface{}
+}
+
+// MarshalJSON emits the interface exactly as the API returned it.
+func (n NodeInterface) MarshalJSON() ([]byte, error) {
+	return json.Marshal(n.Fields)
+}
+
+func newNodeInterface(raw map[string]interface{}) NodeInterface {
+	str := func(k string) string {
+		if v, ok := raw[k]; ok && v != nil {
+			return strings.TrimSpace(fmt.Sprint(v))
+		}
+		return ""
+	}
+	return NodeInterface{
+		Iface:       str("iface"),
+		Type:        str("type"),
+		Active:      truthy(raw["active"]),
+		Autostart:   truthy(raw["autostart"]),
+		Method:      str("method"),
+		CIDR:        str("cidr"),
+		Gateway:     str("gateway"),
+		CIDR6:       str("cidr6"),
+		BridgePorts: str("bridge_ports"),
+		Slaves:      str("slaves"),
+		Comments:    str("comments"),
+		Fields:      raw,
+	}
+}
+
+// NodeInterfaces returns the network interfaces of nodeName sorted by name,
+// optionally limited to one type (e.g. "bridge"; "any_bridge" includes OVS
+// bridges).
+func NodeInterfaces(ctx context.Context, c *proxmox.Client, nodeName, typ string) ([]NodeInterface, error) {
+	path := "/nodes/" + url.PathEscape(nodeName) + "/network"
+	if typ != "" {
+		path += "?type=" + url.QueryEscape(typ)
+	}
+	var raw []map[string]interface{}
+	if err := c.Get(ctx, path, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]NodeInterface, len(raw))
+	for i, r := range raw {
+		out[i] = newNodeInterface(r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Iface < out[j].Iface })
+	return out, nil
+}
+
+// GetNodeInterface returns one network interface of nodeName.
+func GetNodeInterface(ctx context.Context, c *proxmox.Client, nodeName, iface string) (NodeInterface, error) {
+	var raw map[string]interface{}
+	if err := c.Get(ctx, "/nodes/"+url.PathEscape(nodeName)+"/network/"+url.PathEscape(iface), &raw); err != nil {
+		return NodeInterface{}, err
+	}
+	if raw["iface"] == nil {
+		raw["iface"] = iface
+	}
+	return newNodeInterface(raw), nil
+}
+
+// CreateNodeInterface adds interface iface of type typ to the pending
+// network configuration of nodeName. params holds the other API parameters
+// (cidr, bridge_ports, ...).
+func CreateNodeInterface(ctx context.Context, c *proxmox.Client, nodeName, iface, typ string, params map[string]string) error {
+	body := map[string]interface{}{"iface": iface, "type": typ}
+	for k, v := range params {
+		body[k] = v
+	}
+	return c.Post(ctx, "/nodes/"+url.PathEscape(nodeName)+"/network", body, nil)
+}
+
+// UpdateNodeInterface changes interface iface in the pending network
+// configuration. Keys in unset are removed. The API requires the interface
+// type, so it is looked up first.
+func UpdateNodeInterface(ctx context.Context, c *proxmox.Client, nodeName, iface string, params map[string]string, unset []string) error {
+	cur, err := GetNodeInterface(ctx, c, nodeName, iface)
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{"type": cur.Type}
+	for k, v := range params {
+		body[k] = v
+	}
+	if len(unset) > 0 {
+		body["delete"] = strings.Join(unset, ",")
+	}
+	return c.Put(ctx, "/nodes/"+url.PathEscape(nodeName)+"/network/"+url.PathEscape(iface), body, nil)
+}
+
+// DeleteNodeInterface removes interface iface from the pending network
+// configuration.
+func DeleteNodeInterface(ctx context.Context, c *proxmox.Client, nodeName, iface string) error {
+	return c.Delete(ctx, "/nodes/"+url.PathEscape(nodeName)+"/network/"+url.PathEscape(iface), nil)
+}
+
+// NodeNetworkChanges returns the pending changes to nodeName's network
+// configuration as a unified diff of /etc/network/interfaces, or "" when
+// there are none.
+func NodeNetworkChanges(ctx context.Context, c *proxmox.Client, nodeName string) (string, error) {
+	// The diff is a response attribute next to "data", which go-proxmox
+	// drops, so read the raw response.
+	var raw []byte
+	if err := c.Get(client.WithRawResponse(ctx, &raw), "/nodes/"+url.PathEscape(nodeName)+"/network", nil); err != nil {
+		return "", err
+	}
+	var resp struct {
+		Changes string `json:"changes"`
+	}
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return "", fmt.Errorf("decoding network configuration: %w", err)
+	}
+	return resp.Changes, nil
+}
+
+// ApplyNodeNetwork applies the pending network configuration of nodeName
+// (ifreload) and returns the task.
+func ApplyNodeNetwork(ctx context.Context, c *proxmox.Client, nodeName string) (*proxmox.Task, error) {
+	node, err := c.Node(ctx, nodeName)
+	if err != nil {
+		return nil, err
+	}
+	return node.NetworkReload(ctx)
+}
+
+// RevertNodeNetwork discards the pending network configuration of nodeName.
+func RevertNodeNetwork(ctx context.Context, c *proxmox.Client, nodeName string) error {
+	return c.Delete(ctx, "/nodes/"+url.PathEscape(nodeName)+"/network", nil)
+}
@@ -0,0 +1,253 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// SDNKind describes one kind of SDN object: its collection path below
+// /cluster/sdn and the attribute holding its ID.
+type SDNKind struct {
+	Name  string // "zone", "vnet", "subnet", "controller"
+	Path  string // collection path; subnets live below their vnet
+	IDKey string
+}
+
+// SDN object kinds.
+var (
+	SDNZones       = SDNKind{Name: "zone", Path: "zones", IDKey: "zone"}
+	SDNVNets       = SDNKind{Name: "vnet", Path: "vnets", IDKey: "vnet"}
+	SDNSubnets     = SDNKind{Name: "subnet", Path: "subnets", IDKey: "subnet"}
+	SDNControllers = SDNKind{Name: "controller", Path: "controllers", IDKey: "controller"}
+)
+
+// SDNZoneTypes, SDNControllerTypes are the plugin types accepted on create.
+var (
+	SDNZoneTypes       = []string{"simple", "vlan", "qinq", "vxlan", "evpn"}
+	SDNControllerTypes = []string{"evpn", "bgp", "isis"}
+)
+
+// collection returns the API path of the kind's collection. parent is the
+// vnet for subnets and ignored otherwise.
+func (k SDNKind) collection(parent string) string {
+	if k == SDNSubnets {
+		return "/cluster/sdn/vnets/" + url.PathEscape(parent) + "/subnets"
+	}
+	return "/cluster/sdn/" + k.Path
+}
+
+// SDNObject is an SDN zone, vnet, subnet or controller. Fields holds every
+// attribute with pending (not yet applied) values merged in; State is "new",
+// "changed" or "deleted" while a change is pending and "" otherwise.
+type SDNObject struct {
+	ID     string
+	Type   string
+	State  string
+	Fields map[string]interface{}
+}
+
+// MarshalJSON emits the object's attributes plus its pending state.
+func (o SDNObject) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(o.Fields)+1)
+	for k, v := range o.Fields {
+		out[k] = v
+	}
+	if o.State != "" {
+		out["state"] = o.State
+	}
+	return json.Marshal(out)
+}
+
+// Str returns attribute key as a string, or "" when unset.
+func (o SDNObject) Str(key string) string {
+	if v, ok := o.Fields[key]; ok && v != nil {
+		return strings.TrimSpace(fmt.Sprint(v))
+	}
+	return ""
+}
+
+func newSDNObject(kind SDNKind, raw map[string]interface{}) SDNObject {
+	fields := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		if k != "pending" && k != "state" && k != "digest" {
+			fields[k] = v
+		}
+	}
+	// With pending=1 the API reports changed attributes separately, and
+	// removed ones with the value "deleted"; show what apply will produce.
+	if p, ok := raw["pending"].(map[string]interface{}); ok {
+		for k, v := range p {
+			if v == "deleted" {
+				delete(fields, k)
+			} else {
+				fields[k] = v
+			}
+		}
+	}
+	o := SDNObject{Fields: fields}
+	o.ID = o.Str(kind.IDKey)
+	o.Type = o.Str("type")
+	if s, ok := raw["state"].(string); ok {
+		o.State = s
+	}
+	return o
+}
+
+// SDNObjects returns the objects of kind sorted by ID, including pending
+// changes. parent is the vnet for subnets.
+func SDNObjects(ctx context.Context, c *proxmox.Client, kind SDNKind, parent string) ([]SDNObject, error) {
+	var raw []map[string]interface{}
+	if err := c.Get(ctx, kind.collection(parent)+"?pending=1", &raw); err != nil {
+		return nil, err
+	}
+	out := make([]SDNObject, len(raw))
+	for i, r := range raw {
+		out[i] = newSDNObject(kind, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// GetSDNObject returns one object of kind, including pending changes.
+func GetSDNObject(ctx context.Context, c *proxmox.Client, kind SDNKind, parent, id string) (SDNObject, error) {
+	var raw map[string]interface{}
+	if err := c.Get(ctx, kind.collection(parent)+"/"+url.PathEscape(id)+"?pending=1", &raw); err != nil {
+		return SDNObject{}, err
+	}
+	if raw[kind.IDKey] == nil {
+		raw[kind.IDKey] = id
+	}
+	return newSDNObject(kind, raw), nil
+}
+
+// CreateSDNObject adds object id of kind to the pending SDN configuration.
+// params holds the other API parameters (type, zone, tag, ...).
+func CreateSDNObject(ctx context.Context, c *proxmox.Client, kind SDNKind, parent, id string, params map[string]string) error {
+	body := map[string]interface{}{kind.IDKey: id}
+	if kind == SDNSubnets {
+		body["type"] = "subnet"
+	}
+	for k, v := range params {
+		body[k] = v
+	}
+	return c.Post(ctx, kind.collection(parent), body, nil)
+}
+
+// UpdateSDNObject changes object id of kind in the pending SDN
+// configuration. Keys in unset are removed.
+func UpdateSDNObject(ctx context.Context, c *proxmox.Client, kind SDNKind, parent, id string, params map[string]string, unset []string) error {
+	body := map[string]interface{}{}
+	for k, v := range params {
+		body[k] = v
+	}
+	if len(unset) > 0 {
+		body["delete"] = strings.Join(unset, ",")
+	}
+	return c.Put(ctx, kind.collection(parent)+"/"+url.PathEscape(id), body, nil)
+}
+
+// DeleteSDNObject removes object id of kind from the pending SDN
+// configuration.
+func DeleteSDNObject(ctx context.Context, c *proxmox.Client, kind SDNKind, parent, id string) error {
+	return c.Delete(ctx, kind.collection(parent)+"/"+url.PathEscape(id), nil)
+}
+
+// ResolveSDNSubnet returns the ID of the subnet of vnet matching ref, which
+// may be the subnet ID (e.g. "zone1-10.0.0.0-24") or its CIDR.
+func ResolveSDNSubnet(ctx context.Context, c *proxmox.Client, vnet, ref string) (string, error) {
+	subnets, err := SDNObjects(ctx, c, SDNSubnets, vnet)
+	if err != nil {
+		return "", err
+	}
+	for _, s := range subnets {
+		if s.ID == ref || s.Str("cidr") == ref {
+			return s.ID, nil
+		}
+	}
+	return "", fmt.Errorf("subnet %s not found in vnet %s", ref, vnet)
+}
+
+// ApplySDN applies the pending SDN configuration on all nodes and returns
+// the task.
+func ApplySDN(ctx context.Context, c *proxmox.Client) (*proxmox.Task, error) {
+	cl, err := c.Cluster(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cl.SDNApply(ctx)
+}
+
+// SDNZoneStatus is the state of one SDN zone on one node.
+type SDNZoneStatus struct {
+	Node   string `json:"node"`
+	Zone   string `json:"zone"`
+	Status string `json:"status"` // "available", "pending", "error", ...
+}
+
+// SDNStatus returns the state of every SDN zone on every node, sorted by
+// node and zone.
+func SDNStatus(ctx context.Context, c *proxmox.Client) ([]SDNZoneStatus, error) {
+	var raw []struct {
+		Node   string `json:"node"`
+		SDN    string `json:"sdn"`
+		Status string `json:"status"`
+	}
+	if err := c.Get(ctx, "/cluster/resources?type=sdn", &raw); err != nil {
+		return nil, err
+	}
+	out := make([]SDNZoneStatus, len(raw))
+	for i, r := range raw {
+		out[i] = SDNZoneStatus{Node: r.Node, Zone: r.SDN, Status: r.Status}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Node != out[j].Node {
+			return out[i].Node < out[j].Node
+		}
+		return out[i].Zone < out[j].Zone
+	})
+	return out, nil
+}
+
+// BridgeChoice is a network a guest NIC can be attached to: a bridge on the
+// guest's node or an SDN vnet (Zone set). VLANAware reports whether guests
+// may tag their own traffic; it is always true for plain bridges.
+type BridgeChoice struct {
+	Name      string
+	Zone      string
+	Comment   string
+	VLANAware bool
+}
+
+// BridgeChoices returns the bridges of nodeName followed by the SDN vnets.
+// SDN is optional, so failing to list vnets is not an error.
+func BridgeChoices(ctx context.Context, c *proxmox.Client, nodeName string) ([]BridgeChoice, error) {
+	ifaces, err := NodeInterfaces(ctx, c, nodeName, "any_bridge")
+	if err != nil {
+		return nil, err
+	}
+	var out []BridgeChoice
+	for _, i := range ifaces {
+		out = append(out, BridgeChoice{Name: i.Iface, Comment: i.Comments, VLANAware: true})
+	}
+	vnets, err := SDNObjects(ctx, c, SDNVNets, "")
+	if err != nil {
+		return out, nil
+	}
+	for _, v := range vnets {
+		if v.State == "new" || v.State == "deleted" {
+			continue // not usable until applied
+		}
+		comment := v.Str("alias")
+		if tag := v.Str("tag"); tag != "" {
+			comment = strings.TrimSpace("tag " + tag + "  " + comment)
+		}
+		out = append(out, BridgeChoice{Name: v.ID, Zone: v.Str("zone"), Comment: comment, VLANAware: v.Str("vlanaware") == "1"})
+	}
+	return out, nil
+}
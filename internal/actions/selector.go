@@ -0,0 +1,151 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// SelectorKeys are the keys a selector term can match on.
+var SelectorKeys = []string{"tag", "name", "node", "pool", "status", "type", "vmid"}
+
+// Selector picks guests from the cluster resource list. It is parsed from a
+// comma-separated list of key=value (or key!=value) terms, all of which must
+// hold, e.g. "tag=web,node=pve1,name=web-*":
+//
+//	tag     the guest has this tag
+//	name    the name matches this glob
+//	node    the guest is on this node
+//	pool    the guest is in this resource pool
+//	status  running, stopped, ...
+//	type    vm or ct (qemu and lxc work too)
+//	vmid    a VMID or an inclusive range such as 100-199
+type Selector []selectorTerm
+
+type selectorTerm struct {
+	key, value string
+	negate     bool
+}
+
+// ParseSelector parses a selector expression; see Selector.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid selector term %q: want key=value", part)
+		}
+		t := selectorTerm{key: strings.TrimSpace(k), value: strings.TrimSpace(v)}
+		if strings.HasSuffix(t.key, "!") {
+			t.key, t.negate = strings.TrimSpace(strings.TrimSuffix(t.key, "!")), true
+		}
+		switch t.key {
+		case "tag", "name", "node", "pool", "status":
+		case "type":
+			switch t.value {
+			case "vm":
+				t.value = "qemu"
+			case "ct":
+				t.value = "lxc"
+			case "qemu", "lxc":
+			default:
+				return nil, fmt.Errorf("invalid selector type %q: want vm or ct", t.value)
+			}
+		case "vmid":
+			if _, _, err := parseVMIDRange(t.value); err != nil {
+				return nil, err
+			}
+		case "":
+			return nil, fmt.Errorf("invalid selector term %q: missing key", part)
+		default:
+			return nil, fmt.Errorf("unknown selector key %q (valid: %s)", t.key, strings.Join(SelectorKeys, ", "))
+		}
+		if _, err := path.Match(t.value, ""); t.key == "name" && err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", t.value, err)
+		}
+		sel = append(sel, t)
+	}
+	if len(sel) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	return sel, nil
+}
+
+// Match reports whether r is a guest that satisfies every term of s.
+func (s Selector) Match(r *proxmox.ClusterResource) bool {
+	if r.Type != "qemu" && r.Type != "lxc" {
+		return false
+	}
+	for _, t := range s {
+		if t.match(r) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t selectorTerm) match(r *proxmox.ClusterResource) bool {
+	switch t.key {
+	case "tag":
+		for _, tag := range strings.Split(r.Tags, ";") {
+			if strings.TrimSpace(tag) == t.value {
+				return true
+			}
+		}
+		return false
+	case "name":
+		ok, _ := path.Match(t.value, r.Name)
+		return ok
+	case "node":
+		return r.Node == t.value
+	case "pool":
+		return r.Pool == t.value
+	case "status":
+		return r.Status == t.value
+	case "type":
+		return r.Type == t.value
+	case "vmid":
+		lo, hi, _ := parseVMIDRange(t.value)
+		return r.VMID >= lo && r.VMID <= hi
+	}
+	return false
+}
+
+func parseVMIDRange(s string) (lo, hi uint64, err error) {
+	a, b, isRange := strings.Cut(s, "-")
+	if lo, err = strconv.ParseUint(a, 10, 32); err != nil {
+		return 0, 0, fmt.Errorf("invalid selector vmid %q", s)
+	}
+	if !isRange {
+		return lo, lo, nil
+	}
+	if hi, err = strconv.ParseUint(b, 10, 32); err != nil || hi < lo {
+		return 0, 0, fmt.Errorf("invalid selector vmid range %q", s)
+	}
+	return lo, hi, nil
+}
+
+// SelectGuests returns the VMs and containers matching sel, sorted by VMID.
+func SelectGuests(ctx context.Context, c *proxmox.Client, sel Selector) (proxmox.ClusterResources, error) {
+	resources, err := ClusterResources(ctx, c, "vm")
+	if err != nil {
+		return nil, err
+	}
+	var out proxmox.ClusterResources
+	for _, r := range resources {
+		if sel.Match(r) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].VMID < out[j].VMID })
+	return out, nil
+}
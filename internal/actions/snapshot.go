@@ -0,0 +1,372 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// CurrentSnapshot is the name of the pseudo-snapshot that stands for the
+// guest's running state; its parent is the snapshot the guest was last
+// taken or rolled back from.
+const CurrentSnapshot = "current"
+
+// GuestSnapshot is a snapshot of a VM or container. The list of a guest's
+// snapshots includes CurrentSnapshot, so that the tree shows where the
+// running state sits.
+type GuestSnapshot struct {
+	Name        string `json:"name"`
+	Parent      string `json:"parent,omitempty"`
+	Description string `json:"description,omitempty"`
+	Snaptime    int64  `json:"snaptime,omitempty"`
+	VMState     bool   `json:"vmstate,omitempty"` // includes RAM (VMs only)
+}
+
+// IsCurrent reports whether s is the running state rather than a snapshot.
+func (s *GuestSnapshot) IsCurrent() bool {
+	return s.Name == CurrentSnapshot
+}
+
+// SnapshotOptions are the optional settings of a new snapshot.
+type SnapshotOptions struct {
+	Description string
+	VMState     bool // save the RAM of a running VM too
+}
+
+// SnapshotTreeEntry is a snapshot with the tree drawing that goes before
+// its name, e.g. "│  └─ ".
+type SnapshotTreeEntry struct {
+	*GuestSnapshot
+	Prefix string
+}
+
+// guestSnapshots lists the snapshots of guest vmid of type typ ("qemu" or
+// "lxc") on nodeName, oldest first.
+func guestSnapshots(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName string) ([]*GuestSnapshot, error) {
+	var raw []struct {
+		Name        string `json:"name"`
+		Parent      string `json:"parent"`
+		Description string `json:"description"`
+		Snaptime    int64  `json:"snaptime"`
+		VMState     int    `json:"vmstate"`
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d/snapshot", url.PathEscape(nodeName), typ, vmid)
+	if err := c.Get(ctx, path, &raw); err != nil {
+		return nil, err
+	}
+	out := make([]*GuestSnapshot, len(raw))
+	for i, r := range raw {
+		out[i] = &GuestSnapshot{Name: r.Name, Parent: r.Parent, Description: r.Description, Snaptime: r.Snaptime, VMState: r.VMState == 1}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return snapshotLess(out[i], out[j]) })
+	return out, nil
+}
+
+// snapshotLess orders snapshots oldest first, with CurrentSnapshot last.
+func snapshotLess(a, b *GuestSnapshot) bool {
+	if a.IsCurrent() || b.IsCurrent() {
+		return b.IsCurrent() && !a.IsCurrent()
+	}
+	if a.Snaptime != b.Snaptime {
+		return a.Snaptime < b.Snaptime
+	}
+	return a.Name < b.Name
+}
+
+// createGuestSnapshot takes a snapshot of guest vmid of type typ.
+func createGuestSnapshot(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName, name string, opts SnapshotOptions) (*proxmox.Task, error) {
+	body := map[string]interface{}{"snapname": name}
+	if opts.Description != "" {
+		body["description"] = opts.Description
+	}
+	if opts.VMState {
+		if typ == "lxc" {
+			return nil, fmt.Errorf("containers cannot save RAM state in a snapshot")
+		}
+		body["vmstate"] = 1
+	}
+	var upid proxmox.UPID
+	path := fmt.Sprintf("/nodes/%s/%s/%d/snapshot", url.PathEscape(nodeName), typ, vmid)
+	if err := c.Post(ctx, path, body, &upid); err != nil {
+		return nil, err
+	}
+	return proxmox.NewTask(upid, c), nil
+}
+
+// SetGuestSnapshotDescription replaces the description of snapshot name of
+// guest vmid of type typ ("qemu" or "lxc"). If nodeName is empty, the
+// guest's node is looked up.
+func SetGuestSnapshotDescription(ctx context.Context, c *proxmox.Client, typ string, vmid int, nodeName, name, description string) error {
+	if name == CurrentSnapshot {
+		return fmt.Errorf("%q is the running state, not a snapshot", name)
+	}
+	nodeName, err := guestNode(ctx, c, typ, vmid, nodeName)
+	if err != nil {
+		return err
+	}
+	path := fmt.Sprintf("/nodes/%s/%s/%d/snapshot/%s/config", url.PathEscape(nodeName), typ, vmid, url.PathEscape(name))
+	return c.Put(ctx, path, map[string]string{"description": description}, nil)
+}
+
+// SnapshotTree orders snaps as a tree, each snapshot followed by its
+// children, oldest first. Snapshots whose parent is missing become roots.
+func SnapshotTree(snaps []*GuestSnapshot) []SnapshotTreeEntry {
+	byName := map[string]bool{}
+	for _, s := range snaps {
+		byName[s.Name] = true
+	}
+	children := map[string][]*GuestSnapshot{}
+	var roots []*GuestSnapshot
+	for _, s := range snaps {
+		if s.Parent == "" || !byName[s.Parent] || s.Parent == s.Name {
+			roots = append(roots, s)
+		} else {
+			children[s.Parent] = append(children[s.Parent], s)
+		}
+	}
+	sortSnaps := func(l []*GuestSnapshot) {
+		sort.SliceStable(l, func(i, j int) bool { return snapshotLess(l[i], l[j]) })
+	}
+	sortSnaps(roots)
+
+	var out []SnapshotTreeEntry
+	seen := map[string]bool{}
+	var walk func(s *GuestSnapshot, indent, branch string)
+	walk = func(s *GuestSnapshot, indent, branch string) {
+		if seen[s.Name] {
+			return
+		}
+		seen[s.Name] = true
+		out = append(out, SnapshotTreeEntry{GuestSnapshot: s, Prefix: indent + branch})
+		switch branch {
+		case "├─ ":
+			indent += "│  "
+		case "└─ ":
+			indent += "   "
+		}
+		kids := children[s.Name]
+		sortSnaps(kids)
+		for i, k := range kids {
+			b := "├─ "
+			if i == len(kids)-1 {
+				b = "└─ "
+			}
+			walk(k, indent, b)
+		}
+	}
+	for _, r := range roots {
+		walk(r, "", "")
+	}
+	return out
+}
+
+// rotateTimeFormat is the timestamp in the names of rotated snapshots, in
+// UTC so that names and buckets don't shift with daylight saving time.
+const rotateTimeFormat = "20060102-150405"
+
+// maxSnapshotName is the longest snapshot name Proxmox accepts.
+const maxSnapshotName = 40
+
+var snapshotPrefixRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+
+// RotationPolicy says which automatic snapshots "snapshot rotate" keeps: the
+// newest of each of the last KeepHourly hours and KeepDaily days (UTC) that
+// have one. Only snapshots named Prefix-YYYYMMDD-HHMMSS are automatic.
+type RotationPolicy struct {
+	Prefix     string
+	KeepHourly int
+	KeepDaily  int
+}
+
+// Validate checks that the prefix makes valid snapshot names and that the
+// policy keeps something.
+func (p RotationPolicy) Validate() error {
+	if !snapshotPrefixRe.MatchString(p.Prefix) {
+		return fmt.Errorf("invalid prefix %q: must start with a letter and contain only letters, digits, '-' and '_'", p.Prefix)
+	}
+	if n := len(p.Prefix) + 1 + len(rotateTimeFormat); n > maxSnapshotName {
+		return fmt.Errorf("prefix %q is too long: snapshot names are limited to %d characters", p.Prefix, maxSnapshotName)
+	}
+	if p.KeepHourly < 0 || p.KeepDaily < 0 {
+		return fmt.Errorf("keep counts cannot be negative")
+	}
+	if p.KeepHourly == 0 && p.KeepDaily == 0 {
+		return fmt.Errorf("the policy keeps no snapshots; set --keep-hourly and/or --keep-daily")
+	}
+	return nil
+}
+
+// SnapshotName returns the name of an automatic snapshot taken at t.
+func (p RotationPolicy) SnapshotName(t time.Time) string {
+	return p.Prefix + "-" + t.UTC().Format(rotateTimeFormat)
+}
+
+// snapshotTime returns the time in the name of an automatic snapshot, and
+// false for any other name.
+func (p RotationPolicy) snapshotTime(name string) (time.Time, bool) {
+	ts, ok := strings.CutPrefix(name, p.Prefix+"-")
+	if !ok || len(ts) != len(rotateTimeFormat) {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(rotateTimeFormat, ts)
+	return t, err == nil
+}
+
+// Prune splits the automatic snapshots in names into those the policy keeps
+// and those it deletes, both oldest first. Other names are left out of both.
+func (p RotationPolicy) Prune(names []string) (keep, prune []string) {
+	type auto struct {
+		name string
+		t    time.Time
+	}
+	var autos []auto
+	for _, n := range names {
+		if t, ok := p.snapshotTime(n); ok {
+			autos = append(autos, auto{n, t})
+		}
+	}
+	sort.Slice(autos, func(i, j int) bool { return autos[i].t.After(autos[j].t) })
+
+	kept := map[string]bool{}
+	bucket := func(n int, layout string) {
+		seen := map[string]bool{}
+		for _, a := range autos {
+			if len(seen) == n {
+				return
+			}
+			if b := a.t.Format(layout); !seen[b] {
+				seen[b] = true
+				kept[a.name] = true
+			}
+		}
+	}
+	bucket(p.KeepHourly, "2006010215")
+	bucket(p.KeepDaily, "20060102")
+
+	for i := len(autos) - 1; i >= 0; i-- {
+		if kept[autos[i].name] {
+			keep = append(keep, autos[i].name)
+		} else {
+			prune = append(prune, autos[i].name)
+		}
+	}
+	return keep, prune
+}
+
+// RotationResult is the outcome of rotating the snapshots of one guest.
+// Deleted lists the snapshots deleted, or to be deleted with a dry run;
+// Kept the automatic snapshots left in place, including Created.
+type RotationResult struct {
+	VMID    uint64   `json:"vmid"`
+	Name    string   `json:"name"`
+	Node    string   `json:"node"`
+	Type    string   `json:"type"`
+	Created string   `json:"created,omitempty"`
+	Deleted []string `json:"deleted"`
+	Kept    []string `json:"kept"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// RotateSnapshots takes an automatic snapshot of the guest r and deletes the
+// automatic snapshots the policy no longer keeps, waiting for each task with
+// wait. If the snapshot fails, nothing is deleted. With dryRun nothing is
+// changed and the result says what would be.
+func RotateSnapshots(ctx context.Context, c *proxmox.Client, r *proxmox.ClusterResource, p RotationPolicy, now time.Time, dryRun bool, wait func(context.Context, *proxmox.Task) error) *RotationResult {
+	res := &RotationResult{VMID: r.VMID, Name: r.Name, Node: r.Node, Type: r.Type, Deleted: []string{}, Kept: []string{}}
+	fail := func(format string, args ...interface{}) *RotationResult {
+		res.Error = fmt.Sprintf(format, args...)
+		return res
+	}
+	snaps, err := guestSnapshots(ctx, c, r.Type, int(r.VMID), r.Node)
+	if err != nil {
+		return fail("listing snapshots: %v", err)
+	}
+	name := p.SnapshotName(now)
+	names := []string{name}
+	for _, s := range snaps {
+		if s.Name == name {
+			return fail("snapshot %q already exists", name)
+		}
+		names = append(names, s.Name)
+	}
+	keep, prune := p.Prune(names)
+	res.Kept = keep
+
+	if !dryRun {
+		task, err := createGuestSnapshot(ctx, c, r.Type, int(r.VMID), r.Node, name, SnapshotOptions{Description: "Created by pxve snapshot rotate"})
+		if err == nil {
+			err = wait(ctx, task)
+		}
+		if err != nil {
+			res.Kept = without(keep, name)
+			return fail("creating %s: %v", name, err)
+		}
+	}
+	res.Created = name
+
+	for i, n := range prune {
+		if !dryRun {
+			path := fmt.Sprintf("/nodes/%s/%s/%d/snapshot/%s", url.PathEscape(r.Node), r.Type, r.VMID, url.PathEscape(n))
+			var upid proxmox.UPID
+			err := c.Delete(ctx, path, &upid)
+			if err == nil {
+				err = wait(ctx, proxmox.NewTask(upid, c))
+			}
+			if err != nil {
+				// The names sort by time, as they differ only in the timestamp.
+				res.Kept = append(res.Kept, prune[i:]...)
+				sort.Strings(res.Kept)
+				return fail("deleting %s: %v", n, err)
+			}
+		}
+		res.Deleted = append(res.Deleted, n)
+	}
+	return res
+}
+
+// RotateGuests runs RotateSnapshots on each guest, up to parallel at once,
+// and returns the results in the order of guests. Each guest's snapshots are
+// handled one task at a time. done, if set, is called with each result as
+// it completes, one at a time.
+func RotateGuests(ctx context.Context, c *proxmox.Client, guests proxmox.ClusterResources, p RotationPolicy, dryRun bool, parallel int, wait func(context.Context, *proxmox.Task) error, done func(*RotationResult)) []*RotationResult {
+	now := time.Now()
+	sem := make(chan struct{}, max(parallel, 1))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make([]*RotationResult, len(guests))
+	for i, r := range guests {
+		wg.Add(1)
+		go func(i int, r *proxmox.ClusterResource) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			res := RotateSnapshots(ctx, c, r, p, now, dryRun, wait)
+			mu.Lock()
+			defer mu.Unlock()
+			results[i] = res
+			if done != nil {
+				done(res)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+	return results
+}
+
+// without returns l without s.
+func without(l []string, s string) []string {
+	out := make([]string, 0, len(l))
+	for _, v := range l {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
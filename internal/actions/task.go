@@ -0,0 +1,67 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+)
+
+// GetTask returns the task identified by upid with its current status filled in.
+func GetTask(ctx context.Context, c *proxmox.Client, upid string) (*proxmox.Task, error) {
+	// NewTask indexes the UPID fields without bounds checks, so validate the
+	// shape first: UPID:node:pid:pstart:starttime:type:id:user:
+	if !strings.HasPrefix(upid, "UPID:") || len(strings.Split(upid, ":")) < 8 {
+		return nil, fmt.Errorf("invalid UPID %q", upid)
+	}
+	task := proxmox.NewTask(proxmox.UPID(upid), c)
+	if err := task.Ping(ctx); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// TaskLog returns the task's log lines starting at line start (0-based), in
+// order, without the "no content" placeholder of an empty log. Callers
+// tailing a running task pass the number of lines seen so far.
+func TaskLog(ctx context.Context, c *proxmox.Client, upid string, start int) ([]string, error) {
+	task, err := GetTask(ctx, c, upid)
+	if err != nil {
+		return nil, err
+	}
+	log, err := task.Log(ctx, start, 1000)
+	if err != nil {
+		return nil, err
+	}
+	// An empty log (or no lines past start yet) comes back as a single
+	// placeholder line; drop it so callers can count real lines.
+	if len(log) == 1 {
+		for _, line := range log {
+			if line == "no content" {
+				return []string{}, nil
+			}
+		}
+	}
+	// proxmox.Log is a map keyed by line number; restore the order.
+	nums := make([]int, 0, len(log))
+	for n := range log {
+		nums = append(nums, n)
+	}
+	sort.Ints(nums)
+	lines := make([]string, 0, len(nums))
+	for _, n := range nums {
+		lines = append(lines, log[n])
+	}
+	return lines, nil
+}
+
+// StopTask asks Proxmox to abort a running task.
+func StopTask(ctx context.Context, c *proxmox.Client, upid string) error {
+	task, err := GetTask(ctx, c, upid)
+	if err != nil {
+		return err
+	}
+	return task.Stop(ctx)
+}
@@ -96,27 +96,23 @@ func RebootVM(ctx context.Context, c *proxmox.Client, vmid int, nodeName string)
 	return vm.Reboot(ctx)
 }
 
-// VMSnapshots returns the list of snapshots for a VM, sorted newest first.
-func VMSnapshots(ctx context.Context, c *proxmox.Client, vmid int, nodeName string) ([]*proxmox.Snapshot, error) {
+// VMSnapshots returns the snapshots of a VM, oldest first, followed by
+// CurrentSnapshot. Use SnapshotTree to arrange them by parent.
+func VMSnapshots(ctx context.Context, c *proxmox.Client, vmid int, nodeName string) ([]*GuestSnapshot, error) {
 	vm, err := FindVM(ctx, c, vmid, nodeName)
 	if err != nil {
 		return nil, err
 	}
-	snaps, err := vm.Snapshots(ctx)
-	if err != nil {
-		return nil, err
-	}
-	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Snaptime > snaps[j].Snaptime })
-	return snaps, nil
+	return guestSnapshots(ctx, c, "qemu", vmid, vm.Node)
 }
 
 // CreateVMSnapshot creates a snapshot for a VM and returns the task.
-func CreateVMSnapshot(ctx context.Context, c *proxmox.Client, vmid int, nodeName, name string) (*proxmox.Task, error) {
+func CreateVMSnapshot(ctx context.Context, c *proxmox.Client, vmid int, nodeName, name string, opts SnapshotOptions) (*proxmox.Task, error) {
 	vm, err := FindVM(ctx, c, vmid, nodeName)
 	if err != nil {
 		return nil, err
 	}
-	return vm.NewSnapshot(ctx, name)
+	return createGuestSnapshot(ctx, c, "qemu", vmid, vm.Node, name, opts)
 }
 
 // RollbackVMSnapshot rolls back a VM to a snapshot and returns the task.
@@ -295,7 +291,7 @@ func VMAgentExec(ctx context.Context, c *proxmox.Client, vmid int, nodeName stri
 	if err != nil {
 		return nil, err
 	}
-	pid, err := vm.AgentExec(ctx, command, inputData)
+	pid, err := agentExec(ctx, c, vm, command, inputData)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,81 @@
+// Package cache keeps JSON documents in the user's cache directory (e.g.
+// ~/.cache/pxve on Linux) for reuse across runs, up to a maximum age.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Dir returns the cache directory for the Proxmox instance at instURL under
+// the subdirectory kind (e.g. "facts"), creating it if needed. Each instance
+// gets its own directory so that VMIDs of different clusters don't collide.
+func Dir(instURL, kind string) (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(base, "pxve", kind, instanceKey(instURL))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating cache directory: %w", err)
+	}
+	return dir, nil
+}
+
+// instanceKey turns an API URL into a directory name, e.g.
+// "https://pve1:8006" into "pve1_8006".
+func instanceKey(instURL string) string {
+	host := instURL
+	if u, err := url.Parse(instURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-':
+			return r
+		}
+		return '_'
+	}, host)
+}
+
+// Load decodes the document at path into v and returns when it was saved.
+// ok is false if there is no document, it is older than maxAge, or it cannot
+// be read.
+func Load(path string, maxAge time.Duration, v interface{}) (saved time.Time, ok bool) {
+	st, err := os.Stat(path)
+	if err != nil || time.Since(st.ModTime()) > maxAge {
+		return time.Time{}, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return time.Time{}, false
+	}
+	return st.ModTime(), true
+}
+
+// Save writes v to path as JSON. The file is replaced atomically and is
+// readable only by the user, as documents can describe private systems.
+func Save(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
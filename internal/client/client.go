@@ -1,9 +1,13 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	proxmox "github.com/luthermonson/go-proxmox"
@@ -27,11 +31,11 @@ func New(cfg *config.InstanceConfig) (*proxmox.Client, error) {
 	}
 
 	httpClient := &http.Client{
-		Transport: &http.Transport{
+		Transport: captureTransport{base: &http.Transport{
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: !cfg.VerifyTLS, //nolint:gosec
 			},
-		},
+		}},
 	}
 
 	opts := []proxmox.Option{
@@ -52,3 +56,58 @@ func New(cfg *config.InstanceConfig) (*proxmox.Client, error) {
 
 	return proxmox.NewClient(baseURL, opts...), nil
 }
+
+type rawResponseKey struct{}
+
+// WithRawResponse returns a context under which the client copies each API
+// response body into *dst. go-proxmox only decodes the "data" member, so this
+// is how callers read attributes Proxmox returns next to it, such as the
+// pending "changes" of a node's network configuration.
+func WithRawResponse(ctx context.Context, dst *[]byte) context.Context {
+	return context.WithValue(ctx, rawResponseKey{}, dst)
+}
+
+type endpointKey struct{}
+
+// Endpoint is how a request reached the API: its URL, its headers
+// (including authentication) and the TLS settings. It is what a websocket to
+// the same server, such as a terminal console, needs to connect.
+type Endpoint struct {
+	URL    *url.URL
+	Header http.Header
+	TLS    *tls.Config
+}
+
+// WithEndpoint returns a context under which the client records the endpoint
+// of each request into *dst.
+func WithEndpoint(ctx context.Context, dst *Endpoint) context.Context {
+	return context.WithValue(ctx, endpointKey{}, dst)
+}
+
+// captureTransport implements WithRawResponse and WithEndpoint.
+type captureTransport struct {
+	base http.RoundTripper
+}
+
+func (t captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if ep, ok := req.Context().Value(endpointKey{}).(*Endpoint); ok {
+		ep.URL = req.URL
+		ep.Header = req.Header.Clone()
+		if tr, ok := t.base.(*http.Transport); ok {
+			ep.TLS = tr.TLSClientConfig
+		}
+	}
+	res, err := t.base.RoundTrip(req)
+	dst, ok := req.Context().Value(rawResponseKey{}).(*[]byte)
+	if err != nil || !ok {
+		return res, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	*dst = body
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
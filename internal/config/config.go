@@ -20,10 +20,22 @@ type InstanceConfig struct {
 	VerifyTLS   bool   `yaml:"verify-tls,omitempty"`
 }
 
+// HookConfig is a local command or webhook run after a task started by pxve
+// finishes. Exactly one of Command and URL is set.
+type HookConfig struct {
+	Name    string            `yaml:"name,omitempty"`
+	Command string            `yaml:"command,omitempty"` // run with sh -c; payload JSON on stdin
+	URL     string            `yaml:"url,omitempty"`     // payload JSON is POSTed here
+	Headers map[string]string `yaml:"headers,omitempty"` // extra request headers for URL hooks
+	On      string            `yaml:"on,omitempty"`      // always (default), success, or failure
+	Timeout string            `yaml:"timeout,omitempty"` // e.g. 10s (default 30s)
+}
+
 // Config is the top-level configuration structure.
 type Config struct {
 	CurrentInstance string                    `yaml:"current-instance,omitempty"`
 	Instances       map[string]InstanceConfig `yaml:"instances,omitempty"`
+	Hooks           []HookConfig              `yaml:"hooks,omitempty"`
 }
 
 // configPath returns the path to the config file.
@@ -0,0 +1,39 @@
+// Package desktop hands URLs and files to the local desktop: the default
+// browser or handler, or a SPICE viewer.
+package desktop
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// Open opens target, a URL or a file, with the desktop's default handler.
+func Open(target string) error {
+	name := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		name = "open"
+	}
+	return start(name, target)
+}
+
+// OpenViewer opens a virt-viewer connection file with remote-viewer, or
+// with the default handler when remote-viewer is not in PATH (as with the
+// macOS app bundle).
+func OpenViewer(path string) error {
+	if p, err := exec.LookPath("remote-viewer"); err == nil {
+		return start(p, path)
+	}
+	return Open(path)
+}
+
+// start runs name in the background; its output is discarded so it cannot
+// disturb the terminal.
+func start(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", name, err)
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,224 @@
+// Package events turns successive snapshots of cluster state into typed
+// change events (guest started, task failed, node offline, ...).
+package events
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// Event types.
+const (
+	GuestStarted          = "guest.started"
+	GuestStopped          = "guest.stopped"
+	GuestCreated          = "guest.created"
+	GuestRemoved          = "guest.removed"
+	TaskStarted           = "task.started"
+	TaskFailed            = "task.failed"
+	NodeOffline           = "node.offline"
+	NodeOnline            = "node.online"
+	StorageAboveThreshold = "storage.above_threshold"
+	StorageBelowThreshold = "storage.below_threshold"
+)
+
+// Types lists every event type in the order they are documented.
+var Types = []string{
+	GuestStarted, GuestStopped, GuestCreated, GuestRemoved,
+	TaskStarted, TaskFailed,
+	NodeOffline, NodeOnline,
+	StorageAboveThreshold, StorageBelowThreshold,
+}
+
+// Event is a single state change. Only the fields relevant to Type are set.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"type"`
+	Node      string    `json:"node,omitempty"`
+	VMID      uint64    `json:"vmid,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	GuestType string    `json:"guest_type,omitempty"` // qemu or lxc
+	Status    string    `json:"status,omitempty"`     // new guest/node status, or a failed task's exit status
+	Previous  string    `json:"previous,omitempty"`   // status before the change
+	UPID      string    `json:"upid,omitempty"`
+	TaskType  string    `json:"task_type,omitempty"`
+	TaskID    string    `json:"task_id,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Storage   string    `json:"storage,omitempty"`
+	Usage     float64   `json:"usage,omitempty"`     // storage usage, 0..1
+	Threshold float64   `json:"threshold,omitempty"` // storage threshold, 0..1
+}
+
+// Snapshot is the cluster state at one point in time.
+type Snapshot struct {
+	Time      time.Time
+	Resources proxmox.ClusterResources
+	Tasks     proxmox.Tasks
+}
+
+// Take queries cluster resources and recent tasks.
+func Take(ctx context.Context, c *proxmox.Client) (*Snapshot, error) {
+	resources, err := actions.ClusterResources(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := actions.ClusterTasks(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Time: time.Now().Truncate(time.Second), Resources: resources, Tasks: tasks}, nil
+}
+
+// Diff returns the events between prev and cur, oldest first. threshold is
+// the storage usage fraction (0..1) whose crossing is reported; zero
+// disables storage events. A nil prev is the baseline and yields no events.
+//
+// Guest transitions to or from "unknown" (its node is unreachable) are not
+// reported; the node.offline / node.online events cover them.
+func Diff(prev, cur *Snapshot, threshold float64) []Event {
+	if prev == nil {
+		return nil
+	}
+	var evs []Event
+
+	before := make(map[string]*proxmox.ClusterResource, len(prev.Resources))
+	for _, r := range prev.Resources {
+		before[r.ID] = r
+	}
+	seen := make(map[string]bool, len(cur.Resources))
+	for _, r := range cur.Resources {
+		seen[r.ID] = true
+		old := before[r.ID]
+		switch r.Type {
+		case "qemu", "lxc":
+			switch {
+			case old == nil:
+				evs = append(evs, guestEvent(GuestCreated, cur.Time, r, ""))
+			case r.Status == "running" && old.Status != "running" && old.Status != "unknown":
+				evs = append(evs, guestEvent(GuestStarted, cur.Time, r, old.Status))
+			case old.Status == "running" && r.Status != "running" && r.Status != "unknown":
+				evs = append(evs, guestEvent(GuestStopped, cur.Time, r, old.Status))
+			}
+		case "node":
+			switch {
+			case old == nil:
+			case old.Status == "online" && r.Status != "online":
+				evs = append(evs, Event{Time: cur.Time, Type: NodeOffline, Node: r.Node, Status: r.Status, Previous: old.Status})
+			case old.Status != "online" && r.Status == "online":
+				evs = append(evs, Event{Time: cur.Time, Type: NodeOnline, Node: r.Node, Status: r.Status, Previous: old.Status})
+			}
+		case "storage":
+			if old == nil || threshold <= 0 {
+				continue
+			}
+			was, now := usage(old), usage(r)
+			switch {
+			case was < threshold && now >= threshold:
+				evs = append(evs, storageEvent(StorageAboveThreshold, cur.Time, r, now, threshold))
+			case was >= threshold && now < threshold:
+				evs = append(evs, storageEvent(StorageBelowThreshold, cur.Time, r, now, threshold))
+			}
+		}
+	}
+	for _, r := range prev.Resources {
+		if (r.Type == "qemu" || r.Type == "lxc") && !seen[r.ID] {
+			evs = append(evs, guestEvent(GuestRemoved, cur.Time, r, ""))
+		}
+	}
+
+	known := make(map[proxmox.UPID]*proxmox.Task, len(prev.Tasks))
+	for _, t := range prev.Tasks {
+		known[t.UPID] = t
+	}
+	for _, t := range cur.Tasks {
+		old, ok := known[t.UPID]
+		if !ok {
+			evs = append(evs, taskEvent(TaskStarted, t.StartTime, t))
+		}
+		if taskFailed(t) && (!ok || !taskDone(old)) {
+			evs = append(evs, taskEvent(TaskFailed, t.EndTime, t))
+		}
+	}
+
+	sortEvents(evs)
+	return evs
+}
+
+// TasksSince returns task.started and task.failed events for the tasks in s
+// that started at or after since, oldest first. It replays recent history
+// before the first Diff.
+func TasksSince(s *Snapshot, since time.Time) []Event {
+	var evs []Event
+	for _, t := range s.Tasks {
+		if t.StartTime.Before(since) {
+			continue
+		}
+		evs = append(evs, taskEvent(TaskStarted, t.StartTime, t))
+		if taskFailed(t) {
+			evs = append(evs, taskEvent(TaskFailed, t.EndTime, t))
+		}
+	}
+	sortEvents(evs)
+	return evs
+}
+
+func guestEvent(typ string, at time.Time, r *proxmox.ClusterResource, previous string) Event {
+	return Event{
+		Time:      at,
+		Type:      typ,
+		Node:      r.Node,
+		VMID:      r.VMID,
+		Name:      r.Name,
+		GuestType: r.Type,
+		Status:    r.Status,
+		Previous:  previous,
+	}
+}
+
+func storageEvent(typ string, at time.Time, r *proxmox.ClusterResource, u, threshold float64) Event {
+	return Event{Time: at, Type: typ, Node: r.Node, Storage: r.Storage, Usage: u, Threshold: threshold}
+}
+
+func taskEvent(typ string, at time.Time, t *proxmox.Task) Event {
+	e := Event{
+		Time:     at,
+		Type:     typ,
+		Node:     t.Node,
+		UPID:     string(t.UPID),
+		TaskType: t.Type,
+		TaskID:   t.ID,
+		User:     t.User,
+	}
+	if typ == TaskFailed {
+		e.Status = t.Status
+	}
+	return e
+}
+
+func usage(r *proxmox.ClusterResource) float64 {
+	if r.MaxDisk == 0 {
+		return 0
+	}
+	return float64(r.Disk) / float64(r.MaxDisk)
+}
+
+// taskDone reports whether a cluster task entry has finished. Running tasks
+// have neither an end time nor a status.
+func taskDone(t *proxmox.Task) bool {
+	return !t.EndTime.IsZero() || (t.Status != "" && t.Status != "running")
+}
+
+// taskFailed reports whether t finished with an error. Proxmox reports "OK"
+// on success and "WARNINGS: n" when the task succeeded with warnings.
+func taskFailed(t *proxmox.Task) bool {
+	return taskDone(t) && t.Status != "OK" && !strings.HasPrefix(t.Status, "WARNINGS")
+}
+
+func sortEvents(evs []Event) {
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].Time.Before(evs[j].Time) })
+}
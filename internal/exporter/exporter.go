@@ -0,0 +1,264 @@
+// Package exporter serves Proxmox cluster state in the Prometheus text
+// exposition format.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// collectTimeout bounds a single collection when no interval is configured.
+const collectTimeout = 60 * time.Second
+
+// Target is a Proxmox instance to scrape. Name becomes the pxve_instance label.
+type Target struct {
+	Name   string
+	Client *proxmox.Client
+}
+
+// Exporter collects metrics from one or more instances. With a zero interval
+// every scrape triggers a fresh collection; otherwise collections run in the
+// background and scrapes are served from the last result.
+type Exporter struct {
+	targets  []Target
+	interval time.Duration
+	logger   *log.Logger
+
+	collectMu sync.Mutex // serialises collections so concurrent scrapes share the API load
+
+	mu     sync.RWMutex
+	cached []byte
+}
+
+// New returns an Exporter for targets. Errors are logged to logger.
+func New(targets []Target, interval time.Duration, logger *log.Logger) *Exporter {
+	return &Exporter{targets: targets, interval: interval, logger: logger}
+}
+
+// Run refreshes the cache every interval until ctx is cancelled. It returns
+// immediately when no interval is configured.
+func (e *Exporter) Run(ctx context.Context) {
+	if e.interval <= 0 {
+		return
+	}
+	e.refresh(ctx)
+	t := time.NewTicker(e.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			e.refresh(ctx)
+		}
+	}
+}
+
+func (e *Exporter) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.interval)
+	defer cancel()
+	body := e.Collect(ctx)
+	e.mu.Lock()
+	e.cached = body
+	e.mu.Unlock()
+}
+
+// Handler returns an http.Handler serving /metrics and a landing page on /.
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", e.serveMetrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><head><title>pxve exporter</title></head><body><h1>pxve exporter</h1><p><a href="/metrics">Metrics</a></p></body></html>`)
+	})
+	return mux
+}
+
+func (e *Exporter) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if e.interval > 0 {
+		e.mu.RLock()
+		body = e.cached
+		e.mu.RUnlock()
+	}
+	if body == nil {
+		ctx, cancel := context.WithTimeout(r.Context(), collectTimeout)
+		defer cancel()
+		body = e.Collect(ctx)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = w.Write(body)
+}
+
+// snapshot is the raw data gathered from one instance.
+type snapshot struct {
+	target    Target
+	resources proxmox.ClusterResources
+	nodes     map[string]*proxmox.Node
+	backups   []actions.BackupEntry
+	backupErr error
+	err       error
+	duration  time.Duration
+}
+
+// Collect queries every target concurrently and renders the result.
+func (e *Exporter) Collect(ctx context.Context) []byte {
+	e.collectMu.Lock()
+	defer e.collectMu.Unlock()
+
+	snaps := make([]snapshot, len(e.targets))
+	var wg sync.WaitGroup
+	for i, t := range e.targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			snaps[i] = collectInstance(ctx, t)
+		}(i, t)
+	}
+	wg.Wait()
+
+	set := newMetricSet()
+	for _, s := range snaps {
+		if s.err != nil {
+			e.logger.Printf("instance %s: %v", s.target.Name, s.err)
+		}
+		if s.backupErr != nil {
+			e.logger.Printf("instance %s: listing backups: %v", s.target.Name, s.backupErr)
+		}
+		writeSnapshot(set, s)
+	}
+	return set.bytes()
+}
+
+func collectInstance(ctx context.Context, t Target) snapshot {
+	start := time.Now()
+	s := snapshot{target: t, nodes: make(map[string]*proxmox.Node)}
+
+	s.resources, s.err = actions.ClusterResources(ctx, t.Client)
+	if s.err != nil {
+		s.duration = time.Since(start)
+		return s
+	}
+	for _, r := range s.resources {
+		if r.Type != "node" || r.Status != "online" {
+			continue
+		}
+		if n, err := actions.GetNode(ctx, t.Client, r.Node); err == nil {
+			s.nodes[r.Node] = n
+		}
+	}
+	s.backups, s.backupErr = actions.ListBackups(ctx, t.Client, "", "", 0)
+	s.duration = time.Since(start)
+	return s
+}
+
+func writeSnapshot(set *metricSet, s snapshot) {
+	inst := label{"pxve_instance", s.target.Name}
+
+	up := 1.0
+	if s.err != nil {
+		up = 0
+	}
+	set.gauge("pxve_up", "Whether the last collection from the instance succeeded.", up, inst)
+	set.gauge("pxve_scrape_duration_seconds", "Time taken to collect the instance.", s.duration.Seconds(), inst)
+	if s.err != nil {
+		return
+	}
+
+	// Newest backup per VMID (backups are sorted newest first).
+	lastBackup := make(map[uint64]int64)
+	backupCount := make(map[uint64]int)
+	for _, b := range s.backups {
+		if _, ok := lastBackup[b.VMID]; !ok {
+			lastBackup[b.VMID] = b.Ctime
+		}
+		backupCount[b.VMID]++
+	}
+
+	for _, r := range s.resources {
+		switch r.Type {
+		case "node":
+			writeNode(set, inst, r, s.nodes[r.Node])
+		case "qemu", "lxc":
+			writeGuest(set, inst, r)
+			if s.backupErr == nil && r.Template == 0 {
+				labels := []label{inst, {"vmid", strconv.FormatUint(r.VMID, 10)}, {"name", r.Name}, {"type", r.Type}}
+				set.gauge("pxve_guest_last_backup_timestamp_seconds", "Creation time of the newest backup of the guest (0 if none).",
+					float64(lastBackup[r.VMID]), labels...)
+				set.gauge("pxve_guest_backups", "Number of backups of the guest across all backup storages.",
+					float64(backupCount[r.VMID]), labels...)
+			}
+		case "storage":
+			writeStorage(set, inst, r)
+		}
+	}
+}
+
+func writeNode(set *metricSet, inst label, r *proxmox.ClusterResource, n *proxmox.Node) {
+	labels := []label{inst, {"node", r.Node}}
+	set.gauge("pxve_node_up", "Whether the node is online.", boolValue(r.Status == "online"), labels...)
+	set.gauge("pxve_node_cpu_ratio", "Node CPU utilisation (0-1).", r.CPU, labels...)
+	set.gauge("pxve_node_cpus", "Number of CPUs on the node.", float64(r.MaxCPU), labels...)
+	set.gauge("pxve_node_memory_used_bytes", "Node memory in use.", float64(r.Mem), labels...)
+	set.gauge("pxve_node_memory_total_bytes", "Node memory size.", float64(r.MaxMem), labels...)
+	set.gauge("pxve_node_uptime_seconds", "Node uptime.", float64(r.Uptime), labels...)
+	if n == nil {
+		return
+	}
+	for i, name := range []string{"pxve_node_load1", "pxve_node_load5", "pxve_node_load15"} {
+		if i >= len(n.LoadAvg) {
+			break
+		}
+		if v, err := strconv.ParseFloat(n.LoadAvg[i], 64); err == nil {
+			set.gauge(name, "Node load average.", v, labels...)
+		}
+	}
+	set.gauge("pxve_node_swap_used_bytes", "Node swap in use.", float64(n.Swap.Used), labels...)
+	set.gauge("pxve_node_swap_total_bytes", "Node swap size.", float64(n.Swap.Total), labels...)
+	set.gauge("pxve_node_rootfs_used_bytes", "Node root filesystem usage.", float64(n.RootFS.Used), labels...)
+	set.gauge("pxve_node_rootfs_total_bytes", "Node root filesystem size.", float64(n.RootFS.Total), labels...)
+}
+
+func writeGuest(set *metricSet, inst label, r *proxmox.ClusterResource) {
+	labels := []label{inst, {"node", r.Node}, {"vmid", strconv.FormatUint(r.VMID, 10)}, {"name", r.Name}, {"type", r.Type}}
+	set.gauge("pxve_guest_info", "Guest metadata; always 1.", 1,
+		append(labels, label{"tags", r.Tags}, label{"template", strconv.FormatUint(r.Template, 10)})...)
+	set.gauge("pxve_guest_up", "Whether the guest is running.", boolValue(r.Status == "running"), labels...)
+	set.gauge("pxve_guest_cpu_ratio", "Guest CPU utilisation relative to its allocated CPUs (0-1).", r.CPU, labels...)
+	set.gauge("pxve_guest_cpus", "Number of CPUs allocated to the guest.", float64(r.MaxCPU), labels...)
+	set.gauge("pxve_guest_memory_used_bytes", "Guest memory in use.", float64(r.Mem), labels...)
+	set.gauge("pxve_guest_memory_total_bytes", "Guest memory size.", float64(r.MaxMem), labels...)
+	set.gauge("pxve_guest_disk_total_bytes", "Guest root disk size.", float64(r.MaxDisk), labels...)
+	set.gauge("pxve_guest_uptime_seconds", "Guest uptime.", float64(r.Uptime), labels...)
+	set.counter("pxve_guest_network_receive_bytes_total", "Bytes received by the guest since it started.", float64(r.NetIn), labels...)
+	set.counter("pxve_guest_network_transmit_bytes_total", "Bytes sent by the guest since it started.", float64(r.NetOut), labels...)
+	set.counter("pxve_guest_disk_read_bytes_total", "Bytes read from disk by the guest since it started.", float64(r.DiskRead), labels...)
+	set.counter("pxve_guest_disk_write_bytes_total", "Bytes written to disk by the guest since it started.", float64(r.DiskWrite), labels...)
+}
+
+func writeStorage(set *metricSet, inst label, r *proxmox.ClusterResource) {
+	labels := []label{inst, {"node", r.Node}, {"storage", r.Storage}, {"type", r.PluginType}, {"shared", strconv.FormatUint(r.Shared, 10)}}
+	set.gauge("pxve_storage_up", "Whether the storage is available.", boolValue(r.Status == "available"), labels...)
+	set.gauge("pxve_storage_used_bytes", "Storage space in use.", float64(r.Disk), labels...)
+	set.gauge("pxve_storage_total_bytes", "Storage size.", float64(r.MaxDisk), labels...)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// label is a single Prometheus label pair.
+type label struct {
+	Name  string
+	Value string
+}
+
+// family collects the samples of one metric so they can be written
+// contiguously under a single HELP/TYPE header, as the text format requires.
+type family struct {
+	name    string
+	help    string
+	typ     string // "gauge" or "counter"
+	samples []string
+}
+
+// metricSet accumulates samples across instances and renders them in the
+// Prometheus text exposition format (version 0.0.4).
+type metricSet struct {
+	families map[string]*family
+	order    []string
+}
+
+func newMetricSet() *metricSet {
+	return &metricSet{families: make(map[string]*family)}
+}
+
+func (s *metricSet) gauge(name, help string, value float64, labels ...label) {
+	s.add(name, help, "gauge", value, labels)
+}
+
+func (s *metricSet) counter(name, help string, value float64, labels ...label) {
+	s.add(name, help, "counter", value, labels)
+}
+
+func (s *metricSet) add(name, help, typ string, value float64, labels []label) {
+	f, ok := s.families[name]
+	if !ok {
+		f = &family{name: name, help: help, typ: typ}
+		s.families[name] = f
+		s.order = append(s.order, name)
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.Name)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(l.Value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatValue(value))
+	f.samples = append(f.samples, b.String())
+}
+
+// bytes renders all families in first-registered order.
+func (s *metricSet) bytes() []byte {
+	var buf bytes.Buffer
+	for _, name := range s.order {
+		f := s.families[name]
+		fmt.Fprintf(&buf, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", f.name, f.typ)
+		for _, line := range f.samples {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	return buf.Bytes()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
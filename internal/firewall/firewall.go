@@ -0,0 +1,414 @@
+// Package firewall exports a firewall scope (cluster, node, or guest) as a
+// declarative spec and reconciles a scope against one: the rule list in
+// order, IP sets, and aliases.
+package firewall
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// Spec is the file format of "pxve firewall export" and "sync". A nil section
+// (missing from the file) is left untouched by sync; an empty list removes
+// everything in that section.
+type Spec struct {
+	Scope   string                  `yaml:"scope,omitempty"`
+	Rules   []Rule                  `yaml:"rules"`
+	IPSets  []actions.FirewallIPSet `yaml:"ipsets"`
+	Aliases []actions.FirewallAlias `yaml:"aliases"`
+}
+
+// Rule is a firewall rule in a spec. Rules are enabled unless Disabled is
+// set, so hand-written rules need no enable field.
+type Rule struct {
+	actions.FirewallRule `yaml:",inline"`
+	Disabled             bool `yaml:"disabled,omitempty"`
+}
+
+// apiRule converts r to the API representation.
+func (r Rule) apiRule() actions.FirewallRule {
+	out := r.FirewallRule
+	out.Pos = 0
+	out.Enable = 1
+	if r.Disabled {
+		out.Enable = 0
+	}
+	return out
+}
+
+// Ref returns the scope as written in a spec's scope field: "cluster",
+// "node/<node>", or "guest/<vmid>".
+func Ref(s actions.FirewallScope) string {
+	switch {
+	case s.IsGuest():
+		return "guest/" + strconv.Itoa(s.VMID)
+	case s.IsNode():
+		return "node/" + s.Node
+	default:
+		return "cluster"
+	}
+}
+
+// ResolveRef parses a scope written by Ref. Guests are looked up in the
+// cluster to find their node and type.
+func ResolveRef(ctx context.Context, c *proxmox.Client, ref string) (actions.FirewallScope, error) {
+	kind, name, _ := strings.Cut(ref, "/")
+	switch {
+	case ref == "cluster":
+		return actions.ClusterFirewall, nil
+	case kind == "node" && name != "":
+		return actions.FirewallScope{Node: name}, nil
+	case kind == "guest":
+		vmid, err := strconv.Atoi(name)
+		if err != nil {
+			return actions.FirewallScope{}, fmt.Errorf("invalid scope %q: bad VMID", ref)
+		}
+		return actions.GuestFirewall(ctx, c, vmid, "")
+	}
+	return actions.FirewallScope{}, fmt.Errorf("invalid scope %q (expected cluster, node/<node>, or guest/<vmid>)", ref)
+}
+
+// Export reads the current rules, IP sets, and aliases of s. Nodes have no
+// IP sets or aliases, so those sections are left nil.
+func Export(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) (*Spec, error) {
+	if s.IsGroup() {
+		return nil, fmt.Errorf("security groups cannot be exported; export the cluster or a guest instead")
+	}
+	rules, err := actions.FirewallRules(ctx, c, s)
+	if err != nil {
+		return nil, err
+	}
+	spec := &Spec{Scope: Ref(s), Rules: []Rule{}}
+	for _, r := range rules {
+		r.Pos = 0
+		spec.Rules = append(spec.Rules, Rule{FirewallRule: r, Disabled: r.Enable == 0})
+	}
+	if s.IsNode() {
+		return spec, nil
+	}
+
+	sets, err := actions.FirewallIPSets(ctx, c, s)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := actions.FirewallAliases(ctx, c, s)
+	if err != nil {
+		return nil, err
+	}
+	spec.IPSets = append([]actions.FirewallIPSet{}, sets...)
+	spec.Aliases = append([]actions.FirewallAlias{}, aliases...)
+	return spec, nil
+}
+
+// Change kinds, as printed in front of each change.
+const (
+	OpAdd    = "+"
+	OpDelete = "-"
+	OpUpdate = "~"
+)
+
+// Change is one API call needed to reconcile a scope with a spec.
+type Change struct {
+	Op     string // OpAdd, OpDelete, or OpUpdate
+	Object string // e.g. "rule 3", "alias db", "ipset office entry 10.0.0.0/8"
+	Detail string // human-readable description of the new (or removed) value
+
+	apply func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error
+}
+
+// Plan returns the changes that turn cur into want, in the order they must be
+// applied: aliases and IP sets are created before the rules that may
+// reference them, and removed only after those rules are gone. Rules are
+// matched in order (longest common subsequence), so moving one rule shows up
+// as a single delete and insert rather than a rewrite of the whole list.
+func Plan(cur, want *Spec, node bool) ([]Change, error) {
+	if node && (len(want.IPSets) > 0 || len(want.Aliases) > 0) {
+		return nil, fmt.Errorf("nodes have no IP sets or aliases; move them to the cluster or a guest")
+	}
+	for i, r := range want.Rules {
+		if r.Type == "" || r.Action == "" {
+			return nil, fmt.Errorf("rule %d: type and action are required", i)
+		}
+	}
+
+	var changes, deferred []Change
+	if want.Aliases != nil {
+		add, del := planAliases(cur.Aliases, want.Aliases)
+		changes = append(changes, add...)
+		deferred = append(deferred, del...)
+	}
+	if want.IPSets != nil {
+		add, del := planIPSets(cur.IPSets, want.IPSets)
+		changes = append(changes, add...)
+		// IP sets go before aliases, since entries may name an alias.
+		deferred = append(del, deferred...)
+	}
+	if want.Rules != nil {
+		changes = append(changes, planRules(cur.Rules, want.Rules)...)
+	}
+	return append(changes, deferred...), nil
+}
+
+// Apply performs changes in order against s, stopping at the first failure.
+// It returns the number of changes applied.
+func Apply(ctx context.Context, c *proxmox.Client, s actions.FirewallScope, changes []Change) (int, error) {
+	for i, ch := range changes {
+		if err := ch.apply(ctx, c, s); err != nil {
+			return i, fmt.Errorf("%s %s: %w", ch.Op, ch.Object, err)
+		}
+	}
+	return len(changes), nil
+}
+
+func planRules(cur, want []Rule) []Change {
+	a := make([]actions.FirewallRule, len(cur))
+	for i, r := range cur {
+		a[i] = r.apiRule()
+	}
+	b := make([]actions.FirewallRule, len(want))
+	for i, r := range want {
+		b[i] = r.apiRule()
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	keepA := make([]bool, len(a))
+	keepB := make([]bool, len(b))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			keepA[i], keepB[j] = true, true
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	// Delete from the bottom up so earlier positions stay valid. What is left
+	// is the common subsequence in order; inserting the missing rules at
+	// their final positions top-down then yields exactly want. Each insert is
+	// a create (which Proxmox puts at the top) and a move into place.
+	var changes []Change
+	for i := len(a) - 1; i >= 0; i-- {
+		if keepA[i] {
+			continue
+		}
+		pos := i
+		changes = append(changes, Change{
+			Op: OpDelete, Object: fmt.Sprintf("rule %d", pos), Detail: Summary(a[i]),
+			apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+				return actions.DeleteFirewallRule(ctx, c, s, pos)
+			},
+		})
+	}
+	for j := range b {
+		if keepB[j] {
+			continue
+		}
+		pos, r := j, b[j]
+		changes = append(changes, Change{
+			Op: OpAdd, Object: fmt.Sprintf("rule %d", pos), Detail: Summary(r),
+			apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+				return actions.AddFirewallRule(ctx, c, s, r, pos)
+			},
+		})
+	}
+	return changes
+}
+
+// planIPSets returns the changes that create and update sets and entries,
+// and separately the deletions of sets no longer wanted.
+func planIPSets(cur, want []actions.FirewallIPSet) (changes, deletes []Change) {
+	have := map[string]actions.FirewallIPSet{}
+	for _, set := range cur {
+		have[set.Name] = set
+	}
+	wanted := map[string]bool{}
+	for _, set := range want {
+		set := set
+		wanted[set.Name] = true
+		old, exists := have[set.Name]
+		switch {
+		case !exists:
+			changes = append(changes, Change{
+				Op: OpAdd, Object: "ipset " + set.Name, Detail: set.Comment,
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.CreateFirewallIPSet(ctx, c, s, set.Name, set.Comment)
+				},
+			})
+		case old.Comment != set.Comment:
+			changes = append(changes, Change{
+				Op: OpUpdate, Object: "ipset " + set.Name, Detail: fmt.Sprintf("comment %q", set.Comment),
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.UpdateFirewallIPSet(ctx, c, s, set.Name, set.Comment)
+				},
+			})
+		}
+		changes = append(changes, planEntries(set.Name, old.Entries, set.Entries)...)
+	}
+	for _, set := range cur {
+		if wanted[set.Name] {
+			continue
+		}
+		name := set.Name
+		deletes = append(deletes, Change{
+			Op: OpDelete, Object: "ipset " + name, Detail: fmt.Sprintf("%d entries", len(set.Entries)),
+			apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+				return actions.DeleteFirewallIPSet(ctx, c, s, name, true)
+			},
+		})
+	}
+	return changes, deletes
+}
+
+func planEntries(set string, cur, want []actions.FirewallIPSetEntry) []Change {
+	have := map[string]actions.FirewallIPSetEntry{}
+	for _, e := range cur {
+		have[e.CIDR] = e
+	}
+	var changes []Change
+	wanted := map[string]bool{}
+	for _, e := range want {
+		e := e
+		wanted[e.CIDR] = true
+		object := fmt.Sprintf("ipset %s entry %s", set, e.CIDR)
+		old, exists := have[e.CIDR]
+		switch {
+		case !exists:
+			changes = append(changes, Change{
+				Op: OpAdd, Object: object, Detail: entryDetail(e),
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.AddFirewallIPSetEntry(ctx, c, s, set, e)
+				},
+			})
+		case old != e:
+			changes = append(changes, Change{
+				Op: OpUpdate, Object: object, Detail: entryDetail(e),
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.UpdateFirewallIPSetEntry(ctx, c, s, set, e)
+				},
+			})
+		}
+	}
+	for _, e := range cur {
+		if wanted[e.CIDR] {
+			continue
+		}
+		cidr := e.CIDR
+		changes = append(changes, Change{
+			Op: OpDelete, Object: fmt.Sprintf("ipset %s entry %s", set, cidr), Detail: entryDetail(e),
+			apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+				return actions.RemoveFirewallIPSetEntry(ctx, c, s, set, cidr)
+			},
+		})
+	}
+	return changes
+}
+
+func entryDetail(e actions.FirewallIPSetEntry) string {
+	var parts []string
+	if e.NoMatch {
+		parts = append(parts, "nomatch")
+	}
+	if e.Comment != "" {
+		parts = append(parts, "# "+e.Comment)
+	}
+	return strings.Join(parts, " ")
+}
+
+// planAliases returns the changes that create and update aliases, and
+// separately the deletions of aliases no longer wanted.
+func planAliases(cur, want []actions.FirewallAlias) (changes, deletes []Change) {
+	have := map[string]actions.FirewallAlias{}
+	for _, a := range cur {
+		have[a.Name] = a
+	}
+	wanted := map[string]bool{}
+	for _, a := range want {
+		a := a
+		wanted[a.Name] = true
+		old, exists := have[a.Name]
+		switch {
+		case !exists:
+			changes = append(changes, Change{
+				Op: OpAdd, Object: "alias " + a.Name, Detail: aliasDetail(a),
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.CreateFirewallAlias(ctx, c, s, a)
+				},
+			})
+		case old != a:
+			changes = append(changes, Change{
+				Op: OpUpdate, Object: "alias " + a.Name, Detail: aliasDetail(a),
+				apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+					return actions.UpdateFirewallAlias(ctx, c, s, a)
+				},
+			})
+		}
+	}
+	for _, a := range cur {
+		if wanted[a.Name] {
+			continue
+		}
+		name := a.Name
+		deletes = append(deletes, Change{
+			Op: OpDelete, Object: "alias " + name, Detail: aliasDetail(a),
+			apply: func(ctx context.Context, c *proxmox.Client, s actions.FirewallScope) error {
+				return actions.DeleteFirewallAlias(ctx, c, s, name)
+			},
+		})
+	}
+	return changes, deletes
+}
+
+func aliasDetail(a actions.FirewallAlias) string {
+	if a.Comment != "" {
+		return a.CIDR + " # " + a.Comment
+	}
+	return a.CIDR
+}
+
+// Summary describes a rule on one line, e.g.
+// "in ACCEPT macro=SSH source=10.0.0.0/8 # admin access".
+func Summary(r actions.FirewallRule) string {
+	parts := []string{r.Type, r.Action}
+	for _, kv := range [][2]string{
+		{"macro", r.Macro}, {"proto", r.Proto}, {"source", r.Source}, {"sport", r.Sport},
+		{"dest", r.Dest}, {"dport", r.Dport}, {"icmp-type", r.IcmpType}, {"iface", r.Iface}, {"log", r.Log},
+	} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	if r.Enable == 0 {
+		parts = append(parts, "(disabled)")
+	}
+	if r.Comment != "" {
+		parts = append(parts, "# "+r.Comment)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,164 @@
+package firewall
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// ruleServer is an in-memory cluster rule list behind the Proxmox rules API.
+// Like the real server, create ignores "pos" and inserts at the top, and
+// "moveto" takes the rule out and puts it before the rule that was at
+// moveto, or at the end when moveto is past the last rule.
+type ruleServer struct {
+	mu    sync.Mutex
+	rules []actions.FirewallRule
+}
+
+func (rs *ruleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	const base = "/api2/json/cluster/firewall/rules"
+	if !strings.HasPrefix(r.URL.Path, base) {
+		http.NotFound(w, r)
+		return
+	}
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	pos := -1
+	if rest := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, base), "/"); rest != "" {
+		n, err := strconv.Atoi(rest)
+		if err != nil || n >= len(rs.rules) {
+			http.Error(w, "no such rule", http.StatusBadRequest)
+			return
+		}
+		pos = n
+	}
+
+	var data interface{}
+	switch {
+	case r.Method == http.MethodGet && pos < 0:
+		out := make([]actions.FirewallRule, len(rs.rules))
+		for i, rule := range rs.rules {
+			rule.Pos = i
+			out[i] = rule
+		}
+		data = out
+	case r.Method == http.MethodPost && pos < 0:
+		raw, _ := json.Marshal(body)
+		var rule actions.FirewallRule
+		json.Unmarshal(raw, &rule)
+		rule.Pos = 0
+		rs.rules = append([]actions.FirewallRule{rule}, rs.rules...)
+	case r.Method == http.MethodPut && body["moveto"] != nil:
+		moveto := int(body["moveto"].(float64))
+		moved := rs.rules[pos]
+		var out []actions.FirewallRule
+		for i, rule := range rs.rules {
+			if i == pos {
+				continue
+			}
+			if i == moveto {
+				out = append(out, moved)
+			}
+			out = append(out, rule)
+		}
+		if moveto >= len(rs.rules) {
+			out = append(out, moved)
+		}
+		rs.rules = out
+	case r.Method == http.MethodDelete && pos >= 0:
+		rs.rules = append(rs.rules[:pos:pos], rs.rules[pos+1:]...)
+	default:
+		http.Error(w, "unsupported", http.StatusNotImplemented)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func rule(comment string) Rule {
+	return Rule{FirewallRule: actions.FirewallRule{Type: "in", Action: "ACCEPT", Proto: "tcp", Comment: comment}}
+}
+
+func rules(comments string) []Rule {
+	out := []Rule{}
+	for _, c := range strings.Fields(comments) {
+		out = append(out, rule(c))
+	}
+	return out
+}
+
+func comments(rs []actions.FirewallRule) string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.Comment)
+	}
+	return strings.Join(out, " ")
+}
+
+func TestSyncRuleOrder(t *testing.T) {
+	tests := []struct{ cur, want string }{
+		{"", "a b c"},
+		{"a b c", ""},
+		{"a b c", "d a e c f"},
+		{"a b c d", "d c b a"},
+		{"a b c d e", "e a x d y"},
+		{"a b", "a b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cur+"→"+tt.want, func(t *testing.T) {
+			rs := &ruleServer{}
+			for _, r := range rules(tt.cur) {
+				rs.rules = append(rs.rules, r.apiRule())
+			}
+			srv := httptest.NewServer(rs)
+			defer srv.Close()
+			c := proxmox.NewClient(srv.URL+"/api2/json", proxmox.WithAPIToken("root@pam!test", "secret"))
+			ctx := context.Background()
+
+			want := &Spec{Rules: rules(tt.want)}
+			changes, err := Plan(&Spec{Rules: rules(tt.cur)}, want, false)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if _, err := Apply(ctx, c, actions.ClusterFirewall, changes); err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+
+			got, err := actions.FirewallRules(ctx, c, actions.ClusterFirewall)
+			if err != nil {
+				t.Fatalf("FirewallRules: %v", err)
+			}
+			if comments(got) != tt.want {
+				t.Fatalf("rules after sync = %q, want %q", comments(got), tt.want)
+			}
+			var after []Rule
+			for i, r := range got {
+				r.Pos = 0
+				if !reflect.DeepEqual(r, want.Rules[i].apiRule()) {
+					t.Errorf("rule %d = %+v, want %+v", i, r, want.Rules[i].apiRule())
+				}
+				after = append(after, Rule{FirewallRule: r})
+			}
+			again, err := Plan(&Spec{Rules: after}, want, false)
+			if err != nil {
+				t.Fatalf("Plan: %v", err)
+			}
+			if len(again) != 0 {
+				t.Errorf("second sync plans %d changes, want none", len(again))
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// Package hooks runs the task-completion hooks configured in ~/.pxve.yaml:
+// local commands and webhooks that receive a JSON description of the task.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/config"
+)
+
+// DefaultTimeout bounds a hook without an explicit timeout.
+const DefaultTimeout = 30 * time.Second
+
+// Payload describes a finished task. It is POSTed to webhooks and written to
+// the stdin of command hooks.
+type Payload struct {
+	Event      string    `json:"event"`  // always "task.finished"
+	Source     string    `json:"source"` // cli, tui, or test
+	Command    string    `json:"command"`
+	UPID       string    `json:"upid"`
+	Node       string    `json:"node"`
+	TaskType   string    `json:"task_type"`
+	Target     string    `json:"target,omitempty"` // task ID; the VMID for guest tasks
+	VMID       int       `json:"vmid,omitempty"`
+	User       string    `json:"user,omitempty"`
+	Status     string    `json:"status"` // Proxmox exit status, e.g. "OK" or the error message
+	Success    bool      `json:"success"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Duration   float64   `json:"duration_seconds"`
+}
+
+// NewPayload describes task, which pxve started waiting on at started. The
+// task must have been pinged after it finished so its exit status is set.
+func NewPayload(task *proxmox.Task, source, command string, started time.Time) Payload {
+	now := time.Now()
+	p := Payload{
+		Event:      "task.finished",
+		Source:     source,
+		Command:    command,
+		UPID:       string(task.UPID),
+		Node:       task.Node,
+		TaskType:   task.Type,
+		Target:     task.ID,
+		User:       task.User,
+		Status:     task.ExitStatus,
+		Success:    !task.IsFailed,
+		StartedAt:  started,
+		FinishedAt: now,
+		Duration:   now.Sub(started).Seconds(),
+	}
+	if id, err := strconv.Atoi(task.ID); err == nil {
+		p.VMID = id
+	}
+	return p
+}
+
+// Validate reports configuration errors in h.
+func Validate(h config.HookConfig) error {
+	switch {
+	case h.Command == "" && h.URL == "":
+		return fmt.Errorf("hook %s: either command or url is required", Label(h))
+	case h.Command != "" && h.URL != "":
+		return fmt.Errorf("hook %s: command and url are mutually exclusive", Label(h))
+	}
+	switch h.On {
+	case "", "always", "success", "failure":
+	default:
+		return fmt.Errorf("hook %s: invalid on %q (must be always, success, or failure)", Label(h), h.On)
+	}
+	if h.Timeout != "" {
+		if d, err := time.ParseDuration(h.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("hook %s: invalid timeout %q", Label(h), h.Timeout)
+		}
+	}
+	return nil
+}
+
+// Label returns the hook's name, or its command or URL when unnamed.
+func Label(h config.HookConfig) string {
+	switch {
+	case h.Name != "":
+		return h.Name
+	case h.URL != "":
+		return h.URL
+	default:
+		return h.Command
+	}
+}
+
+// Run fires every hook whose "on" condition matches p, one after another.
+// Command hook output goes to out. Failures are collected rather than
+// stopping later hooks.
+func Run(ctx context.Context, hooks []config.HookConfig, p Payload, out io.Writer) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, h := range hooks {
+		if err := Validate(h); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if (h.On == "success" && !p.Success) || (h.On == "failure" && p.Success) {
+			continue
+		}
+		if err := runOne(ctx, h, p, body, out); err != nil {
+			errs = append(errs, fmt.Errorf("hook %s: %w", Label(h), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runOne(ctx context.Context, h config.HookConfig, p Payload, body []byte, out io.Writer) error {
+	timeout := DefaultTimeout
+	if h.Timeout != "" {
+		timeout, _ = time.ParseDuration(h.Timeout) // checked by Validate
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if h.URL != "" {
+		return post(ctx, h, body)
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(os.Environ(),
+		"PXVE_EVENT="+p.Event,
+		"PXVE_SOURCE="+p.Source,
+		"PXVE_COMMAND="+p.Command,
+		"PXVE_UPID="+p.UPID,
+		"PXVE_NODE="+p.Node,
+		"PXVE_TASK_TYPE="+p.TaskType,
+		"PXVE_TARGET="+p.Target,
+		"PXVE_STATUS="+p.Status,
+		"PXVE_SUCCESS="+strconv.FormatBool(p.Success),
+		"PXVE_DURATION="+strconv.FormatFloat(p.Duration, 'f', 0, 64),
+	)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out after %s", timeout)
+		}
+		return err
+	}
+	return nil
+}
+
+func post(ctx context.Context, h config.HookConfig, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "pxve")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body) //nolint:errcheck
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %s: %s", h.URL, resp.Status)
+	}
+	return nil
+}
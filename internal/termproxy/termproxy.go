@@ -0,0 +1,207 @@
+// Package termproxy is the client side of the Proxmox VE terminal proxy
+// protocol spoken by the web UI's xterm.js consoles over the vncwebsocket
+// endpoint.
+//
+// After the websocket opens, the client sends "user:ticket\n" and the server
+// answers "OK". From then on the server sends raw terminal output, and the
+// client sends framed messages: "0:<length>:<data>" for input,
+// "1:<cols>:<rows>:" when the terminal is resized, and "2" as a keepalive.
+package termproxy
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrDetached is returned by Attach when the user typed the escape byte.
+var ErrDetached = errors.New("detached")
+
+// keepaliveInterval is how often Attach pings the proxy, which closes
+// connections that stay silent for too long.
+const keepaliveInterval = 30 * time.Second
+
+// Session is an authenticated terminal proxy connection. Read returns the
+// terminal output and Write sends input, so it can be used with io.Copy.
+type Session struct {
+	conn *websocket.Conn
+	wmu  sync.Mutex // gorilla/websocket allows one concurrent writer
+	buf  []byte     // output received but not read yet
+}
+
+// Dial opens the websocket at url (ws:// or wss://) with header and
+// tlsConfig and logs in with user and ticket.
+func Dial(ctx context.Context, url string, header http.Header, tlsConfig *tls.Config, user, ticket string) (*Session, error) {
+	d := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+		TLSClientConfig:  tlsConfig,
+		Subprotocols:     []string{"binary"},
+	}
+	conn, resp, err := d.DialContext(ctx, url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("opening console websocket: %s", resp.Status)
+		}
+		return nil, fmt.Errorf("opening console websocket: %w", err)
+	}
+	s := &Session{conn: conn}
+	if err := s.login(user, ticket); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Session) login(user, ticket string) error {
+	if err := s.send([]byte(user + ":" + ticket + "\n")); err != nil {
+		return fmt.Errorf("console login: %w", err)
+	}
+	_, msg, err := s.conn.ReadMessage()
+	if err != nil {
+		return fmt.Errorf("console login: %w", err)
+	}
+	if string(msg) != "OK" {
+		return fmt.Errorf("console login refused: %q", msg)
+	}
+	return nil
+}
+
+func (s *Session) send(msg []byte) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, msg)
+}
+
+// Read reads terminal output. It returns io.EOF once the server closes the
+// session.
+func (s *Session) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		_, msg, err := s.conn.ReadMessage()
+		if err != nil {
+			var ce *websocket.CloseError
+			if errors.As(err, &ce) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, io.EOF
+			}
+			return 0, err
+		}
+		s.buf = msg
+	}
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// Write sends p as terminal input.
+func (s *Session) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	msg := append([]byte(fmt.Sprintf("0:%d:", len(p))), p...)
+	if err := s.send(msg); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize tells the remote terminal its new size.
+func (s *Session) Resize(cols, rows int) error {
+	return s.send([]byte(fmt.Sprintf("1:%d:%d:", cols, rows)))
+}
+
+// Ping sends a keepalive.
+func (s *Session) Ping() error {
+	return s.send([]byte("2"))
+}
+
+// Close ends the session.
+func (s *Session) Close() error {
+	s.wmu.Lock()
+	_ = s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	s.wmu.Unlock()
+	return s.conn.Close()
+}
+
+// Attach connects in and out to the session until the server closes it, ctx
+// is done, or escape is read from in, in which case it returns ErrDetached.
+// An escape of 0 disables detaching. When in reaches EOF, output is still
+// copied until the server closes, so piped input such as "cmd; exit" works.
+// The session is closed on return.
+func (s *Session) Attach(ctx context.Context, in io.Reader, out io.Writer, escape byte) error {
+	errc := make(chan error, 3)
+	go func() {
+		_, err := io.Copy(out, s)
+		errc <- err
+	}()
+	go func() {
+		if err := copyInput(s, in, escape); err != nil {
+			errc <- err
+		}
+	}()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		t := time.NewTicker(keepaliveInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				if err := s.Ping(); err != nil {
+					errc <- err
+					return
+				}
+			}
+		}
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	s.Close()
+	return err
+}
+
+// copyInput copies in to s up to the escape byte.
+func copyInput(s *Session, in io.Reader, escape byte) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			chunk := buf[:n]
+			detach := false
+			if escape != 0 {
+				for i, b := range chunk {
+					if b == escape {
+						chunk, detach = chunk[:i], true
+						break
+					}
+				}
+			}
+			if _, werr := s.Write(chunk); werr != nil {
+				return werr
+			}
+			if detach {
+				return ErrDetached
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
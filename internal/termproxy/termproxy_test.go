@@ -0,0 +1,190 @@
+package termproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	testUser   = "root@pam"
+	testTicket = "PVE:root@pam:TICKET"
+)
+
+// standIn starts a local terminal proxy that accepts testUser and testTicket,
+// then runs serve on the logged-in connection. Every message the client sends
+// after the login is passed on over the returned channel, which is closed
+// when the client goes away.
+func standIn(t *testing.T, serve func(*websocket.Conn)) (string, <-chan string) {
+	t.Helper()
+	msgs := make(chan string, 16)
+	up := websocket.Upgrader{Subprotocols: []string{"binary"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := up.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, login, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if string(login) != testUser+":"+testTicket+"\n" {
+			conn.WriteMessage(websocket.BinaryMessage, []byte("permission denied"))
+			return
+		}
+		conn.WriteMessage(websocket.BinaryMessage, []byte("OK"))
+		go func() {
+			defer close(msgs)
+			for {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return
+				}
+				msgs <- string(msg)
+			}
+		}()
+		serve(conn)
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), msgs
+}
+
+// waitOpen keeps a stand-in connection open until the test ends.
+func waitOpen(t *testing.T) func(*websocket.Conn) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	return func(*websocket.Conn) { <-done }
+}
+
+func dial(t *testing.T, url, ticket string) (*Session, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return Dial(ctx, url, nil, nil, testUser, ticket)
+}
+
+func next(t *testing.T, msgs <-chan string) string {
+	t.Helper()
+	select {
+	case m, ok := <-msgs:
+		if !ok {
+			t.Fatal("connection closed, want a message")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a message")
+	}
+	return ""
+}
+
+func TestLogin(t *testing.T) {
+	url, _ := standIn(t, waitOpen(t))
+	s, err := dial(t, url, testTicket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	s.Close()
+}
+
+func TestLoginRefused(t *testing.T) {
+	url, _ := standIn(t, waitOpen(t))
+	_, err := dial(t, url, "bogus")
+	if err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("Dial with a bad ticket: got %v, want a refused login", err)
+	}
+}
+
+func TestFraming(t *testing.T) {
+	url, msgs := standIn(t, waitOpen(t))
+	s, err := dial(t, url, testTicket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer s.Close()
+
+	if n, err := s.Write([]byte("ls -l\n")); err != nil || n != 6 {
+		t.Fatalf("Write = %d, %v; want 6, nil", n, err)
+	}
+	if got, want := next(t, msgs), "0:6:ls -l\n"; got != want {
+		t.Errorf("input frame = %q, want %q", got, want)
+	}
+	if err := s.Resize(120, 40); err != nil {
+		t.Fatalf("Resize: %v", err)
+	}
+	if got, want := next(t, msgs), "1:120:40:"; got != want {
+		t.Errorf("resize frame = %q, want %q", got, want)
+	}
+	if err := s.Ping(); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got, want := next(t, msgs), "2"; got != want {
+		t.Errorf("keepalive frame = %q, want %q", got, want)
+	}
+}
+
+func TestAttachEscape(t *testing.T) {
+	url, msgs := standIn(t, waitOpen(t))
+	s, err := dial(t, url, testTicket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	err = s.Attach(context.Background(), strings.NewReader("top\x1dnot sent"), io.Discard, 0x1d)
+	if !errors.Is(err, ErrDetached) {
+		t.Fatalf("Attach = %v, want ErrDetached", err)
+	}
+	var got []string
+	for m := range msgs {
+		got = append(got, m)
+	}
+	if len(got) != 1 || got[0] != "0:3:top" {
+		t.Errorf("sent %q, want only the input before the escape byte", got)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	url, _ := standIn(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.BinaryMessage, []byte("bye\r\n"))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	})
+	s, err := dial(t, url, testTicket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer s.Close()
+
+	buf := make([]byte, 64)
+	n, err := s.Read(buf)
+	if err != nil || string(buf[:n]) != "bye\r\n" {
+		t.Fatalf("Read = %q, %v; want the server output", buf[:n], err)
+	}
+	if _, err := s.Read(buf); err != io.EOF {
+		t.Fatalf("Read after close = %v, want io.EOF", err)
+	}
+}
+
+func TestAttachServerClose(t *testing.T) {
+	url, _ := standIn(t, func(conn *websocket.Conn) {
+		conn.WriteMessage(websocket.BinaryMessage, []byte("logout\r\n"))
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	})
+	s, err := dial(t, url, testTicket)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	in, _ := io.Pipe() // input that never ends, like an idle terminal
+	var out strings.Builder
+	if err := s.Attach(context.Background(), in, &out, 0x1d); err != nil {
+		t.Fatalf("Attach = %v, want nil when the server closes", err)
+	}
+	if out.String() != "logout\r\n" {
+		t.Errorf("output = %q, want %q", out.String(), "logout\r\n")
+	}
+}
@@ -0,0 +1,117 @@
+#!/usr/bin/env bash
+# Smoke tests for task-completion hooks, fired via "pxve hook test" against a
+# local HTTP listener. No Proxmox instance is needed; requires python3.
+# Usage: ./tests/test-hooks.sh [binary]
+#   binary defaults to ./dist/pxve-macos-arm64
+
+set -uo pipefail
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/helpers.sh"
+
+resolve_bin "${1:-}"
+
+echo "Running hook tests against $BIN ..."
+echo ""
+
+# ---------------------------------------------------------------------------
+# Setup: temporary HOME with a hooks config and a listener recording POSTs
+# ---------------------------------------------------------------------------
+
+WORK="$(mktemp -d)"
+PORT="${HOOK_PORT:-18765}"
+
+python3 - "$PORT" "$WORK/posted" <<'EOF' &
+import sys
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+class Handler(BaseHTTPRequestHandler):
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers.get("Content-Length", 0)))
+        with open(sys.argv[2], "ab") as f:
+            f.write(self.headers.get("X-Test", "").encode() + b" " + body + b"\n")
+        self.send_response(204)
+        self.end_headers()
+
+    def log_message(self, *args):
+        pass
+
+HTTPServer(("127.0.0.1", int(sys.argv[1])), Handler).serve_forever()
+EOF
+LISTENER=$!
+trap 'kill $LISTENER 2>/dev/null; rm -rf "$WORK"' EXIT
+sleep 1
+
+cat > "$WORK/.pxve.yaml" <<EOF
+hooks:
+  - name: web
+    url: http://127.0.0.1:$PORT/hook
+    headers:
+      X-Test: hello
+  - name: cmd
+    command: 'echo "\$PXVE_TASK_TYPE \$PXVE_TARGET \$PXVE_SUCCESS" > "$WORK/cmd"; cat >> "$WORK/cmd"'
+  - name: failures
+    command: 'touch "$WORK/failed"'
+    on: failure
+  - name: broken
+    url: http://127.0.0.1:1/unreachable
+    timeout: 2s
+EOF
+
+pxve() { HOME="$WORK" "$BIN" "$@"; }
+
+# ---------------------------------------------------------------------------
+# Tests
+# ---------------------------------------------------------------------------
+
+assert_output_contains \
+  "hook list shows configured hooks" \
+  "webhook" \
+  pxve hook list
+
+assert_output_contains \
+  "hook test fires the webhook" \
+  "Fired 1 hook(s)" \
+  pxve hook test web
+
+assert_output_contains \
+  "webhook receives custom headers" \
+  "hello " \
+  cat "$WORK/posted"
+
+assert_output_contains \
+  "webhook receives the payload" \
+  '"upid":"UPID:pve:' \
+  cat "$WORK/posted"
+
+assert \
+  "command hook succeeds" \
+  pxve hook test cmd
+
+assert_output_contains \
+  "command hook gets PXVE_* variables" \
+  "qmstart 100 true" \
+  cat "$WORK/cmd"
+
+assert_output_contains \
+  "command hook gets the payload on stdin" \
+  '"event":"task.finished"' \
+  cat "$WORK/cmd"
+
+assert \
+  "on: failure hook is skipped for successful tasks" \
+  bash -c "HOME='$WORK' '$BIN' hook test failures && test ! -e '$WORK/failed'"
+
+assert \
+  "on: failure hook fires for failed tasks" \
+  bash -c "HOME='$WORK' '$BIN' hook test failures --failed && test -e '$WORK/failed'"
+
+assert_fail \
+  "unreachable webhook is reported" \
+  pxve hook test broken
+
+assert_fail \
+  "unknown hook name is rejected" \
+  pxve hook test nope
+
+print_report
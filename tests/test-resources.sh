@@ -141,6 +141,21 @@ assert_output_contains \
   "<$id_label_lower>" \
   "$BIN" $CMD snapshot create --help
 
+assert_output_contains \
+  "$CMD snapshot create --help contains --description" \
+  "--description" \
+  "$BIN" $CMD snapshot create --help
+
+if_vm assert_output_contains \
+  "$CMD snapshot create --help contains --vmstate" \
+  "--vmstate" \
+  "$BIN" $CMD snapshot create --help
+
+assert_output_contains \
+  "$CMD snapshot edit --help contains --description" \
+  "--description" \
+  "$BIN" $CMD snapshot edit --help
+
 assert_output_contains \
   "$CMD snapshot delete --help" \
   "<$id_label_lower>" \
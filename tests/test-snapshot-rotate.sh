@@ -0,0 +1,81 @@
+#!/usr/bin/env bash
+# Offline smoke tests for "pxve snapshot rotate": the policy and flag checks
+# that run before any guest is touched. No Proxmox instance is needed.
+# Usage: ./tests/test-snapshot-rotate.sh [binary]
+#   binary defaults to ./dist/pxve-macos-arm64
+
+set -uo pipefail
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+source "$SCRIPT_DIR/helpers.sh"
+
+resolve_bin "${1:-}"
+
+echo "Running snapshot rotate tests against $BIN ..."
+echo ""
+
+# An empty HOME has no instances, so a command that got past validation would
+# still fail; the output checks make sure it failed for the right reason.
+WORK="$(mktemp -d)"
+trap 'rm -rf "$WORK"' EXIT
+
+pxve() { HOME="$WORK" PROXMOX_INSTANCE= "$BIN" "$@"; }
+
+LONG_PREFIX="abcdefghijklmnopqrstuvwxyz1234"
+
+# ---------------------------------------------------------------------------
+# Tests
+# ---------------------------------------------------------------------------
+
+assert_output_contains \
+  "snapshot --help lists rotate" \
+  "rotate" \
+  pxve snapshot --help
+
+assert_output_contains \
+  "snapshot rotate --help lists --dry-run" \
+  "--dry-run" \
+  pxve snapshot rotate --help
+
+assert_fail \
+  "snapshot rotate --keep-hourly 0 --keep-daily 0 fails" \
+  pxve snapshot rotate --selector tag=prod --keep-hourly 0 --keep-daily 0
+
+assert_output_contains \
+  "snapshot rotate rejects a policy that keeps nothing" \
+  "keeps no snapshots" \
+  pxve snapshot rotate --selector tag=prod --keep-hourly 0 --keep-daily 0
+
+assert_fail \
+  "snapshot rotate without --selector fails" \
+  pxve snapshot rotate --keep-daily 7
+
+assert_output_contains \
+  "snapshot rotate requires --selector" \
+  "--selector is required" \
+  pxve snapshot rotate --keep-daily 7
+
+assert_fail \
+  "snapshot rotate --prefix 1x fails" \
+  pxve snapshot rotate --selector tag=prod --keep-daily 7 --prefix 1x
+
+assert_output_contains \
+  "snapshot rotate rejects a prefix starting with a digit" \
+  "invalid prefix" \
+  pxve snapshot rotate --selector tag=prod --keep-daily 7 --prefix 1x
+
+assert_fail \
+  "snapshot rotate with a 30-character prefix fails" \
+  pxve snapshot rotate --selector tag=prod --keep-daily 7 --prefix "$LONG_PREFIX"
+
+assert_output_contains \
+  "snapshot rotate rejects a prefix too long for a timestamped name" \
+  "too long" \
+  pxve snapshot rotate --selector tag=prod --keep-daily 7 --prefix "$LONG_PREFIX"
+
+assert_output_contains \
+  "snapshot rotate rejects an invalid selector" \
+  "invalid selector" \
+  pxve snapshot rotate --selector bogus --keep-daily 7
+
+print_report
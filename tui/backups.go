@@ -544,9 +544,9 @@ func (m backupsScreenModel) viewOverlay() []string {
 	default:
 		lines = append(lines, "")
 		if len(m.backups) > 0 {
-			lines = append(lines, renderHelp("[Alt+d] delete  [Alt+r] restore  [/] filter  |  [Tab] Resources  |  [ctrl+r] refresh"))
+			lines = append(lines, renderHelp("[Alt+d] delete  [Alt+r] restore  [/] filter  |  [Tab] Tasks  |  [ctrl+r] refresh"))
 		} else {
-			lines = append(lines, renderHelp("[Tab] Resources  |  [ctrl+r] refresh"))
+			lines = append(lines, renderHelp("[Tab] Tasks  |  [ctrl+r] refresh"))
 		}
 	}
 
@@ -605,7 +605,7 @@ func (m backupsScreenModel) deleteBackupCmd(volid, storage, node string) tea.Cmd
 			return backupsScreenActionMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return backupsScreenActionMsg{err: werr}
 			}
 		}
@@ -653,7 +653,7 @@ func (m backupsScreenModel) restoreBackupCmd(volid, node string, vmid int, name,
 			return backupsScreenActionMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return backupsScreenActionMsg{err: werr}
 			}
 		}
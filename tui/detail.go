@@ -12,11 +12,23 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
 )
 
 // tagInputRegex validates tag names: letters, digits, hyphens, underscores, dots.
 var tagInputRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
 
+// Detail view tabs, cycled with Tab.
+const (
+	detailTabSnapshots = iota
+	detailTabBackups
+	detailTabMetrics
+	detailTabFirewall
+	detailTabHardware
+	detailTabCount
+)
+
 type detailMode int
 
 const (
@@ -38,14 +50,21 @@ const (
 	detailSelectMoveDisk                 // cursor picker: choose which disk to move
 	detailSelectMoveStorage              // cursor picker: choose target storage for move
 	detailEditConfig                     // 2-field form: name/hostname + description
+	detailSelectNIC                      // cursor picker: choose which NIC to reattach
+	detailSelectBridge                   // cursor picker: choose bridge or SDN vnet for the NIC
 )
 
-// snapshotEntry is a unified representation for both VM and CT snapshots.
+// snapshotEntry is a unified representation for both VM and CT snapshots,
+// in tree order. Current marks the guest's running state ("current"), which
+// is listed under the snapshot it was taken or rolled back from.
 type snapshotEntry struct {
 	Name        string
+	Prefix      string // tree drawing before the name
 	Parent      string
 	Description string
 	Date        string
+	RAM         bool
+	Current     bool
 }
 
 // backupEntry is a unified representation for backup items in the TUI.
@@ -147,6 +166,18 @@ type moveStoragesLoadedMsg struct {
 	err      error
 }
 
+// nicListLoadedMsg is sent when NIC enumeration completes.
+type nicListLoadedMsg struct {
+	nics map[string]string // net0 → spec string
+	err  error
+}
+
+// bridgeChoicesLoadedMsg is sent when node bridges and SDN vnets are loaded.
+type bridgeChoicesLoadedMsg struct {
+	choices []actions.BridgeChoice
+	err     error
+}
+
 // configLoadedMsg is sent when the current config is loaded for editing.
 type configLoadedMsg struct {
 	name        string
@@ -185,7 +216,8 @@ type detailModel struct {
 	actionBusy    bool
 	lastRefreshed time.Time
 
-	// Tab state: 0 = Snapshots, 1 = Backups
+	// Tab state: one of detailTabSnapshots, detailTabBackups, detailTabMetrics,
+	// detailTabFirewall, detailTabHardware
 	activeTab int
 
 	// Backup state
@@ -241,12 +273,42 @@ type detailModel struct {
 	moveStorages   []storageChoice
 	moveStorageIdx int
 
+	// NIC bridge state
+	availableNICs map[string]string
+	nicKeys       []string // sorted keys of availableNICs
+	nicIdx        int
+	pendingNIC    string
+	bridgeChoices []actions.BridgeChoice
+	bridgeIdx     int
+
 	// Filter state
 	snapFilter            tableFilter
 	backupFilter          tableFilter
 	filteredSnapIndices   []int // maps table row → m.snapshots index
 	filteredBackupIndices []int // maps table row → m.backups index
 
+	// Metrics tab state, loaded when the tab is first opened
+	metrics        []actions.MetricPoint
+	metricsLoaded  bool
+	metricsLoading bool
+	metricsLoadErr error
+	metricsTFIdx   int // index into actions.MetricTimeframes
+
+	// Firewall tab state, loaded when the tab is first opened
+	fwRules   []actions.EffectiveFirewallRule
+	fwOptions map[string]interface{}
+	fwLoaded  bool
+	fwLoading bool
+	fwLoadErr error
+	fwOffset  int // first rule line shown
+
+	// Hardware tab state, loaded when the tab is first opened
+	hwCfg     *actions.GuestConfig
+	hwLoaded  bool
+	hwLoading bool
+	hwLoadErr error
+	hwOffset  int // first line shown
+
 	width  int
 	height int
 }
@@ -305,7 +367,7 @@ func newDetailModel(c *proxmox.Client, r proxmox.ClusterResource, w, h int) deta
 		resource:         r,
 		loading:          true,
 		backupLoading:    true,
-		activeTab:        0,
+		activeTab:        detailTabSnapshots,
 		input:            ti,
 		restoreIDInput:   ridInput,
 		restoreNameInput: rnameInput,
@@ -334,15 +396,15 @@ func (m detailModel) withRebuiltSnapTable() detailModel {
 		return m
 	}
 
-	descWidth := m.width - 20 - 18 - 15 - 12 // remainder after NAME+DATE+PARENT+padding
+	descWidth := m.width - 30 - 18 - 5 - 12 // remainder after NAME+DATE+RAM+padding
 	if descWidth < 10 {
 		descWidth = 10
 	}
 
 	cols := []table.Column{
-		{Title: "NAME", Width: 20},
+		{Title: "NAME", Width: 30},
 		{Title: "DATE", Width: 18},
-		{Title: "PARENT", Width: 15},
+		{Title: "RAM", Width: 5},
 		{Title: "DESCRIPTION", Width: descWidth},
 	}
 
@@ -352,7 +414,15 @@ func (m detailModel) withRebuiltSnapTable() detailModel {
 		if !m.snapFilter.matches(s.Name, s.Description) {
 			continue
 		}
-		rows = append(rows, table.Row{s.Name, s.Date, s.Parent, s.Description})
+		if s.Current {
+			rows = append(rows, table.Row{s.Prefix + "● " + s.Name, "", "", "you are here"})
+		} else {
+			ram := ""
+			if s.RAM {
+				ram = "✓"
+			}
+			rows = append(rows, table.Row{s.Prefix + s.Name, s.Date, ram, s.Description})
+		}
 		m.filteredSnapIndices = append(m.filteredSnapIndices, i)
 	}
 
@@ -579,10 +649,15 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 		}
 		m.statusMsg = msg.message
 		m.statusErr = false
+		var cmds []tea.Cmd
 		if msg.needRefresh {
-			return m, m.refreshResourceCmd()
+			cmds = append(cmds, m.refreshResourceCmd())
 		}
-		return m, nil
+		if m.hwLoaded {
+			// Disk and NIC changes show up in the hardware tab.
+			cmds = append(cmds, m.loadHardwareCmd())
+		}
+		return m, tea.Batch(cmds...)
 
 	case allTagsLoadedMsg:
 		m.actionBusy = false
@@ -634,6 +709,49 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 		}
 		return m, nil
 
+	case metricsLoadedMsg:
+		if msg.timeframe != m.metricsTimeframe() {
+			return m, nil // timeframe changed while loading; a newer load is in flight
+		}
+		m.metricsLoading = false
+		m.metricsLoaded = true
+		if msg.err != nil {
+			m.metricsLoadErr = msg.err
+		} else {
+			m.metricsLoadErr = nil
+			m.metrics = msg.points
+		}
+		return m, nil
+
+	case firewallLoadedMsg:
+		m.fwLoading = false
+		m.fwLoaded = true
+		if msg.err != nil {
+			m.fwLoadErr = msg.err
+		} else {
+			m.fwLoadErr = nil
+			m.fwRules = msg.rules
+			m.fwOptions = msg.options
+			if m.fwOffset > len(m.fwRules) {
+				m.fwOffset = 0
+			}
+		}
+		return m, nil
+
+	case hardwareLoadedMsg:
+		m.hwLoading = false
+		m.hwLoaded = true
+		if msg.err != nil {
+			m.hwLoadErr = msg.err
+		} else {
+			m.hwLoadErr = nil
+			m.hwCfg = msg.cfg
+			if m.hwOffset > len(m.hardwareLines()) {
+				m.hwOffset = 0
+			}
+		}
+		return m, nil
+
 	case primaryDiskLoadedMsg:
 		m.diskLocation = msg.location
 		return m, nil
@@ -690,6 +808,59 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 		m.mode = detailSelectMoveStorage
 		return m, nil
 
+	case nicListLoadedMsg:
+		m.actionBusy = false
+		if msg.err != nil {
+			m.statusMsg = "Error: " + msg.err.Error()
+			m.statusErr = true
+			return m, nil
+		}
+		if len(msg.nics) == 0 {
+			m.statusMsg = "No network devices found"
+			m.statusErr = true
+			return m, nil
+		}
+		m.availableNICs = msg.nics
+		keys := make([]string, 0, len(msg.nics))
+		for k := range msg.nics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		m.nicKeys = keys
+		if len(keys) == 1 {
+			m.pendingNIC = keys[0]
+			m.actionBusy = true
+			m.statusMsg = "Loading bridges..."
+			return m, tea.Batch(m.loadBridgeChoicesCmd(), m.spinner.Tick)
+		}
+		m.nicIdx = 0
+		m.mode = detailSelectNIC
+		return m, nil
+
+	case bridgeChoicesLoadedMsg:
+		m.actionBusy = false
+		if msg.err != nil {
+			m.statusMsg = "Error: " + msg.err.Error()
+			m.statusErr = true
+			return m, nil
+		}
+		if len(msg.choices) == 0 {
+			m.statusMsg = "No bridges or vnets found"
+			m.statusErr = true
+			return m, nil
+		}
+		m.bridgeChoices = msg.choices
+		// Start on the NIC's current bridge.
+		m.bridgeIdx = 0
+		current := nicOption(m.availableNICs[m.pendingNIC], "bridge")
+		for i, b := range msg.choices {
+			if b.Name == current {
+				m.bridgeIdx = i
+			}
+		}
+		m.mode = detailSelectBridge
+		return m, nil
+
 	case tagUpdatedMsg:
 		m.actionBusy = false
 		m.resource.Tags = msg.newTags
@@ -712,7 +883,7 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 	case spinner.TickMsg:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
-		if m.loading || m.backupLoading || m.actionBusy {
+		if m.loading || m.backupLoading || m.metricsLoading || m.fwLoading || m.actionBusy {
 			return m, cmd
 		}
 		return m, nil
@@ -745,6 +916,10 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 			return m.handleSelectMoveDiskMode(msg)
 		case detailSelectMoveStorage:
 			return m.handleSelectMoveStorageMode(msg)
+		case detailSelectNIC:
+			return m.handleSelectNICMode(msg)
+		case detailSelectBridge:
+			return m.handleSelectBridgeMode(msg)
 		case detailTagManage:
 			return m.handleTagManageMode(msg)
 		case detailTagSelect:
@@ -766,14 +941,31 @@ func (m detailModel) update(msg tea.Msg) (detailModel, tea.Cmd) {
 		return m, nil
 	}
 	var cmd tea.Cmd
-	if m.activeTab == 0 {
+	switch m.activeTab {
+	case detailTabSnapshots:
 		m.snapTable, cmd = m.snapTable.Update(msg)
-	} else {
+	case detailTabBackups:
 		m.backupTable, cmd = m.backupTable.Update(msg)
 	}
 	return m, cmd
 }
 
+// snapshotCounts returns how many snapshots the filter shows and how many
+// there are, not counting the "current" row.
+func (m detailModel) snapshotCounts() (shown, total int) {
+	for _, s := range m.snapshots {
+		if !s.Current {
+			total++
+		}
+	}
+	for _, i := range m.filteredSnapIndices {
+		if !m.snapshots[i].Current {
+			shown++
+		}
+	}
+	return shown, total
+}
+
 func (m detailModel) selectedSnapshotName() string {
 	if len(m.filteredSnapIndices) == 0 {
 		return ""
@@ -782,7 +974,11 @@ func (m detailModel) selectedSnapshotName() string {
 	if cursor < 0 || cursor >= len(m.filteredSnapIndices) {
 		return ""
 	}
-	return m.snapshots[m.filteredSnapIndices[cursor]].Name
+	s := m.snapshots[m.filteredSnapIndices[cursor]]
+	if s.Current {
+		return "" // the running state can't be deleted or rolled back to
+	}
+	return s.Name
 }
 
 func (m detailModel) selectedBackupInfo() (volid, storage string) {
@@ -799,8 +995,11 @@ func (m detailModel) selectedBackupInfo() (volid, storage string) {
 
 // activeFilter returns the filter for the currently active tab.
 func (m detailModel) activeFilter() tableFilter {
-	if m.activeTab == 0 {
+	switch m.activeTab {
+	case detailTabSnapshots:
 		return m.snapFilter
+	case detailTabBackups:
+		return m.backupFilter
 	}
-	return m.backupFilter
+	return tableFilter{}
 }
@@ -3,6 +3,7 @@ package tui
 import (
 	"context"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
 
@@ -10,6 +11,7 @@ import (
 	proxmox "github.com/luthermonson/go-proxmox"
 
 	"github.com/chupakbra/proxmox-cli/internal/actions"
+	"github.com/chupakbra/proxmox-cli/internal/desktop"
 )
 
 func (m detailModel) loadSnapshotsCmd() tea.Cmd {
@@ -17,40 +19,33 @@ func (m detailModel) loadSnapshotsCmd() tea.Cmd {
 	r := m.resource
 	return func() tea.Msg {
 		ctx := context.Background()
-		var entries []snapshotEntry
-
+		var snaps []*actions.GuestSnapshot
+		var err error
 		if r.Type == "qemu" {
-			snaps, err := actions.VMSnapshots(ctx, c, int(r.VMID), r.Node)
-			if err != nil {
-				return detailLoadedMsg{err: err}
-			}
-			for _, s := range snaps {
-				if s.Name == "current" {
-					continue
-				}
-				entries = append(entries, snapshotEntry{
-					Name:        s.Name,
-					Parent:      s.Parent,
-					Description: s.Description,
-					Date:        formatSnapTime(s.Snaptime),
-				})
-			}
+			snaps, err = actions.VMSnapshots(ctx, c, int(r.VMID), r.Node)
 		} else {
-			snaps, err := actions.ContainerSnapshots(ctx, c, int(r.VMID), r.Node)
-			if err != nil {
-				return detailLoadedMsg{err: err}
-			}
-			for _, s := range snaps {
-				if s.Name == "current" {
-					continue
-				}
-				entries = append(entries, snapshotEntry{
-					Name:        s.Name,
-					Parent:      s.Parent,
-					Description: s.Description,
-					Date:        formatSnapTime(s.SnapshotCreationTime),
-				})
-			}
+			snaps, err = actions.ContainerSnapshots(ctx, c, int(r.VMID), r.Node)
+		}
+		if err != nil {
+			return detailLoadedMsg{err: err}
+		}
+		tree := actions.SnapshotTree(snaps)
+		if len(tree) <= 1 {
+			return detailLoadedMsg{} // only "current": no snapshots
+		}
+
+		var entries []snapshotEntry
+		for _, s := range tree {
+			desc, _, _ := strings.Cut(strings.TrimSpace(s.Description), "\n")
+			entries = append(entries, snapshotEntry{
+				Name:        s.Name,
+				Prefix:      s.Prefix,
+				Parent:      s.Parent,
+				Description: desc,
+				Date:        formatSnapTime(s.Snaptime),
+				RAM:         s.VMState,
+				Current:     s.IsCurrent(),
+			})
 		}
 		return detailLoadedMsg{snapshots: entries}
 	}
@@ -160,7 +155,7 @@ func (m detailModel) cloneResourceCmd(newid int, name string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -178,7 +173,7 @@ func (m detailModel) createBackupCmd(storageName string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -196,7 +191,7 @@ func (m detailModel) deleteBackupCmd(volid, storage string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -214,7 +209,7 @@ func (m detailModel) restoreBackupCmd(volid string, vmid int, name, restoreStora
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -240,7 +235,7 @@ func (m detailModel) deleteResourceCmd() tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -272,7 +267,7 @@ func (m detailModel) convertToTemplateCmd() tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 120); werr != nil {
+			if werr := waitTask(ctx, task, 120); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -340,7 +335,7 @@ func (m detailModel) powerCmd(action string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -427,7 +422,7 @@ func (m detailModel) addTagCmd(tag string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 60); werr != nil {
+			if werr := waitTask(ctx, task, 60); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -454,7 +449,7 @@ func (m detailModel) removeTagCmd(tag string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 60); werr != nil {
+			if werr := waitTask(ctx, task, 60); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -471,6 +466,50 @@ func (m detailModel) removeTagCmd(tag string) tea.Cmd {
 	}
 }
 
+// openConsoleCmd opens the guest's graphical console: a SPICE viewer for
+// VMs with a SPICE display, otherwise the web UI's noVNC console in the
+// browser. Without a browser, the URL is shown instead.
+func (m detailModel) openConsoleCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	return func() tea.Msg {
+		ctx := context.Background()
+		cfg, err := actions.GetGuestConfig(ctx, c, r.Type, int(r.VMID), r.Node)
+		if err != nil {
+			return actionResultMsg{err: err}
+		}
+		if r.Type == "qemu" && actions.HasSpiceDisplay(cfg) {
+			vv, err := actions.SpiceViewerFile(ctx, c, cfg, "")
+			if err != nil {
+				return actionResultMsg{err: err}
+			}
+			f, err := os.CreateTemp("", fmt.Sprintf("pxve-%d-*.vv", r.VMID))
+			if err != nil {
+				return actionResultMsg{err: err}
+			}
+			_, err = f.Write(vv)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return actionResultMsg{err: err}
+			}
+			if err := desktop.OpenViewer(f.Name()); err != nil {
+				return actionResultMsg{err: fmt.Errorf("%w (install virt-viewer for remote-viewer)", err)}
+			}
+			return actionResultMsg{message: "Opened SPICE console"}
+		}
+		u, err := actions.NoVNCURL(ctx, c, cfg)
+		if err != nil {
+			return actionResultMsg{err: err}
+		}
+		if err := desktop.Open(u); err != nil {
+			return actionResultMsg{message: "Console: " + u}
+		}
+		return actionResultMsg{message: "Opened noVNC console in the browser"}
+	}
+}
+
 func (m detailModel) loadConfigCmd() tea.Cmd {
 	c := m.client
 	r := m.resource
@@ -507,7 +546,7 @@ func (m detailModel) updateConfigCmd(name, description string) tea.Cmd {
 				return actionResultMsg{err: err}
 			}
 			if task != nil {
-				if werr := task.WaitFor(ctx, 120); werr != nil {
+				if werr := waitTask(ctx, task, 120); werr != nil {
 					return actionResultMsg{err: werr}
 				}
 			}
@@ -521,7 +560,7 @@ func (m detailModel) updateConfigCmd(name, description string) tea.Cmd {
 				return actionResultMsg{err: err}
 			}
 			if task != nil {
-				if werr := task.WaitFor(ctx, 120); werr != nil {
+				if werr := waitTask(ctx, task, 120); werr != nil {
 					return actionResultMsg{err: werr}
 				}
 			}
@@ -610,7 +649,7 @@ func (m detailModel) moveDiskCmd(disk, storage string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -618,6 +657,111 @@ func (m detailModel) moveDiskCmd(disk, storage string) tea.Cmd {
 	}
 }
 
+func (m detailModel) loadNICsCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	return func() tea.Msg {
+		ctx := context.Background()
+		nics := make(map[string]string)
+		if r.Type == "qemu" {
+			cfg, err := actions.GetVMConfig(ctx, c, int(r.VMID), r.Node)
+			if err != nil {
+				return nicListLoadedMsg{err: err}
+			}
+			for k, v := range cfg.MergeNets() {
+				if v != "" {
+					nics[k] = v
+				}
+			}
+		} else {
+			cfg, err := actions.GetContainerConfig(ctx, c, int(r.VMID), r.Node)
+			if err != nil {
+				return nicListLoadedMsg{err: err}
+			}
+			for k, v := range cfg.MergeNets() {
+				if v != "" {
+					nics[k] = v
+				}
+			}
+		}
+		return nicListLoadedMsg{nics: nics}
+	}
+}
+
+func (m detailModel) loadBridgeChoicesCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	return func() tea.Msg {
+		choices, err := actions.BridgeChoices(context.Background(), c, r.Node)
+		return bridgeChoicesLoadedMsg{choices: choices, err: err}
+	}
+}
+
+// nicOption returns the value of key in a NIC spec such as
+// "virtio=BC:24:11:00:00:01,bridge=vmbr0,firewall=1".
+func nicOption(spec, key string) string {
+	for _, part := range strings.Split(spec, ",") {
+		if k, v, ok := strings.Cut(part, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// withNICOption returns spec with key set to value, keeping the other options
+// (model, MAC address, ...) in place.
+func withNICOption(spec, key, value string) string {
+	parts := strings.Split(spec, ",")
+	for i, part := range parts {
+		if k, _, ok := strings.Cut(part, "="); ok && k == key {
+			parts[i] = key + "=" + value
+			return strings.Join(parts, ",")
+		}
+	}
+	return strings.Join(append(parts, key+"="+value), ",")
+}
+
+// withoutNICOption returns spec with key removed.
+func withoutNICOption(spec, key string) string {
+	var kept []string
+	for _, part := range strings.Split(spec, ",") {
+		if k, _, _ := strings.Cut(part, "="); k != key {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+func (m detailModel) setNICBridgeCmd(nic string, bridge actions.BridgeChoice) tea.Cmd {
+	c := m.client
+	r := m.resource
+	spec := withNICOption(m.availableNICs[nic], "bridge", bridge.Name)
+	if !bridge.VLANAware {
+		// The vnet tags the traffic; a guest-side tag would be rejected.
+		spec = withoutNICOption(spec, "tag")
+	}
+	return func() tea.Msg {
+		ctx := context.Background()
+		vmid := int(r.VMID)
+		var task *proxmox.Task
+		var err error
+		if r.Type == "qemu" {
+			task, err = actions.ConfigVM(ctx, c, vmid, r.Node, []proxmox.VirtualMachineOption{{Name: nic, Value: spec}})
+		} else {
+			task, err = actions.ConfigContainer(ctx, c, vmid, r.Node, []proxmox.ContainerOption{{Name: nic, Value: spec}})
+		}
+		if err != nil {
+			return actionResultMsg{err: err}
+		}
+		if task != nil {
+			if werr := waitTask(ctx, task, 120); werr != nil {
+				return actionResultMsg{err: werr}
+			}
+		}
+		return actionResultMsg{message: fmt.Sprintf("%s attached to %s", nic, bridge.Name)}
+	}
+}
+
 func (m detailModel) resizeDiskCmd(disk, size string) tea.Cmd {
 	c := m.client
 	r := m.resource
@@ -635,7 +779,7 @@ func (m detailModel) resizeDiskCmd(disk, size string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -653,15 +797,15 @@ func (m detailModel) createSnapshotCmd(name string) tea.Cmd {
 		vmid := int(r.VMID)
 
 		if r.Type == "qemu" {
-			task, err = actions.CreateVMSnapshot(ctx, c, vmid, r.Node, name)
+			task, err = actions.CreateVMSnapshot(ctx, c, vmid, r.Node, name, actions.SnapshotOptions{})
 		} else {
-			task, err = actions.CreateContainerSnapshot(ctx, c, vmid, r.Node, name)
+			task, err = actions.CreateContainerSnapshot(ctx, c, vmid, r.Node, name, actions.SnapshotOptions{})
 		}
 		if err != nil {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -687,7 +831,7 @@ func (m detailModel) deleteSnapshotCmd(name string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -713,7 +857,7 @@ func (m detailModel) rollbackSnapshotCmd(name string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
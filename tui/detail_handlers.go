@@ -7,6 +7,8 @@ import (
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
 )
 
 // startAction sets the busy state with a status message and batches the given
@@ -623,12 +625,63 @@ func (m detailModel) handleSelectMoveStorageMode(msg tea.KeyMsg) (detailModel, t
 	return m, nil
 }
 
+func (m detailModel) handleSelectNICMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.nicIdx > 0 {
+			m.nicIdx--
+		}
+	case "down", "j":
+		if m.nicIdx < len(m.nicKeys)-1 {
+			m.nicIdx++
+		}
+	case "enter":
+		m.pendingNIC = m.nicKeys[m.nicIdx]
+		m.mode = detailNormal
+		m.actionBusy = true
+		m.statusMsg = "Loading bridges..."
+		return m, tea.Batch(m.loadBridgeChoicesCmd(), m.spinner.Tick)
+	case "esc":
+		m.mode = detailNormal
+	}
+	return m, nil
+}
+
+func (m detailModel) handleSelectBridgeMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.bridgeIdx > 0 {
+			m.bridgeIdx--
+		}
+	case "down", "j":
+		if m.bridgeIdx < len(m.bridgeChoices)-1 {
+			m.bridgeIdx++
+		}
+	case "enter":
+		bridge := m.bridgeChoices[m.bridgeIdx]
+		m.mode = detailNormal
+		if bridge.Name == nicOption(m.availableNICs[m.pendingNIC], "bridge") {
+			m.statusMsg = fmt.Sprintf("%s is already on %s", m.pendingNIC, bridge.Name)
+			m.statusErr = false
+			m.pendingNIC = ""
+			return m, nil
+		}
+		m.actionBusy = true
+		m.statusMsg = fmt.Sprintf("Attaching %s to %s...", m.pendingNIC, bridge.Name)
+		return m, tea.Batch(m.setNICBridgeCmd(m.pendingNIC, bridge), m.spinner.Tick)
+	case "esc":
+		m.mode = detailNormal
+		m.pendingNIC = ""
+	}
+	return m, nil
+}
+
 // handleNormalMode handles key events in detailNormal mode.  It includes table
 // delegation for unmatched keys so that arrow-key navigation continues to work
 // when no action overlay is active.
 func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 	// Filter input mode.
-	if m.activeTab == 0 && m.snapFilter.active {
+	if m.activeTab == detailTabSnapshots && m.snapFilter.active {
 		var rebuild bool
 		m.snapFilter, rebuild = m.snapFilter.handleKey(msg)
 		if rebuild {
@@ -636,7 +689,7 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		}
 		return m, nil
 	}
-	if m.activeTab == 1 && m.backupFilter.active {
+	if m.activeTab == detailTabBackups && m.backupFilter.active {
 		var rebuild bool
 		m.backupFilter, rebuild = m.backupFilter.handleKey(msg)
 		if rebuild {
@@ -647,17 +700,18 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 
 	switch msg.String() {
 	case "/":
-		if m.activeTab == 0 {
+		switch m.activeTab {
+		case detailTabSnapshots:
 			m.snapFilter.active = true
-		} else {
+		case detailTabBackups:
 			m.backupFilter.active = true
 		}
 		return m, nil
 	case "ctrl+u":
-		if m.activeTab == 0 && m.snapFilter.hasActiveFilter() {
+		if m.activeTab == detailTabSnapshots && m.snapFilter.hasActiveFilter() {
 			m.snapFilter.text = ""
 			m = m.withRebuiltSnapTable()
-		} else if m.activeTab == 1 && m.backupFilter.hasActiveFilter() {
+		} else if m.activeTab == detailTabBackups && m.backupFilter.hasActiveFilter() {
 			m.backupFilter.text = ""
 			m = m.withRebuiltBackupTable()
 		}
@@ -685,6 +739,8 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		return m, nil
 	case "E":
 		return m.startAction("Loading config...", m.loadConfigCmd())
+	case "v":
+		return m.startAction("Opening console...", m.openConsoleCmd())
 	case "alt+z", "Ω":
 		defaultDisk := "scsi0"
 		if m.resource.Type == "lxc" {
@@ -706,6 +762,8 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		return m, nil
 	case "alt+m", "µ":
 		return m.startAction("Loading disk info...", m.loadDisksCmd())
+	case "alt+n", "˜":
+		return m.startAction("Loading network devices...", m.loadNICsCmd())
 	case "ctrl+r", "f5":
 		m.loading = true
 		m.loadErr = nil
@@ -713,31 +771,56 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		m.backupLoadErr = nil
 		m.statusMsg = ""
 		m.statusErr = false
-		return m, tea.Batch(m.loadSnapshotsCmd(), m.loadBackupsCmd(), m.refreshResourceCmd(), m.spinner.Tick)
+		cmds := []tea.Cmd{m.loadSnapshotsCmd(), m.loadBackupsCmd(), m.refreshResourceCmd(), m.spinner.Tick}
+		if m.metricsLoaded || m.activeTab == detailTabMetrics {
+			m.metricsLoading = true
+			m.metricsLoadErr = nil
+			cmds = append(cmds, m.loadMetricsCmd())
+		}
+		if m.fwLoaded || m.activeTab == detailTabFirewall {
+			m.fwLoading = true
+			m.fwLoadErr = nil
+			cmds = append(cmds, m.loadFirewallCmd())
+		}
+		if m.hwLoaded || m.activeTab == detailTabHardware {
+			m.hwLoading = true
+			m.hwLoadErr = nil
+			cmds = append(cmds, m.loadHardwareCmd())
+		}
+		return m, tea.Batch(cmds...)
 	case "tab":
-		if m.activeTab == 0 {
-			m.activeTab = 1
-		} else {
-			m.activeTab = 0
+		m.activeTab = (m.activeTab + 1) % detailTabCount
+		if m.activeTab == detailTabMetrics && !m.metricsLoaded && !m.metricsLoading {
+			m.metricsLoading = true
+			return m, tea.Batch(m.loadMetricsCmd(), m.spinner.Tick)
+		}
+		if m.activeTab == detailTabFirewall && !m.fwLoaded && !m.fwLoading {
+			m.fwLoading = true
+			return m, tea.Batch(m.loadFirewallCmd(), m.spinner.Tick)
+		}
+		if m.activeTab == detailTabHardware && !m.hwLoaded && !m.hwLoading {
+			m.hwLoading = true
+			return m, tea.Batch(m.loadHardwareCmd(), m.spinner.Tick)
 		}
 		return m, nil
 	}
 
 	// Tab-specific keys in normal mode.
-	if m.activeTab == 0 {
+	switch m.activeTab {
+	case detailTabSnapshots:
 		switch msg.String() {
 		case "alt+s", "ß":
 			m.mode = detailInputName
 			m.input.Focus()
 			return m, textinput.Blink
 		case "alt+d", "∂":
-			if len(m.snapshots) == 0 {
+			if m.selectedSnapshotName() == "" {
 				return m, nil
 			}
 			m.mode = detailConfirmDelete
 			return m, nil
 		case "alt+r", "®":
-			if len(m.snapshots) == 0 {
+			if m.selectedSnapshotName() == "" {
 				return m, nil
 			}
 			m.mode = detailConfirmRollback
@@ -745,7 +828,7 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		case "esc":
 			return m, nil
 		}
-	} else {
+	case detailTabBackups:
 		switch msg.String() {
 		case "alt+b", "∫":
 			m.mode = detailSelectBackupStorage
@@ -769,13 +852,49 @@ func (m detailModel) handleNormalMode(msg tea.KeyMsg) (detailModel, tea.Cmd) {
 		case "esc":
 			return m, nil
 		}
+	case detailTabMetrics:
+		switch msg.String() {
+		case "t":
+			m.metricsTFIdx = (m.metricsTFIdx + 1) % len(actions.MetricTimeframes)
+			m.metricsLoading = true
+			m.metricsLoadErr = nil
+			return m, tea.Batch(m.loadMetricsCmd(), m.spinner.Tick)
+		}
+		return m, nil
+	case detailTabFirewall:
+		maxOffset := len(m.fwRules) - m.firewallPageSize()
+		switch msg.String() {
+		case "down", "j":
+			if m.fwOffset < maxOffset {
+				m.fwOffset++
+			}
+		case "up", "k":
+			if m.fwOffset > 0 {
+				m.fwOffset--
+			}
+		}
+		return m, nil
+	case detailTabHardware:
+		maxOffset := len(m.hardwareLines()) - m.firewallPageSize()
+		switch msg.String() {
+		case "down", "j":
+			if m.hwOffset < maxOffset {
+				m.hwOffset++
+			}
+		case "up", "k":
+			if m.hwOffset > 0 {
+				m.hwOffset--
+			}
+		}
+		return m, nil
 	}
 
 	// Unmatched key: delegate to the active tab's table for navigation.
 	var cmd tea.Cmd
-	if m.activeTab == 0 {
+	switch m.activeTab {
+	case detailTabSnapshots:
 		m.snapTable, cmd = m.snapTable.Update(msg)
-	} else {
+	case detailTabBackups:
 		m.backupTable, cmd = m.backupTable.Update(msg)
 	}
 	return m, cmd
@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
 )
 
 // typeStr returns "VM" for qemu resources and "CT" for lxc resources.
@@ -66,8 +68,8 @@ func (m detailModel) view() string {
 		lines = append(lines, StyleDim.Render("Tags: ")+StyleTag.Render(strings.Join(tags, ", ")))
 	}
 
-	lines = append(lines, renderHelp("[s] start  [S] stop  [U] shutdown  [R] reboot  [c] clone  [D] delete  [T] template  [E] edit"))
-	lines = append(lines, renderHelp("[Alt+z] resize disk  [Alt+m] move disk  [Alt+t] tags"))
+	lines = append(lines, renderHelp("[s] start  [S] stop  [U] shutdown  [R] reboot  [c] clone  [D] delete  [T] template  [E] edit  [v] console"))
+	lines = append(lines, renderHelp("[Alt+z] resize disk  [Alt+m] move disk  [Alt+n] network  [Alt+t] tags"))
 	lines = append(lines, sep)
 
 	// Tab bar
@@ -75,10 +77,17 @@ func (m detailModel) view() string {
 	lines = append(lines, "")
 
 	// Tab content
-	if m.activeTab == 0 {
+	switch m.activeTab {
+	case detailTabSnapshots:
 		lines = append(lines, m.viewSnapshotsTab()...)
-	} else {
+	case detailTabBackups:
 		lines = append(lines, m.viewBackupsTab()...)
+	case detailTabMetrics:
+		lines = append(lines, m.viewMetricsTab()...)
+	case detailTabFirewall:
+		lines = append(lines, m.viewFirewallTab()...)
+	case detailTabHardware:
+		lines = append(lines, m.viewHardwareTab()...)
 	}
 
 	// Status/spinner feedback line.
@@ -103,10 +112,10 @@ func (m detailModel) view() string {
 
 func (m detailModel) renderTabBar() string {
 	var snapLabel, backupLabel string
-	if m.snapFilter.hasActiveFilter() {
-		snapLabel = fmt.Sprintf("Snapshots (%d/%d)", len(m.filteredSnapIndices), len(m.snapshots))
+	if shown, total := m.snapshotCounts(); m.snapFilter.hasActiveFilter() {
+		snapLabel = fmt.Sprintf("Snapshots (%d/%d)", shown, total)
 	} else {
-		snapLabel = fmt.Sprintf("Snapshots (%d)", len(m.snapshots))
+		snapLabel = fmt.Sprintf("Snapshots (%d)", total)
 	}
 	if m.backupFilter.hasActiveFilter() {
 		backupLabel = fmt.Sprintf("Backups (%d/%d)", len(m.filteredBackupIndices), len(m.backups))
@@ -114,12 +123,22 @@ func (m detailModel) renderTabBar() string {
 		backupLabel = fmt.Sprintf("Backups (%d)", len(m.backups))
 	}
 
-	if m.activeTab == 0 {
-		return StyleTitle.Render(snapLabel) + "  " + StyleDim.Render(backupLabel) +
-			"                " + renderHelp("[Tab] switch")
+	metricsLabel := fmt.Sprintf("Metrics (%s)", m.metricsTimeframe())
+
+	fwLabel := "Firewall"
+	if m.fwLoaded && m.fwLoadErr == nil {
+		fwLabel = fmt.Sprintf("Firewall (%d)", len(m.fwRules))
+	}
+
+	labels := []string{snapLabel, backupLabel, metricsLabel, fwLabel, "Hardware"}
+	for i, l := range labels {
+		if i == m.activeTab {
+			labels[i] = StyleTitle.Render(l)
+		} else {
+			labels[i] = StyleDim.Render(l)
+		}
 	}
-	return StyleDim.Render(snapLabel) + "  " + StyleTitle.Render(backupLabel) +
-		"                " + renderHelp("[Tab] switch")
+	return strings.Join(labels, "  ") + "                " + renderHelp("[Tab] switch")
 }
 
 func (m detailModel) viewSnapshotsTab() []string {
@@ -349,6 +368,41 @@ func (m detailModel) viewOverlay() []string {
 		}
 		lines = append(lines, renderHelp("[↑/↓] navigate   [Enter] select   [Esc] cancel"))
 
+	case detailSelectNIC:
+		lines = append(lines, "")
+		lines = append(lines, StyleWarning.Render("Select network device:"))
+		for i, k := range m.nicKeys {
+			cursor := "  "
+			if i == m.nicIdx {
+				cursor = "> "
+			}
+			lines = append(lines, StyleWarning.Render(fmt.Sprintf("%s%s  %s", cursor, k, m.availableNICs[k])))
+		}
+		lines = append(lines, renderHelp("[↑/↓] navigate   [Enter] select   [Esc] cancel"))
+
+	case detailSelectBridge:
+		current := nicOption(m.availableNICs[m.pendingNIC], "bridge")
+		lines = append(lines, "")
+		lines = append(lines, StyleWarning.Render(fmt.Sprintf("Attach %s to:", m.pendingNIC)))
+		for i, b := range m.bridgeChoices {
+			cursor := "  "
+			if i == m.bridgeIdx {
+				cursor = "> "
+			}
+			label := b.Name
+			if b.Zone != "" {
+				label += fmt.Sprintf(" (SDN vnet, zone %s)", b.Zone)
+			}
+			if b.Comment != "" {
+				label += "  " + b.Comment
+			}
+			if b.Name == current {
+				label += "  [current]"
+			}
+			lines = append(lines, StyleWarning.Render(cursor+label))
+		}
+		lines = append(lines, renderHelp("[↑/↓] navigate   [Enter] select   [Esc] cancel"))
+
 	case detailEditConfig:
 		nameLabel := "Name"
 		if m.resource.Type == "lxc" {
@@ -369,37 +423,35 @@ func (m detailModel) viewOverlay() []string {
 
 	default:
 		lines = append(lines, "")
-		if m.activeTab == 0 {
+		switch m.activeTab {
+		case detailTabSnapshots:
 			if len(m.snapshots) > 0 {
 				lines = append(lines, renderHelp("[Alt+s] new  [Alt+d] delete  [Alt+r] rollback  [/] filter  |  [ctrl+r] refresh"))
 			} else {
 				lines = append(lines, renderHelp("[Alt+s] new snapshot"))
 			}
-		} else {
+		case detailTabBackups:
 			if len(m.backups) > 0 {
 				lines = append(lines, renderHelp("[Alt+b] backup  [Alt+d] delete  [Alt+r] restore  [/] filter  |  [ctrl+r] refresh"))
 			} else {
 				lines = append(lines, renderHelp("[Alt+b] new backup"))
 			}
+		case detailTabMetrics:
+			lines = append(lines, renderHelp("[t] timeframe  |  [ctrl+r] refresh"))
+		case detailTabFirewall:
+			lines = append(lines, renderHelp("[↑/↓] scroll  |  [ctrl+r] refresh  |  edit with 'pxve firewall'"))
+		case detailTabHardware:
+			lines = append(lines, renderHelp("[↑/↓] scroll  |  [ctrl+r] refresh  |  "+m.hardwareEditHint()))
 		}
 	}
 
 	return lines
 }
 
-// primaryAgentIP returns the first non-loopback IPv4 address from agent interfaces.
+// primaryAgentIP returns the primary IPv4 address from agent interfaces, the
+// same one the list's IP column shows.
 func (m detailModel) primaryAgentIP() string {
-	for _, iface := range m.agentNetIfaces {
-		if iface.Name == "lo" {
-			continue
-		}
-		for _, addr := range iface.IPAddresses {
-			if addr.IPAddressType == "ipv4" {
-				return addr.IPAddress
-			}
-		}
-	}
-	return ""
+	return actions.PrimaryAgentIPv4(m.agentNetIfaces)
 }
 
 // formatUptime converts seconds to a human-readable uptime string.
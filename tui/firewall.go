@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// firewallLoadedMsg is sent when the guest's effective firewall rules and
+// options for the detail firewall tab load.
+type firewallLoadedMsg struct {
+	rules   []actions.EffectiveFirewallRule
+	options map[string]interface{}
+	err     error
+}
+
+func (m detailModel) loadFirewallCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	return func() tea.Msg {
+		ctx := context.Background()
+		s := actions.FirewallScope{Node: r.Node, VMID: int(r.VMID), GuestType: r.Type}
+		rules, err := actions.EffectiveGuestFirewall(ctx, c, s)
+		if err != nil {
+			return firewallLoadedMsg{err: err}
+		}
+		opts, err := actions.FirewallOptions(ctx, c, s)
+		return firewallLoadedMsg{rules: rules, options: opts, err: err}
+	}
+}
+
+// firewallPageSize is the number of rule lines shown at once; it matches the
+// snapshot/backup table height.
+func (m detailModel) firewallPageSize() int {
+	n := m.height - 19
+	if n < 3 {
+		n = 3
+	}
+	return n
+}
+
+func (m detailModel) viewFirewallTab() []string {
+	var lines []string
+	switch {
+	case m.fwLoading && !m.fwLoaded:
+		lines = append(lines, StyleWarning.Render(m.spinner.View()+" Loading firewall..."))
+	case m.fwLoadErr != nil:
+		lines = append(lines, StyleError.Render("  Error: "+m.fwLoadErr.Error()))
+		lines = append(lines, renderHelp("  [ctrl+r] retry"))
+	default:
+		lines = append(lines, m.renderFirewallOptions())
+		if len(m.fwRules) == 0 {
+			lines = append(lines, StyleDim.Render("  No firewall rules"))
+			break
+		}
+		lines = append(lines, StyleDim.Render(fmt.Sprintf("  %-5s %-4s %-5s %-12s %-10s %-6s %-18s %-18s %-12s %s",
+			"POS", "ON", "DIR", "ACTION", "MACRO", "PROTO", "SOURCE", "DEST", "DPORT", "COMMENT")))
+		end := m.fwOffset + m.firewallPageSize()
+		if end > len(m.fwRules) {
+			end = len(m.fwRules)
+		}
+		for _, r := range m.fwRules[m.fwOffset:end] {
+			lines = append(lines, renderFirewallRule(r))
+		}
+		if len(m.fwRules) > m.firewallPageSize() {
+			lines = append(lines, StyleDim.Render(fmt.Sprintf("  rules %d-%d of %d", m.fwOffset+1, end, len(m.fwRules))))
+		}
+	}
+	// Keep the same footer spacing as the table tabs (filter line).
+	lines = append(lines, "")
+	return lines
+}
+
+// renderFirewallOptions summarises whether the guest firewall is enabled and
+// its default policies.
+func (m detailModel) renderFirewallOptions() string {
+	opt := func(k, def string) string {
+		if v, ok := m.fwOptions[k]; ok {
+			return fmt.Sprint(v)
+		}
+		return def
+	}
+	enabled := StyleError.Render("disabled")
+	if opt("enable", "0") == "1" {
+		enabled = StyleSuccess.Render("enabled")
+	}
+	return "  " + StyleDim.Render("Firewall: ") + enabled +
+		StyleDim.Render("   Policy in: ") + opt("policy_in", "DROP") +
+		StyleDim.Render("   Policy out: ") + opt("policy_out", "ACCEPT")
+}
+
+// renderFirewallRule formats one rule line. Rules expanded from a security
+// group are indented under their group reference; disabled rules are dimmed.
+func renderFirewallRule(r actions.EffectiveFirewallRule) string {
+	pos := strconv.Itoa(r.Pos)
+	if r.Group != "" {
+		pos = "└" + pos
+	}
+	on := "yes"
+	if r.Enable == 0 {
+		on = "no"
+	}
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	comment := r.Comment
+	if r.Group != "" {
+		comment = strings.TrimSpace("[" + r.Group + "] " + comment)
+	}
+	line := fmt.Sprintf("  %-5s %-4s %-5s %-12s %-10s %-6s %-18s %-18s %-12s %s",
+		pos, on, r.Type, runewidth.Truncate(r.Action, 12, "…"), runewidth.Truncate(dash(r.Macro), 10, "…"), dash(r.Proto),
+		runewidth.Truncate(dash(r.Source), 18, "…"), runewidth.Truncate(dash(r.Dest), 18, "…"), runewidth.Truncate(dash(r.Dport), 12, "…"), comment)
+	if r.Enable == 0 {
+		return StyleDim.Render(line)
+	}
+	return line
+}
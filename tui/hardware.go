@@ -0,0 +1,124 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// hardwareLoadedMsg is sent when the guest's config for the detail hardware
+// tab loads.
+type hardwareLoadedMsg struct {
+	cfg *actions.GuestConfig
+	err error
+}
+
+func (m detailModel) loadHardwareCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	return func() tea.Msg {
+		cfg, err := actions.GetGuestConfig(context.Background(), c, r.Type, int(r.VMID), r.Node)
+		return hardwareLoadedMsg{cfg: cfg, err: err}
+	}
+}
+
+// hardwareSection groups the config keys matching re under a title.
+type hardwareSection struct {
+	title string
+	re    *regexp.Regexp
+}
+
+var (
+	vmHardwareSections = []hardwareSection{
+		{"Disks", regexp.MustCompile(`^(scsi|virtio|sata|ide|efidisk|tpmstate)\d+$`)},
+		{"Network", regexp.MustCompile(`^net\d+$`)},
+		{"PCI passthrough", regexp.MustCompile(`^hostpci\d+$`)},
+		{"USB", regexp.MustCompile(`^usb\d+$`)},
+		{"Serial", regexp.MustCompile(`^serial\d+$`)},
+		{"Unused disks", regexp.MustCompile(`^unused\d+$`)},
+	}
+	ctHardwareSections = []hardwareSection{
+		{"Root disk", regexp.MustCompile(`^rootfs$`)},
+		{"Mount points", regexp.MustCompile(`^mp\d+$`)},
+		{"Network", regexp.MustCompile(`^net\d+$`)},
+		{"Devices", regexp.MustCompile(`^dev\d+$`)},
+		{"Features", regexp.MustCompile(`^features$`)},
+		{"Unused disks", regexp.MustCompile(`^unused\d+$`)},
+	}
+)
+
+// hardwareLines renders the guest's devices as section headers followed by
+// one line per config key. Empty sections are left out.
+func (m detailModel) hardwareLines() []string {
+	if m.hwCfg == nil {
+		return nil
+	}
+	sections := vmHardwareSections
+	if m.resource.Type == "lxc" {
+		sections = ctHardwareSections
+	}
+	width := m.width - 20
+	if width < 40 {
+		width = 40
+	}
+	var lines []string
+	for _, sec := range sections {
+		var keys []string
+		for k := range m.hwCfg.Values {
+			if sec.re.MatchString(k) {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Slice(keys, func(i, j int) bool { return actions.ConfigKeyLess(keys[i], keys[j]) })
+		lines = append(lines, "  "+StyleSubtitle.Render(sec.title))
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("    %-10s %s", k, runewidth.Truncate(m.hwCfg.Values[k], width, "…")))
+		}
+	}
+	return lines
+}
+
+func (m detailModel) viewHardwareTab() []string {
+	var lines []string
+	switch {
+	case m.hwLoading && !m.hwLoaded:
+		lines = append(lines, StyleWarning.Render(m.spinner.View()+" Loading hardware..."))
+	case m.hwLoadErr != nil:
+		lines = append(lines, StyleError.Render("  Error: "+m.hwLoadErr.Error()))
+		lines = append(lines, renderHelp("  [ctrl+r] retry"))
+	default:
+		all := m.hardwareLines()
+		if len(all) == 0 {
+			lines = append(lines, StyleDim.Render("  No devices"))
+			break
+		}
+		end := m.hwOffset + m.firewallPageSize()
+		if end > len(all) {
+			end = len(all)
+		}
+		lines = append(lines, all[m.hwOffset:end]...)
+		if len(all) > m.firewallPageSize() {
+			lines = append(lines, StyleDim.Render(fmt.Sprintf("  lines %d-%d of %d", m.hwOffset+1, end, len(all))))
+		}
+	}
+	// Keep the same footer spacing as the table tabs (filter line).
+	lines = append(lines, "")
+	return lines
+}
+
+// hardwareEditHint names the CLI commands that change what the tab shows.
+func (m detailModel) hardwareEditHint() string {
+	if m.resource.Type == "lxc" {
+		return "edit with 'pxve ct mp|net|features'"
+	}
+	return "edit with 'pxve vm disk|nic|hostpci|usb|serial'"
+}
@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	proxmox "github.com/luthermonson/go-proxmox"
+
+	"github.com/chupakbra/proxmox-cli/internal/config"
+	"github.com/chupakbra/proxmox-cli/internal/hooks"
+)
+
+var (
+	// taskHooks are the task-completion hooks from the config file, set by
+	// LaunchTUI.
+	taskHooks []config.HookConfig
+	// hooksRunning tracks hooks still in flight so LaunchTUI can let them
+	// finish after the program exits.
+	hooksRunning sync.WaitGroup
+)
+
+// waitTask waits up to seconds for task to finish, like task.WaitFor, and
+// then fires the configured hooks in the background. Hook output and errors
+// are discarded since they cannot be shown without disturbing the screen.
+func waitTask(ctx context.Context, task *proxmox.Task, seconds int) error {
+	started := time.Now()
+	err := task.WaitFor(ctx, seconds)
+	if len(taskHooks) == 0 || !task.IsCompleted {
+		return err
+	}
+	p := hooks.NewPayload(task, "tui", "pxve --tui", started)
+	hooksRunning.Add(1)
+	go func() {
+		defer hooksRunning.Done()
+		_ = hooks.Run(context.Background(), taskHooks, p, io.Discard)
+	}()
+	return err
+}
@@ -37,10 +37,28 @@ type resourcesFetchedMsg struct {
 	fetchID   int64 // matches listModel.fetchID; stale responses are discarded
 }
 
+// pendingFetchedMsg carries the VMIDs of running guests with pending config
+// changes, looked up after each resource fetch.
+type pendingFetchedMsg struct {
+	vmids   map[uint64]bool
+	fetchID int64
+}
+
+// ipsFetchedMsg carries the primary IPs of running guests, looked up after
+// each resource fetch while the IP column is shown.
+type ipsFetchedMsg struct {
+	ips     map[uint64]string
+	fetchID int64
+}
+
 type listModel struct {
 	client        *proxmox.Client
 	instName      string
+	instURL       string // keys the guest IP cache
 	resources     proxmox.ClusterResources
+	pending       map[uint64]bool   // guests with pending config changes
+	showIP        bool              // IP column toggled on with [i]
+	ips           map[uint64]string // primary IPs of running guests, while showIP
 	loading       bool
 	err           error
 	table         table.Model
@@ -78,7 +96,7 @@ type listModel struct {
 	height int
 }
 
-func newListModel(c *proxmox.Client, instName string, w, h int) listModel {
+func newListModel(c *proxmox.Client, instName, instURL string, w, h int) listModel {
 	s := spinner.New()
 	s.Spinner = CLISpinner
 	s.Style = StyleSpinner
@@ -105,6 +123,7 @@ func newListModel(c *proxmox.Client, instName string, w, h int) listModel {
 	return listModel{
 		client:          c,
 		instName:        instName,
+		instURL:         instURL,
 		loading:         true,
 		spinner:         s,
 		fetchID:         time.Now().UnixNano(),
@@ -122,8 +141,14 @@ func newListModel(c *proxmox.Client, instName string, w, h int) listModel {
 // Plus cell padding: 10 columns × 2 chars (1 left + 1 right per cell) = 20.
 const fixedColWidth = 79 + 20
 
+// ipColWidth is the width of the optional IP column, plus its cell padding.
+const ipColWidth = 15
+
 func (m listModel) nameColWidth() int {
 	w := m.width - fixedColWidth - 4 // 4 for outer padding
+	if m.showIP {
+		w -= ipColWidth + 2
+	}
 	if w < 20 {
 		w = 20
 	}
@@ -145,6 +170,9 @@ func (m listModel) withRebuiltTable() listModel {
 		{Title: "DISK", Width: 10},
 		{Title: "TAGS", Width: 15},
 	}
+	if m.showIP {
+		cols = append(cols, table.Column{Title: "IP", Width: ipColWidth})
+	}
 
 	var rows []table.Row
 	m.filteredIndices = nil
@@ -161,18 +189,26 @@ func (m listModel) withRebuiltTable() listModel {
 		if r.Template == 1 {
 			tmpl = "✓"
 		}
-		rows = append(rows, table.Row{
+		status := r.Status
+		if m.pending[r.VMID] {
+			status += " ↻"
+		}
+		row := table.Row{
 			vmidStr,
 			typeStr,
 			tmpl,
 			r.Name,
 			r.Node,
-			r.Status,
+			status,
 			formatPercent(r.CPU),
 			formatBytes(r.Mem),
 			formatBytes(r.MaxDisk),
 			formatTagsCell(r.Tags),
-		})
+		}
+		if m.showIP {
+			row = append(row, m.ips[r.VMID])
+		}
+		rows = append(rows, row)
 		m.filteredIndices = append(m.filteredIndices, i)
 	}
 
@@ -222,6 +258,30 @@ func (m listModel) update(msg tea.Msg) (listModel, tea.Cmd) {
 		m.resources = msg.resources
 		m.lastRefreshed = time.Now()
 		m = m.withRebuiltTable()
+		cmds := []tea.Cmd{fetchPendingGuests(m.client, m.resources, m.fetchID)}
+		if m.showIP {
+			cmds = append(cmds, fetchGuestIPs(m.client, m.instURL, m.resources, m.fetchID))
+		}
+		return m, tea.Batch(cmds...)
+
+	case pendingFetchedMsg:
+		if msg.fetchID != m.fetchID {
+			return m, nil
+		}
+		m.pending = msg.vmids
+		cursor := m.table.Cursor()
+		m = m.withRebuiltTable()
+		m.table.SetCursor(cursor)
+		return m, nil
+
+	case ipsFetchedMsg:
+		if msg.fetchID != m.fetchID || !m.showIP {
+			return m, nil
+		}
+		m.ips = msg.ips
+		cursor := m.table.Cursor()
+		m = m.withRebuiltTable()
+		m.table.SetCursor(cursor)
 		return m, nil
 
 	case actionResultMsg:
@@ -612,6 +672,16 @@ func (m listModel) update(msg tea.Msg) (listModel, tea.Cmd) {
 			return m, func() tea.Msg {
 				return resourceSelectedMsg{resource: res}
 			}
+		case "i":
+			m.showIP = !m.showIP
+			m.ips = nil
+			cursor := m.table.Cursor()
+			m = m.withRebuiltTable()
+			m.table.SetCursor(cursor)
+			if !m.showIP || len(m.resources) == 0 {
+				return m, nil
+			}
+			return m, fetchGuestIPs(m.client, m.instURL, m.resources, m.fetchID)
 		case "s":
 			if m.selectedResource() == nil {
 				return m, nil
@@ -759,7 +829,15 @@ func (m listModel) view() string {
 	case m.statusMsg != "":
 		lines = append(lines, StyleSuccess.Render(m.statusMsg))
 	default:
-		lines = append(lines, "")
+		if r := m.selectedResource(); r != nil && m.pending[r.VMID] {
+			noun := "vm"
+			if r.Type == "lxc" {
+				noun = "ct"
+			}
+			lines = append(lines, StyleDim.Render(fmt.Sprintf("↻ pending config changes — reboot to apply, or see 'pxve %s pending %d'", noun, r.VMID)))
+		} else {
+			lines = append(lines, "")
+		}
 	}
 
 	// Action hints or confirmation/input overlay.
@@ -852,7 +930,7 @@ func (m listModel) view() string {
 		lines = append(lines, renderHelp("[↑/↓] navigate   [Enter] select   [Esc] cancel"))
 	default:
 		lines = append(lines, renderHelp("[s] start  [S] stop  [U] shutdown  [R] reboot  [c] clone  [D] delete  [T] template  |  [Tab] Users and Groups  |  [ctrl+r] refresh"))
-		lines = append(lines, renderHelp("[Alt+z] resize disk  [Alt+m] move disk  [/] filter"))
+		lines = append(lines, renderHelp("[Alt+z] resize disk  [Alt+m] move disk  [i] IPs  [/] filter"))
 	}
 	lines = append(lines, renderHelp("[Esc] back   [Q] quit"))
 	return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
@@ -918,7 +996,7 @@ func (m listModel) listPowerCmd(action string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -948,7 +1026,7 @@ func (m listModel) listDeleteResourceCmd() tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -991,7 +1069,7 @@ func (m listModel) listCloneResourceCmd(newid int, name string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -1021,7 +1099,7 @@ func (m listModel) listResizeDiskCmd(disk, size string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 300); werr != nil {
+			if werr := waitTask(ctx, task, 300); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -1054,7 +1132,7 @@ func (m listModel) listConvertToTemplateCmd() tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 120); werr != nil {
+			if werr := waitTask(ctx, task, 120); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -1157,7 +1235,7 @@ func (m listModel) listMoveDiskCmd(disk, storage string) tea.Cmd {
 			return actionResultMsg{err: err}
 		}
 		if task != nil {
-			if werr := task.WaitFor(ctx, 600); werr != nil {
+			if werr := waitTask(ctx, task, 600); werr != nil {
 				return actionResultMsg{err: werr}
 			}
 		}
@@ -1183,6 +1261,21 @@ func fetchAllResources(c *proxmox.Client, fetchID int64) tea.Cmd {
 	}
 }
 
+// fetchPendingGuests looks up which running guests have pending config
+// changes, for the list's status badge.
+func fetchPendingGuests(c *proxmox.Client, resources proxmox.ClusterResources, fetchID int64) tea.Cmd {
+	return func() tea.Msg {
+		return pendingFetchedMsg{vmids: actions.GuestsWithPending(context.Background(), c, resources), fetchID: fetchID}
+	}
+}
+
+// fetchGuestIPs looks up the primary IPs of running guests for the IP column.
+func fetchGuestIPs(c *proxmox.Client, instURL string, resources proxmox.ClusterResources, fetchID int64) tea.Cmd {
+	return func() tea.Msg {
+		return ipsFetchedMsg{ips: actions.GuestIPs(context.Background(), c, instURL, resources), fetchID: fetchID}
+	}
+}
+
 // parseTags splits a Proxmox semicolon-separated tags string into a slice.
 func parseTags(s string) []string {
 	if s == "" {
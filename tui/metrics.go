@@ -0,0 +1,229 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+// metricsLoadedMsg is sent when RRD history for the detail metrics tab loads.
+type metricsLoadedMsg struct {
+	points    []actions.MetricPoint
+	timeframe string
+	err       error
+}
+
+// chartAxisWidth is the width of the y-axis label column left of each chart.
+const chartAxisWidth = 13
+
+var chartBlocks = []rune("▁▂▃▄▅▆▇█")
+
+var (
+	styleChartCPU  = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	styleChartMem  = lipgloss.NewStyle().Foreground(lipgloss.Color("62"))
+	styleChartNet  = lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+	styleChartDisk = lipgloss.NewStyle().Foreground(lipgloss.Color("135"))
+)
+
+func (m detailModel) metricsTimeframe() string {
+	return actions.MetricTimeframes[m.metricsTFIdx]
+}
+
+func (m detailModel) loadMetricsCmd() tea.Cmd {
+	c := m.client
+	r := m.resource
+	tf := m.metricsTimeframe()
+	return func() tea.Msg {
+		ctx := context.Background()
+		var points []actions.MetricPoint
+		var err error
+		if r.Type == "qemu" {
+			points, err = actions.VMMetrics(ctx, c, int(r.VMID), r.Node, tf, "AVERAGE")
+		} else {
+			points, err = actions.ContainerMetrics(ctx, c, int(r.VMID), r.Node, tf, "AVERAGE")
+		}
+		return metricsLoadedMsg{points: points, timeframe: tf, err: err}
+	}
+}
+
+func (m detailModel) viewMetricsTab() []string {
+	var lines []string
+	switch {
+	case m.metricsLoading && len(m.metrics) == 0:
+		lines = append(lines, StyleWarning.Render(m.spinner.View()+" Loading metrics..."))
+	case m.metricsLoadErr != nil:
+		lines = append(lines, StyleError.Render("  Error: "+m.metricsLoadErr.Error()))
+		lines = append(lines, renderHelp("  [ctrl+r] retry"))
+	case len(m.metrics) == 0:
+		lines = append(lines, StyleDim.Render("  No metrics data"))
+	default:
+		lines = append(lines, m.renderMetricCharts()...)
+	}
+	// Keep the same footer spacing as the table tabs (filter line).
+	lines = append(lines, "")
+	return lines
+}
+
+// renderMetricCharts lays out CPU / memory, network in / out, and disk
+// read / write charts in a two-column grid sized to the terminal.
+func (m detailModel) renderMetricCharts() []string {
+	series := func(get func(p actions.MetricPoint) *float64) []float64 {
+		vals := make([]float64, len(m.metrics))
+		for i, p := range m.metrics {
+			if v := get(p); v != nil {
+				vals[i] = *v
+			} else {
+				vals[i] = math.NaN()
+			}
+		}
+		return vals
+	}
+
+	// Same vertical budget as the snapshot/backup tables (table + header + filter).
+	avail := m.height - 16
+	chartHeight := avail/3 - 1 // one title line per chart
+	if chartHeight < 2 {
+		chartHeight = 2
+	}
+	colWidth := (m.width - 4 - 3) / 2
+	if colWidth < chartAxisWidth+10 {
+		colWidth = chartAxisWidth + 10
+	}
+
+	memMax := 0.0
+	for _, p := range m.metrics {
+		if p.MemTotal != nil && *p.MemTotal > memMax {
+			memMax = *p.MemTotal
+		}
+	}
+
+	bytesFmt := func(v float64) string { return formatBytes(uint64(math.Max(v, 0))) }
+	rateFmt := func(v float64) string { return bytesFmt(v) + "/s" }
+	pctFmt := func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) }
+
+	grid := [][2][]string{
+		{
+			renderChart("CPU", series(func(p actions.MetricPoint) *float64 { return p.CPU }), colWidth, chartHeight, 1, pctFmt, styleChartCPU),
+			renderChart("Memory", series(func(p actions.MetricPoint) *float64 { return p.MemUsed }), colWidth, chartHeight, memMax, bytesFmt, styleChartMem),
+		},
+		{
+			renderChart("Net In", series(func(p actions.MetricPoint) *float64 { return p.NetIn }), colWidth, chartHeight, 0, rateFmt, styleChartNet),
+			renderChart("Net Out", series(func(p actions.MetricPoint) *float64 { return p.NetOut }), colWidth, chartHeight, 0, rateFmt, styleChartNet),
+		},
+		{
+			renderChart("Disk Read", series(func(p actions.MetricPoint) *float64 { return p.DiskRead }), colWidth, chartHeight, 0, rateFmt, styleChartDisk),
+			renderChart("Disk Write", series(func(p actions.MetricPoint) *float64 { return p.DiskWrite }), colWidth, chartHeight, 0, rateFmt, styleChartDisk),
+		},
+	}
+
+	var lines []string
+	for _, row := range grid {
+		left := lipgloss.NewStyle().Width(colWidth).Render(strings.Join(row[0], "\n"))
+		right := strings.Join(row[1], "\n")
+		lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, left, "   ", right))
+	}
+	return lines
+}
+
+// renderChart draws vals as a bar chart of the given width (including the
+// y-axis labels) and height (bar rows, excluding the title line). Bars scale
+// from zero to scaleMax, or to the series peak when scaleMax is zero; NaN
+// gaps are left blank.
+func renderChart(title string, vals []float64, width, height int, scaleMax float64, format func(float64) string, style lipgloss.Style) []string {
+	barWidth := width - chartAxisWidth
+	cols := downsampleSeries(vals, barWidth)
+
+	peak, last, have := 0.0, 0.0, false
+	for _, v := range vals {
+		if math.IsNaN(v) {
+			continue
+		}
+		peak = math.Max(peak, v)
+		last = v
+		have = true
+	}
+
+	lines := []string{StyleSubtitle.Render(title)}
+	if !have {
+		lines[0] += StyleDim.Render("  no data")
+		for i := 0; i < height; i++ {
+			lines = append(lines, strings.Repeat(" ", chartAxisWidth-1)+StyleDim.Render("│"))
+		}
+		return lines
+	}
+	lines[0] += StyleDim.Render(fmt.Sprintf("  now %s  peak %s", format(last), format(peak)))
+
+	if scaleMax <= 0 {
+		scaleMax = peak
+	}
+	blocks := len(chartBlocks)
+	for row := 0; row < height; row++ {
+		var label string
+		switch row {
+		case 0:
+			label = format(scaleMax)
+		case height - 1:
+			label = "0"
+		}
+		// Each row covers `blocks` eighths of a character cell; the row base is
+		// counted from the bottom of the chart.
+		base := (height - 1 - row) * blocks
+		var b strings.Builder
+		for _, v := range cols {
+			if math.IsNaN(v) || scaleMax <= 0 {
+				b.WriteRune(' ')
+				continue
+			}
+			fill := int(math.Round(math.Min(v/scaleMax, 1)*float64(height*blocks))) - base
+			switch {
+			case fill >= blocks:
+				b.WriteRune(chartBlocks[blocks-1])
+			case fill > 0:
+				b.WriteRune(chartBlocks[fill-1])
+			case row == height-1:
+				b.WriteRune(chartBlocks[0]) // baseline so zero values stay visible
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		axis := fmt.Sprintf("%*s ", chartAxisWidth-2, label)
+		lines = append(lines, StyleDim.Render(axis+"│")+style.Render(b.String()))
+	}
+	return lines
+}
+
+// downsampleSeries averages vals into at most width buckets, skipping NaN
+// values. A bucket with no values at all is NaN.
+func downsampleSeries(vals []float64, width int) []float64 {
+	if width <= 0 {
+		return nil
+	}
+	if len(vals) <= width {
+		return vals
+	}
+	out := make([]float64, width)
+	for i := range out {
+		lo := i * len(vals) / width
+		hi := (i + 1) * len(vals) / width
+		var sum float64
+		var n int
+		for _, v := range vals[lo:hi] {
+			if !math.IsNaN(v) {
+				sum += v
+				n++
+			}
+		}
+		if n == 0 {
+			out[i] = math.NaN()
+		} else {
+			out[i] = sum / float64(n)
+		}
+	}
+	return out
+}
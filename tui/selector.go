@@ -600,7 +600,7 @@ func connectToInstance(cfg *config.Config, instances map[string]config.InstanceC
 		}
 		cfg.CurrentInstance = name
 		_ = config.Save(cfg) // best-effort; ignore save errors
-		return instanceSelectedMsg{client: c, name: name}
+		return instanceSelectedMsg{client: c, name: name, url: inst.URL}
 	}
 }
 
@@ -617,6 +617,6 @@ func reconnectToInstance(cfg *config.Config, c *proxmox.Client, name string) tea
 		}
 		cfg.CurrentInstance = name
 		_ = config.Save(cfg)
-		return instanceSelectedMsg{client: c, name: name}
+		return instanceSelectedMsg{client: c, name: name, url: cfg.Instances[name].URL}
 	}
 }
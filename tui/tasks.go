@@ -0,0 +1,694 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	proxmox "github.com/luthermonson/go-proxmox"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/chupakbra/proxmox-cli/internal/actions"
+)
+
+const (
+	tasksRefreshInterval = 5 * time.Second // task list auto-refresh
+	taskLogPollInterval  = 2 * time.Second // log pane tail while the task runs
+)
+
+type tasksScreenMode int
+
+const (
+	tasksScreenNormal      tasksScreenMode = iota
+	tasksScreenConfirmStop                 // confirm stopping the selected / viewed task
+)
+
+// taskEntry is the TUI representation of a cluster task.
+type taskEntry struct {
+	UPID     string
+	Node     string
+	Type     string
+	ID       string
+	User     string
+	Status   string // "running", "OK", or the failure message
+	Started  string
+	Duration string
+	running  bool
+	start    time.Time
+}
+
+// tasksFetchedMsg is sent when the async fetch of cluster tasks completes.
+type tasksFetchedMsg struct {
+	tasks   []taskEntry
+	err     error
+	fetchID int64
+}
+
+// tasksTickMsg drives the periodic task list refresh. Ticks from a previous
+// chain (tickID mismatch) are dropped so only one refresh loop runs.
+type tasksTickMsg struct{ tickID int64 }
+
+// taskLogMsg carries newly fetched log lines for the open log pane.
+type taskLogMsg struct {
+	upid    string
+	lines   []string
+	running bool
+	status  string
+	err     error
+}
+
+// taskLogTickMsg schedules the next log poll for upid.
+type taskLogTickMsg struct{ upid string }
+
+// taskStoppedMsg is sent after a stop request completes.
+type taskStoppedMsg struct {
+	upid string
+	err  error
+}
+
+type tasksScreenModel struct {
+	client        *proxmox.Client
+	instName      string
+	tasks         []taskEntry
+	loading       bool
+	err           error
+	table         table.Model
+	spinner       spinner.Model
+	mode          tasksScreenMode
+	fetchID       int64
+	tickID        int64
+	lastRefreshed time.Time
+
+	statusMsg  string
+	statusErr  bool
+	actionBusy bool
+
+	// Log pane state. logTask is nil while the task list is shown.
+	logTask    *taskEntry
+	logLines   []string
+	logView    viewport.Model
+	logFollow  bool // keep the viewport pinned to the newest line
+	logLoading bool
+	logErr     error
+
+	// Filter state
+	filter          tableFilter
+	filteredIndices []int // maps table row index → m.tasks index
+
+	width  int
+	height int
+}
+
+func newTasksScreenModel(c *proxmox.Client, instName string, w, h int) tasksScreenModel {
+	s := spinner.New()
+	s.Spinner = CLISpinner
+	s.Style = StyleSpinner
+
+	now := time.Now().UnixNano()
+	return tasksScreenModel{
+		client:   c,
+		instName: instName,
+		loading:  true,
+		spinner:  s,
+		fetchID:  now,
+		tickID:   now,
+		width:    w,
+		height:   h,
+	}
+}
+
+// fixedTasksColWidth: STARTED(16)+NODE(12)+TYPE(14)+ID(8)+USER(16)+DURATION(9) = 75 + separators 14 = 89
+const fixedTasksColWidth = 75 + 14
+
+func (m tasksScreenModel) statusColWidth() int {
+	w := m.width - fixedTasksColWidth - 4
+	if w < 20 {
+		w = 20
+	}
+	return w
+}
+
+func (m tasksScreenModel) withRebuiltTable() tasksScreenModel {
+	statusWidth := m.statusColWidth()
+	cols := []table.Column{
+		{Title: "STARTED", Width: 16},
+		{Title: "NODE", Width: 12},
+		{Title: "TYPE", Width: 14},
+		{Title: "ID", Width: 8},
+		{Title: "USER", Width: 16},
+		{Title: "DURATION", Width: 9},
+		{Title: "STATUS", Width: statusWidth},
+	}
+
+	var rows []table.Row
+	m.filteredIndices = nil
+	for i, t := range m.tasks {
+		if !m.filter.matches(t.Node, t.Type, t.ID, t.User, t.Status) {
+			continue
+		}
+		rows = append(rows, table.Row{
+			t.Started,
+			t.Node,
+			t.Type,
+			t.ID,
+			t.User,
+			t.Duration,
+			styledCell(taskStatusStyle(t), t.Status, statusWidth),
+		})
+		m.filteredIndices = append(m.filteredIndices, i)
+	}
+
+	// Same budget as the backups screen: padding, header, status, overlay, help.
+	tableHeight := m.height - 14
+	if tableHeight < 3 {
+		tableHeight = 3
+	}
+
+	cursor := m.table.Cursor()
+	t := table.New(
+		table.WithColumns(cols),
+		table.WithRows(rows),
+		table.WithFocused(true),
+		table.WithHeight(tableHeight),
+	)
+	s := table.DefaultStyles()
+	s.Header = s.Header.
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color("240")).
+		BorderBottom(true).
+		Bold(true)
+	s.Selected = s.Selected.
+		Foreground(lipgloss.Color("255")).
+		Background(lipgloss.Color("236")).
+		Bold(false)
+	t.SetStyles(s)
+	// Keep the cursor in place across auto-refreshes.
+	if cursor > 0 && cursor < len(rows) {
+		t.SetCursor(cursor)
+	}
+	m.table = t
+	return m
+}
+
+// withResizedLog sizes the log viewport to the current terminal.
+func (m tasksScreenModel) withResizedLog() tasksScreenModel {
+	// padding(2) + title(1) + upid(1) + blank(1) + status(1) + overlay(2) + help(2)
+	h := m.height - 10
+	if h < 3 {
+		h = 3
+	}
+	m.logView.Width = m.width - 4
+	m.logView.Height = h
+	m.logView.SetContent(strings.Join(m.logLines, "\n"))
+	if m.logFollow {
+		m.logView.GotoBottom()
+	}
+	return m
+}
+
+func (m tasksScreenModel) init() tea.Cmd {
+	return tea.Batch(fetchClusterTasks(m.client, m.fetchID), m.spinner.Tick, tasksTickCmd(m.tickID))
+}
+
+// resume restarts the refresh loop when the screen becomes active again. The
+// previous tick chain is abandoned because its ticks were delivered to other
+// screens while this one was hidden.
+func (m tasksScreenModel) resume() (tasksScreenModel, tea.Cmd) {
+	m.tickID = time.Now().UnixNano()
+	m.fetchID = m.tickID
+	cmds := []tea.Cmd{fetchClusterTasks(m.client, m.fetchID), tasksTickCmd(m.tickID)}
+	if m.logTask != nil && m.logTask.running {
+		cmds = append(cmds, m.fetchLogCmd())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m tasksScreenModel) update(msg tea.Msg) (tasksScreenModel, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tasksFetchedMsg:
+		if msg.fetchID != m.fetchID {
+			return m, nil // stale response; discard
+		}
+		m.loading = false
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.err = nil
+		m.tasks = msg.tasks
+		m.lastRefreshed = time.Now()
+		m = m.withRebuiltTable()
+		return m, nil
+
+	case tasksTickMsg:
+		if msg.tickID != m.tickID {
+			return m, nil
+		}
+		m.fetchID = time.Now().UnixNano()
+		return m, tea.Batch(fetchClusterTasks(m.client, m.fetchID), tasksTickCmd(m.tickID))
+
+	case taskLogMsg:
+		if m.logTask == nil || msg.upid != m.logTask.UPID {
+			return m, nil // pane was closed or switched to another task
+		}
+		m.logLoading = false
+		if msg.err != nil {
+			m.logErr = msg.err
+			return m, nil
+		}
+		m.logErr = nil
+		m.logLines = append(m.logLines, msg.lines...)
+		m.logTask.running = msg.running
+		if msg.status != "" {
+			m.logTask.Status = msg.status
+		}
+		m = m.withResizedLog()
+		if msg.running {
+			upid := msg.upid
+			return m, tea.Tick(taskLogPollInterval, func(time.Time) tea.Msg { return taskLogTickMsg{upid: upid} })
+		}
+		return m, nil
+
+	case taskLogTickMsg:
+		if m.logTask == nil || msg.upid != m.logTask.UPID {
+			return m, nil
+		}
+		return m, m.fetchLogCmd()
+
+	case taskStoppedMsg:
+		m.actionBusy = false
+		if msg.err != nil {
+			m.statusMsg = "Error: " + msg.err.Error()
+			m.statusErr = true
+			return m, nil
+		}
+		m.statusMsg = "Stop requested"
+		m.statusErr = false
+		m.fetchID = time.Now().UnixNano()
+		return m, fetchClusterTasks(m.client, m.fetchID)
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		if m.loading || m.actionBusy || m.logLoading {
+			return m, cmd
+		}
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.mode == tasksScreenConfirmStop {
+			switch msg.String() {
+			case "enter":
+				m.mode = tasksScreenNormal
+				t := m.stopTarget()
+				if t == nil {
+					return m, nil
+				}
+				m.actionBusy = true
+				m.statusMsg = "Stopping task..."
+				m.statusErr = false
+				return m, tea.Batch(m.stopTaskCmd(t.UPID), m.spinner.Tick)
+			case "esc":
+				m.mode = tasksScreenNormal
+				return m, nil
+			}
+			return m, nil
+		}
+
+		if m.logTask != nil {
+			return m.handleLogKey(msg)
+		}
+
+		if m.filter.active {
+			var rebuild bool
+			m.filter, rebuild = m.filter.handleKey(msg)
+			if rebuild {
+				m = m.withRebuiltTable()
+			}
+			return m, nil
+		}
+
+		if m.actionBusy {
+			return m, nil
+		}
+
+		switch msg.String() {
+		case "/":
+			m.filter.active = true
+			return m, nil
+		case "ctrl+u":
+			if m.filter.hasActiveFilter() {
+				m.filter.text = ""
+				m = m.withRebuiltTable()
+			}
+			return m, nil
+		case "enter":
+			t := m.selectedTask()
+			if t == nil {
+				return m, nil
+			}
+			entry := *t
+			m.logTask = &entry
+			m.logLines = nil
+			m.logFollow = true
+			m.logLoading = true
+			m.logErr = nil
+			m.logView = viewport.New(0, 0)
+			m = m.withResizedLog()
+			return m, tea.Batch(m.fetchLogCmd(), m.spinner.Tick)
+		case "S":
+			t := m.selectedTask()
+			if t == nil || !t.running {
+				return m, nil
+			}
+			m.mode = tasksScreenConfirmStop
+			return m, nil
+		case "ctrl+r":
+			m.loading = true
+			m.err = nil
+			m.statusMsg = ""
+			m.statusErr = false
+			m.fetchID = time.Now().UnixNano()
+			return m, tea.Batch(fetchClusterTasks(m.client, m.fetchID), m.spinner.Tick)
+		}
+	}
+
+	if m.logTask != nil {
+		var cmd tea.Cmd
+		m.logView, cmd = m.logView.Update(msg)
+		return m, cmd
+	}
+	var cmd tea.Cmd
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+// handleLogKey handles keys while the log pane is open.
+func (m tasksScreenModel) handleLogKey(msg tea.KeyMsg) (tasksScreenModel, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.logTask = nil
+		m.logLines = nil
+		return m, nil
+	case "f", "end", "G":
+		m.logFollow = true
+		m.logView.GotoBottom()
+		return m, nil
+	case "S":
+		if m.logTask.running && !m.actionBusy {
+			m.mode = tasksScreenConfirmStop
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.logView, cmd = m.logView.Update(msg)
+	// Scrolling away from the bottom pauses following until [f] is pressed.
+	m.logFollow = m.logView.AtBottom()
+	return m, cmd
+}
+
+// isNormalMode reports whether the screen is showing the plain task list, so
+// the router may switch screens on Tab/Esc.
+func (m tasksScreenModel) isNormalMode() bool {
+	return m.mode == tasksScreenNormal && m.logTask == nil && !m.filter.active
+}
+
+func (m tasksScreenModel) view() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	if m.logTask != nil {
+		return m.viewLog()
+	}
+
+	title := StyleTitle.Render(fmt.Sprintf("Tasks — %s", m.instName))
+
+	if m.loading && len(m.tasks) == 0 {
+		return lipgloss.NewStyle().Padding(1, 2).Render(
+			title + "\n\n" + StyleWarning.Render(m.spinner.View()+" Loading..."),
+		)
+	}
+
+	if m.err != nil && len(m.tasks) == 0 {
+		lines := []string{
+			title,
+			"",
+			StyleError.Render("Error: " + m.err.Error()),
+			"",
+			renderHelp("[ctrl+r] retry"),
+			renderHelp("[Esc] back   [Q] quit"),
+		}
+		return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
+	}
+
+	running := 0
+	for _, t := range m.tasks {
+		if t.running {
+			running++
+		}
+	}
+	var count string
+	if m.filter.hasActiveFilter() {
+		count = StyleDim.Render(fmt.Sprintf(" (%d/%d, %d running)", len(m.filteredIndices), len(m.tasks), running))
+	} else {
+		count = StyleDim.Render(fmt.Sprintf(" (%d, %d running)", len(m.tasks), running))
+	}
+
+	var lines []string
+	lines = append(lines, headerLine(title+count, m.width, m.lastRefreshed))
+	lines = append(lines, "")
+	lines = append(lines, m.table.View())
+
+	if fl := m.filter.renderLine(); fl != "" {
+		lines = append(lines, fl)
+	} else {
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, m.statusLine())
+	lines = append(lines, m.viewOverlay()...)
+	lines = append(lines, renderHelp("[Esc] back   [Q] quit"))
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
+
+func (m tasksScreenModel) viewLog() string {
+	t := m.logTask
+	title := StyleTitle.Render(fmt.Sprintf("Task %s %s on %s", t.Type, t.ID, t.Node)) +
+		"  " + taskStatusStyle(*t).Render(t.Status)
+
+	var lines []string
+	lines = append(lines, title)
+	lines = append(lines, StyleDim.Render(t.UPID))
+	lines = append(lines, "")
+	switch {
+	case m.logLoading && len(m.logLines) == 0:
+		lines = append(lines, StyleWarning.Render(m.spinner.View()+" Loading log..."))
+	case m.logErr != nil && len(m.logLines) == 0:
+		lines = append(lines, StyleError.Render("Error: "+m.logErr.Error()))
+	default:
+		lines = append(lines, m.logView.View())
+	}
+
+	lines = append(lines, m.statusLine())
+	lines = append(lines, m.viewOverlay()...)
+	lines = append(lines, renderHelp("[Esc] back   [Q] quit"))
+
+	return lipgloss.NewStyle().Padding(1, 2).Render(strings.Join(lines, "\n"))
+}
+
+func (m tasksScreenModel) statusLine() string {
+	switch {
+	case m.actionBusy:
+		return StyleWarning.Render(m.spinner.View() + " " + m.statusMsg)
+	case m.statusMsg != "" && m.statusErr:
+		return StyleError.Render(m.statusMsg)
+	case m.statusMsg != "":
+		return StyleSuccess.Render(m.statusMsg)
+	}
+	return ""
+}
+
+func (m tasksScreenModel) viewOverlay() []string {
+	var lines []string
+	switch {
+	case m.mode == tasksScreenConfirmStop:
+		desc := ""
+		if t := m.stopTarget(); t != nil {
+			desc = fmt.Sprintf("%s %s on %s", t.Type, t.ID, t.Node)
+		}
+		lines = append(lines, "")
+		lines = append(lines, StyleWarning.Render(
+			fmt.Sprintf("Stop task %s? [Enter] confirm   [Esc] cancel", desc),
+		))
+	case m.logTask != nil:
+		follow := "[f] follow"
+		if m.logFollow {
+			follow = "[f] following"
+		}
+		help := "[↑/↓/PgUp/PgDn] scroll  " + follow
+		if m.logTask.running {
+			help += "  [S] stop task"
+		}
+		lines = append(lines, "")
+		lines = append(lines, renderHelp(help))
+	default:
+		lines = append(lines, "")
+		if len(m.tasks) > 0 {
+			lines = append(lines, renderHelp("[Enter] log  [S] stop  [/] filter  |  [Tab] Resources  |  [ctrl+r] refresh"))
+		} else {
+			lines = append(lines, renderHelp("[Tab] Resources  |  [ctrl+r] refresh"))
+		}
+	}
+	return lines
+}
+
+// clearFilter resets the filter and rebuilds the table to show all rows.
+func (m *tasksScreenModel) clearFilter() {
+	if m.filter.hasActiveFilter() || m.filter.active {
+		m.filter.clear()
+		*m = m.withRebuiltTable()
+	}
+}
+
+func (m tasksScreenModel) selectedTask() *taskEntry {
+	if len(m.filteredIndices) == 0 {
+		return nil
+	}
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.filteredIndices) {
+		return nil
+	}
+	return &m.tasks[m.filteredIndices[cursor]]
+}
+
+// stopTarget returns the task a stop confirmation applies to: the one in the
+// open log pane, or the selected row.
+func (m tasksScreenModel) stopTarget() *taskEntry {
+	if m.logTask != nil {
+		return m.logTask
+	}
+	return m.selectedTask()
+}
+
+func tasksTickCmd(tickID int64) tea.Cmd {
+	return tea.Tick(tasksRefreshInterval, func(time.Time) tea.Msg { return tasksTickMsg{tickID: tickID} })
+}
+
+func fetchClusterTasks(c *proxmox.Client, fetchID int64) tea.Cmd {
+	return func() tea.Msg {
+		ctx := context.Background()
+		list, err := actions.ClusterTasks(ctx, c)
+		if err != nil {
+			return tasksFetchedMsg{err: err, fetchID: fetchID}
+		}
+		entries := make([]taskEntry, 0, len(list))
+		for _, t := range list {
+			entries = append(entries, newTaskEntry(t))
+		}
+		sort.SliceStable(entries, func(i, j int) bool { return entries[i].start.After(entries[j].start) })
+		return tasksFetchedMsg{tasks: entries, fetchID: fetchID}
+	}
+}
+
+func newTaskEntry(t *proxmox.Task) taskEntry {
+	e := taskEntry{
+		UPID:     string(t.UPID),
+		Node:     t.Node,
+		Type:     t.Type,
+		ID:       t.ID,
+		User:     t.User,
+		Started:  "-",
+		Duration: "-",
+		start:    t.StartTime,
+	}
+	// Cluster task entries carry the exit status in "status" once finished
+	// and have no end time while still running.
+	e.running = t.EndTime.IsZero() || t.Status == proxmox.TaskRunning
+	switch {
+	case e.running:
+		e.Status = proxmox.TaskRunning
+	case t.ExitStatus != "":
+		e.Status = t.ExitStatus
+	default:
+		e.Status = t.Status
+	}
+	if !t.StartTime.IsZero() {
+		e.Started = t.StartTime.Format("2006-01-02 15:04")
+	}
+	switch {
+	case e.running && !t.StartTime.IsZero():
+		e.Duration = time.Since(t.StartTime).Round(time.Second).String()
+	case t.Duration > 0:
+		e.Duration = t.Duration.Round(time.Second).String()
+	}
+	return e
+}
+
+func (m tasksScreenModel) fetchLogCmd() tea.Cmd {
+	c := m.client
+	upid := m.logTask.UPID
+	start := len(m.logLines)
+	return func() tea.Msg {
+		ctx := context.Background()
+		task, err := actions.GetTask(ctx, c, upid)
+		if err != nil {
+			return taskLogMsg{upid: upid, err: err}
+		}
+		lines, err := actions.TaskLog(ctx, c, upid, start)
+		if err != nil {
+			return taskLogMsg{upid: upid, err: err}
+		}
+		running := task.Status == proxmox.TaskRunning
+		status := proxmox.TaskRunning
+		if !running {
+			status = task.ExitStatus
+		}
+		return taskLogMsg{upid: upid, lines: lines, running: running, status: status}
+	}
+}
+
+func (m tasksScreenModel) stopTaskCmd(upid string) tea.Cmd {
+	c := m.client
+	return func() tea.Msg {
+		ctx := context.Background()
+		return taskStoppedMsg{upid: upid, err: actions.StopTask(ctx, c, upid)}
+	}
+}
+
+// taskStatusStyle colors running tasks amber, successful ones green and
+// failures red.
+func taskStatusStyle(t taskEntry) lipgloss.Style {
+	switch {
+	case t.running:
+		return StyleWarning
+	case t.Status == "OK":
+		return StyleSuccess
+	case strings.HasPrefix(t.Status, "WARNINGS"):
+		return StyleWarning
+	default:
+		return StyleError
+	}
+}
+
+// styledCell renders text in style for a table column of the given width.
+// bubbles/table truncates cells by counting escape sequences as visible
+// characters, so the plain text is shortened first to leave room for them.
+func styledCell(style lipgloss.Style, text string, width int) string {
+	overhead := runewidth.StringWidth(style.Render("x")) - 1
+	if max := width - overhead; runewidth.StringWidth(text) > max {
+		if max < 1 {
+			return text
+		}
+		text = runewidth.Truncate(text, max, "…")
+	}
+	return style.Render(text)
+}
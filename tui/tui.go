@@ -15,6 +15,7 @@ const (
 	screenList               // VMs / containers
 	screenUsers              // Proxmox users
 	screenBackups            // cluster-wide backups
+	screenTasks              // cluster task log
 	screenDetail             // VM / CT detail + snapshots
 	screenUserDetail         // User detail + tokens + ACLs
 )
@@ -23,6 +24,7 @@ const (
 type instanceSelectedMsg struct {
 	client *proxmox.Client
 	name   string
+	url    string // API URL of the instance, for per-instance caches
 }
 
 // resourceSelectedMsg is sent by the list when the user selects a VM or CT.
@@ -52,6 +54,7 @@ type appModel struct {
 	list       listModel
 	users      usersModel
 	backups    backupsScreenModel
+	tasks      tasksScreenModel
 	detail     detailModel
 	userDetail userDetailModel
 
@@ -90,6 +93,8 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.users.height = msg.Height
 		a.backups.width = msg.Width
 		a.backups.height = msg.Height
+		a.tasks.width = msg.Width
+		a.tasks.height = msg.Height
 		a.detail.width = msg.Width
 		a.detail.height = msg.Height
 		a.userDetail.width = msg.Width
@@ -106,6 +111,12 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if !a.backups.loading && len(a.backups.backups) > 0 {
 			a.backups = a.backups.withRebuiltTable()
 		}
+		if !a.tasks.loading && len(a.tasks.tasks) > 0 {
+			a.tasks = a.tasks.withRebuiltTable()
+		}
+		if a.tasks.logTask != nil {
+			a.tasks = a.tasks.withResizedLog()
+		}
 		if !a.detail.loading {
 			a.detail = a.detail.withRebuiltTable()
 		}
@@ -129,12 +140,14 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			a.users = usersModel{}
 			a.backups = backupsScreenModel{}
+			a.tasks = tasksScreenModel{}
 			return a, nil
 		}
 
-		a.list = newListModel(msg.client, msg.name, a.width, a.height)
+		a.list = newListModel(msg.client, msg.name, msg.url, a.width, a.height)
 		a.users = usersModel{}
 		a.backups = backupsScreenModel{}
+		a.tasks = tasksScreenModel{}
 		return a, a.list.init()
 
 	case resourceSelectedMsg:
@@ -146,7 +159,7 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.screen = screenList
 		// Invalidate the list cache so the deleted resource disappears.
 		delete(a.listCache, a.list.instName)
-		a.list = newListModel(a.list.client, a.list.instName, a.width, a.height)
+		a.list = newListModel(a.list.client, a.list.instName, a.list.instURL, a.width, a.height)
 		return a, a.list.init()
 
 	case userSelectedMsg:
@@ -179,6 +192,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.screen == screenBackups && (a.backups.mode != backupsScreenNormal || a.backups.filter.active) {
 				break
 			}
+			if a.screen == screenTasks && !a.tasks.isNormalMode() {
+				break
+			}
 			return a, tea.Quit
 
 		case "tab":
@@ -192,6 +208,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.screen == screenBackups && (a.backups.mode != backupsScreenNormal || a.backups.filter.active) {
 				break
 			}
+			if a.screen == screenTasks && !a.tasks.isNormalMode() {
+				break
+			}
 			switch a.screen {
 			case screenList:
 				a.list.clearFilter()
@@ -224,6 +243,9 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return a, nil
 			case screenBackups:
 				a.backups.clearFilter()
+				return a.switchToTasks()
+			case screenTasks:
+				a.tasks.clearFilter()
 				a.screen = screenList
 				return a, nil
 			}
@@ -238,9 +260,15 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if a.screen == screenBackups && (a.backups.mode != backupsScreenNormal || a.backups.filter.active) {
 				break
 			}
+			if a.screen == screenTasks && !a.tasks.isNormalMode() {
+				break
+			}
 			switch a.screen {
 			case screenList:
 				a.list.clearFilter()
+				return a.switchToTasks()
+			case screenTasks:
+				a.tasks.clearFilter()
 				a.screen = screenBackups
 				if a.backups.client == nil {
 					a.backups = newBackupsScreenModel(a.list.client, a.list.instName, a.width, a.height)
@@ -317,6 +345,16 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				a.selector.table = a.selector.buildTable()
 				a.screen = screenSelector
 				return a, nil
+			case screenTasks:
+				if !a.tasks.isNormalMode() {
+					break // let tasks handle dialog/log/filter dismissal
+				}
+				a.tasks.clearFilter()
+				a.listCache[a.list.instName] = a.list
+				a.selector.current = a.list.instName
+				a.selector.table = a.selector.buildTable()
+				a.screen = screenSelector
+				return a, nil
 			// screenSelector: fall through — selector handles esc (closes help / add form).
 			}
 		}
@@ -333,6 +371,8 @@ func (a appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.users, cmd = a.users.update(msg)
 	case screenBackups:
 		a.backups, cmd = a.backups.update(msg)
+	case screenTasks:
+		a.tasks, cmd = a.tasks.update(msg)
 	case screenDetail:
 		a.detail, cmd = a.detail.update(msg)
 	case screenUserDetail:
@@ -351,6 +391,8 @@ func (a appModel) View() string {
 		return a.users.view()
 	case screenBackups:
 		return a.backups.view()
+	case screenTasks:
+		return a.tasks.view()
 	case screenDetail:
 		return a.detail.view()
 	case screenUserDetail:
@@ -359,10 +401,25 @@ func (a appModel) View() string {
 	return ""
 }
 
+// switchToTasks activates the tasks screen, creating it on first use and
+// restarting its refresh loop otherwise.
+func (a appModel) switchToTasks() (tea.Model, tea.Cmd) {
+	a.screen = screenTasks
+	if a.tasks.client == nil {
+		a.tasks = newTasksScreenModel(a.list.client, a.list.instName, a.width, a.height)
+		return a, a.tasks.init()
+	}
+	var cmd tea.Cmd
+	a.tasks, cmd = a.tasks.resume()
+	return a, cmd
+}
+
 // LaunchTUI starts the Bubble Tea program and blocks until the user quits.
 func LaunchTUI(cfg *config.Config) error {
+	taskHooks = cfg.Hooks
 	m := newAppModel(cfg)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	_, err := p.Run()
+	hooksRunning.Wait()
 	return err
 }